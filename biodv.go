@@ -119,6 +119,7 @@ const (
 	RecOrganism = "organism"   // An ID of the organism
 	RecSex      = "sex"        // Sex of the organism
 	RecStage    = "stage"      // Life stage of the organism
+	RecFlags    = "flags"      // Quality flags of the record
 )
 
 // ParseDriverString separates a driver
@@ -10,11 +10,21 @@ import (
 	"github.com/js-arias/biodv/cmdapp"
 
 	// load drivers
+	_ "github.com/js-arias/biodv/driver/col"
+	_ "github.com/js-arias/biodv/driver/dwca"
 	_ "github.com/js-arias/biodv/driver/gbif"
+	_ "github.com/js-arias/biodv/driver/geonames"
 	_ "github.com/js-arias/biodv/driver/geolocate"
+	_ "github.com/js-arias/biodv/driver/inat"
 
 	// initialize database sub-commands
+	_ "github.com/js-arias/biodv/cmd/biodv/internal/database/cache"
+	_ "github.com/js-arias/biodv/cmd/biodv/internal/database/diff"
 	_ "github.com/js-arias/biodv/cmd/biodv/internal/database/drivers"
+	_ "github.com/js-arias/biodv/cmd/biodv/internal/database/export"
+	_ "github.com/js-arias/biodv/cmd/biodv/internal/database/log"
+	_ "github.com/js-arias/biodv/cmd/biodv/internal/database/merge"
+	_ "github.com/js-arias/biodv/cmd/biodv/internal/database/undo"
 )
 
 var dbHelp = &cmdapp.Command{
@@ -42,6 +52,7 @@ Here is an example of a project sub-directories:
 			sources.stz
 		geography/
 			geography.stz
+			borders.json
 
 Not all sub-directories are required, as they will be created given
 the needs of each project.
@@ -53,6 +64,51 @@ called, or where it can be found.
 Each data guide include more details on how each subdirectory is
 organized, and the particular constrains of the stored data in that
 subdirectories.
+
+Changes on the database are committed atomically: files are written
+in temporary files, and replaced only when all of them are complete,
+using a journal file (biodv.journal). If a command is interrupted
+while committing, the operation will be completed (or discarded, if
+the journal was not written) the next time the database is opened.
+
+Each commit also appends the changed values into a change log (the
+file changes.stz), that can be browsed with db.log, and reverted with
+db.undo.
+
+Several biodv processes can use the same project at the same time.
+When a database is read, the project is locked with a shared lock (a
+file biodv.lock.<owner>), and when a database is committed, the project
+is locked with an exclusive lock (the file biodv.lock), so no process
+reads a database while it is being written. If a database was modified
+by another process after it was read, the commit will fail, instead of
+overwriting the changes of the other process.
+
+By default, if the project is locked, the command waits up to 5
+seconds, and then fails with an error that indicates the owner of the
+lock. To change the time to wait for the lock, use the option -wait
+before the command name, with the maximum time to wait (0 to fail
+immediately), for example:
+
+	biodv -wait 2m tax.db.sync
+
+If a lock file was left by an interrupted process, and no other process
+is using the project, the lock file can be safely removed.
+
+Drivers of web services (for example, gbif) keep the answers of the
+server in a cache, in the biodv directory of the user cache directory,
+so repeated requests (for example, when running tax.db.update again)
+do not use the network. By default, cached answers are used for 24
+hours. To set a different time, use the option -cache before the
+command name (use 0 to disable the cache), and to use only the
+cached answers, without any network request, use the option -offline,
+for example:
+
+	biodv -offline tax.db.update
+
+The answers of lists and searches (for example, the records of a taxon
+in gbif) are always requested to the server, so all of its pages are
+consistent, and the cached answers are only used with -offline. To
+remove the expired answers from the cache, use db.cache.
 	`,
 }
 
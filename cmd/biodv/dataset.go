@@ -8,5 +8,9 @@ package main
 
 import (
 	// initialize dataset sub-commands
+	_ "github.com/js-arias/biodv/cmd/biodv/internal/dataset/add"
+	_ "github.com/js-arias/biodv/cmd/biodv/internal/dataset/del"
 	_ "github.com/js-arias/biodv/cmd/biodv/internal/dataset/info"
+	_ "github.com/js-arias/biodv/cmd/biodv/internal/dataset/list"
+	_ "github.com/js-arias/biodv/cmd/biodv/internal/dataset/set"
 )
@@ -8,22 +8,38 @@ Usage:
     biodv [help] <command> [<args>...]
 
 The commands are:
+    db.cache         prune the cache of web answers
+    db.diff          compare the database of another project
     db.drivers       list the database drivers
+    db.export        export the database as a Darwin Core Archive
+    db.log           print the change log of the database
+    db.merge         merge the database of another project
+    db.undo          revert the last operations on the database
     help             display help information about biodv
     rec.add          add specimen records
     rec.assign       change taxon assignment of an specimen record
     rec.db.add       add records from an external DB
     rec.del          eliminate an specimen record from the database
+    rec.dups         find duplicated specimen records
+    rec.dwca.import  import records from a Darwin Core Archive
     rec.ed           edit records interactively
+    rec.flag         flag records with georeference problems
+    rec.geocheck     check the administrative data of georeferenced records
     rec.georef       set the georeference of an specimen record
     rec.gz.georef    georeference specimen records
     rec.info         print record information
     rec.map          produce a map with georeferenced records
+    rec.outliers     detect geographic outliers
+    rec.select       select records using a filter expression
     rec.set          set an specimen record value
     rec.table        print a table of records
     rec.validate     validate an specimen records database
     rec.value        get an specimen record value
+    set.add          add a dataset to the database
+    set.del          eliminate a dataset from the database
     set.info         print dataset information
+    set.list         print a list of datasets
+    set.set          set a dataset value
     tax.add          add taxon names
     tax.catalog      print a taxonomic catalog
     tax.db.add       add taxons validated on an external DB
@@ -31,9 +47,11 @@ The commands are:
     tax.db.sync      synchronize the local DB to an external taxonomy
     tax.db.update    update taxon information from an external DB
     tax.del          eliminate a taxon from the database
+    tax.ed           edit the taxonomy interactively
     tax.format       synonymize rankless taxa
     tax.info         print taxon information
     tax.list         print a list of taxons
+    tax.match        match names with the taxonomy
     tax.move         change a taxon parent
     tax.rank         change a taxon rank
     tax.set          set a taxon data value
@@ -74,6 +92,7 @@ Here is an example of a project sub-directories:
 			sources.stz
 		geography/
 			geography.stz
+			borders.json
 
 Not all sub-directories are required, as they will be created given
 the needs of each project.
@@ -86,6 +105,126 @@ Each data guide include more details on how each subdirectory is
 organized, and the particular constrains of the stored data in that
 subdirectories.
 
+Changes on the database are committed atomically: files are written
+in temporary files, and replaced only when all of them are complete,
+using a journal file (biodv.journal). If a command is interrupted
+while committing, the operation will be completed (or discarded, if
+the journal was not written) the next time the database is opened.
+
+Each commit also appends the changed values into a change log (the
+file changes.stz), that can be browsed with db.log, and reverted with
+db.undo.
+
+Several biodv processes can use the same project at the same time.
+When a database is read, the project is locked with a shared lock (a
+file biodv.lock.<owner>), and when a database is committed, the project
+is locked with an exclusive lock (the file biodv.lock), so no process
+reads a database while it is being written. If a database was modified
+by another process after it was read, the commit will fail, instead of
+overwriting the changes of the other process.
+
+By default, if the project is locked, the command waits up to 5
+seconds, and then fails with an error that indicates the owner of the
+lock. To change the time to wait for the lock, use the option -wait
+before the command name, with the maximum time to wait (0 to fail
+immediately), for example:
+
+	biodv -wait 2m tax.db.sync
+
+If a lock file was left by an interrupted process, and no other process
+is using the project, the lock file can be safely removed.
+
+Drivers of web services (for example, gbif) keep the answers of the
+server in a cache, in the biodv directory of the user cache directory,
+so repeated requests (for example, when running tax.db.update again)
+do not use the network. By default, cached answers are used for 24
+hours. To set a different time, use the option -cache before the
+command name (use 0 to disable the cache), and to use only the
+cached answers, without any network request, use the option -offline,
+for example:
+
+	biodv -offline tax.db.update
+
+The answers of lists and searches (for example, the records of a taxon
+in gbif) are always requested to the server, so all of its pages are
+consistent, and the cached answers are only used with -offline. To
+remove the expired answers from the cache, use db.cache.
+
+Prune the cache of web answers
+
+Usage:
+
+	biodv db.cache [-a|--all]
+
+Command db.cache removes the expired answers from the cache of web
+answers used by the drivers of web services (for example, gbif), i.e.
+the answers older than the time to keep cached answers (by default, 24
+hours, it can be changed with the option -cache before the command
+name, see 'biodv help database').
+
+The number of removed files is printed in the standard output.
+
+Options are:
+
+    -a
+    --all
+      If set, all the answers will be removed from the cache.
+
+Compare the database of another project
+
+Usage:
+
+	biodv db.diff [--stanza] <dir>
+
+Command db.diff compares the taxonomy, the specimen records, and the
+datasets of the current project, with the biodv project stored in
+another directory, and prints the changes required to transform the
+current project into the other project.
+
+The elements are compared by its IDs, and by its values, rather than
+by the text of the database files, so the order of the elements, or
+of its fields, is ignored.
+
+By default, the changes are printed as text, one change per line. An
+element of the other project not found in the current project is
+printed as:
+
+    + <kind> <id>
+
+An element of the current project not found in the other project is
+printed as:
+
+    - <kind> <id>
+
+And a changed value of an element found in both projects is printed
+as:
+
+    ~ <kind> <id>: <change>: <current value> -> <other value>
+
+in which the kind is 'taxon', 'record', or 'dataset'. Kinds of changes
+are:
+
+    georef  the georeference of a record was changed.
+    moved   the parent of a taxon, or the taxon of a record, was
+            changed.
+    rank    the rank of a taxon was changed.
+    status  the status of a taxon (correct or synonym) was changed.
+
+Any other change is the change of the value of a key, and it is
+printed with the name of the key.
+
+Options are:
+
+    --stanza
+      If set, the changes will be printed in the stanza format, with
+      the fields 'kind', 'id', 'change' (with the values 'added',
+      'removed', or the kind of the change), 'key', 'old' (the value
+      in the current project), and 'new' (the value in the other
+      project).
+
+    <dir>
+      The directory of the project to be compared. Required.
+
 List the database drivers
 
 Usage:
@@ -103,9 +242,165 @@ Options are:
       printed.
       Valid database kinds are:
       	dataset   dataset databases
+        gazetteer gazetteer services
         records   specimen record databases
         taxonomy  taxonomic names databases
 
+Export the database as a Darwin Core Archive
+
+Usage:
+
+	biodv db.export [-o|--output <file>] [-t|--title <title>]
+		[<name>]
+
+Command db.export writes the specimen records of the database as a
+Darwin Core Archive (DwC-A), i.e. a zip file that can be published or
+read by any tool that accepts Darwin Core Archives.
+
+The archive contains an occurrence core (occurrence.txt) with the
+specimen records, a taxon extension (taxon.txt) with the taxonomic data
+of the taxon assigned to each record (including its rank, authorship,
+parent, and accepted name if the taxon is a synonym), and an EML
+metadata file (eml.xml) that lists the datasets referenced by the
+records as the sources of the archive.
+
+The taxonID of each taxon is its name in the taxonomy database.
+
+Options are:
+
+    -o <file>
+    --output <file>
+      Sets the name of the archive file. By default the archive will be
+      called 'dwca.zip'.
+
+    -t <title>
+    --title <title>
+      Sets the title of the archive. If the title is a dataset in the
+      dataset database, its metadata will be used as the metadata of
+      the archive. By default the title is 'biodv database'.
+
+    <name>
+      If set, only the records of the indicated taxon (and its
+      descendants) will be exported.
+
+Print the change log of the database
+
+Usage:
+
+	biodv db.log [-n|--number <number>] [--id <id>]
+		[--noheader]
+
+Command db.log prints the changes made on the database, as a table
+separated by tabs. Each row of the table is a change of a value, with
+the time of the change, the command used to made the change, the
+database (taxonomy, records, or dataset), the ID of the changed
+element, the key, and the old and new values.
+
+When an element (a taxon, a record, or a dataset) is added, all of its
+values are printed with an empty old value, and when an element is
+deleted, all of its values are printed with an empty new value.
+
+Changes are grouped in operations, i.e. the set of changes committed
+by a single command. Operations made with db.undo are printed with the
+command 'db.undo'.
+
+Options are:
+
+    -n <number>
+    --number <number>
+      If set, only the last indicated number of operations will be
+      printed.
+
+    --id <id>
+      If set, only the changes of the element with the indicated ID
+      will be printed.
+
+    --noheader
+      If set, the table will be printed without the columns header.
+
+Merge the database of another project
+
+Usage:
+
+	biodv db.merge [-n|--noheader] <dir>
+
+Command db.merge adds the taxonomy, the specimen records, and the
+datasets of the biodv project stored in another directory, into the
+current project.
+
+A taxon of the other project is matched with a taxon of the current
+project if any of its extern IDs is used by a taxon of the current
+project, or if both taxa have the same name, and the same author (if
+both taxa have an author). If the taxa have the same name, but
+different authors, the taxa are homonyms, and the taxon of the other
+project, and its descendants, are not added. Empty values of the
+matched taxa will be filled with the values of the other project, and
+taxa without a match will be added to the current project, with the
+same rank and status, attached to the match of its parent. Datasets
+are matched by its ID, or its extern IDs.
+
+Specimen records of the other project are added to the match of its
+taxon. If the ID of a record is already used in the current project,
+the record will be added with a new ID (usually its catalog number).
+Records with a catalog number, or an extern ID, already used in the
+current project are not added, as they are likely duplicates of
+records of the current project.
+
+Conflicts between both projects are printed in the standard output
+as a table separated by tabs, with the kind of the element ('taxon',
+'record', or 'dataset'), its ID in the other project, the kind of the
+conflict, and the value in the current project, and in the other
+project. Kinds of conflicts are:
+
+    author      the taxa, matched by an extern ID, have different
+                authors.
+    correct     the taxa have different status (correct or synonym).
+    extern      an extern ID is matched with a different element.
+    homonym     the taxa have the same name, but different authors.
+    not-added   the element was not added.
+    parent      the taxa have different parents.
+    rank        the taxa have different ranks.
+    re-keyed    the record was added with a new ID.
+
+In all conflicts, except for 're-keyed', 'homonym', and 'not-added',
+the values of the current project are kept. The conflicts of taxa
+should be reviewed by the user, for example with tax.ed.
+
+Options are:
+
+    -n
+    --noheader
+      If set, the conflicts table will be printed without the columns
+      header.
+
+    <dir>
+      The directory of the project to be merged. Required.
+
+Revert the last operations on the database
+
+Usage:
+
+	biodv db.undo [<number>]
+
+Command db.undo reverts the last operations stored in the change log
+of the database (see db.log). An operation is the set of changes
+committed by a single command. By default only the last operation is
+reverted.
+
+The reversion is itself stored in the change log, and the reverted
+operations (as well as the db.undo operations) will be skipped by
+subsequent calls to db.undo, so calling db.undo twice reverts the last
+two operations.
+
+Changes made on the database files without using biodv are not stored
+in the change log, so reverting operations on files modified by hand
+might produce unexpected results.
+
+Options are:
+
+    <number>
+      If set, the indicated number of operations will be reverted.
+
 Display help information about biodv
 
 Usage:
@@ -121,7 +416,8 @@ Add specimen records
 
 Usage:
 
-	biodv rec.add [-g|--georef] [-l|--locatable] [<file>...]
+	biodv rec.add [-g|--georef] [-l|--locatable] [-m|--match]
+		[<file>...]
 
 Command rec.add adds one or more records from the indicated files, or
 the standard input (if no file is defined) to the specimen records
@@ -186,6 +482,16 @@ Options are:
       georeferenced or with a complete description of the locality)
       will be stored.
 
+    -m
+    --match
+      If set, the taxon names that are not in the taxonomy database
+      will be searched with approximate matching (see tax.match), so
+      misspelled names, or names with a different gender ending, will
+      be assigned to the taxon in the database. A name will be replaced
+      only if it has a single best candidate, and a warning will be
+      printed in the standard error. Names without a match, or with
+      ambiguous matches, are kept as given.
+
     <file>
       One or more files to be processed by rec.add. If no file is given,
       the data will be read from the standard input.
@@ -266,6 +572,131 @@ Options are:
     <name>
       The specimen record to be deleted.
 
+Find duplicated specimen records
+
+Usage:
+
+	biodv rec.dups [-m|--merge] [<name>]
+
+Command rec.dups searches, for each taxon, the specimen records that are
+likely duplicates, for example, a specimen added from a collection
+database with rec.add, and then added again from an extern service
+with rec.db.add.
+
+Two records are taken as duplicates if their catalog numbers are
+equivalent (i.e. ignoring case, punctuation, leading zeros, and
+institution or collection prefixes, so 'MLP:ENT:0123' is equivalent to
+'ent 123', but a bare number, as '123', is only equivalent to the same
+number), or if they do not have catalog numbers (or only one of them
+has it) and they were collected at the same date by the same collector.
+In both cases, if both records are georeferenced, the distance between
+them should be smaller than the sum of their uncertainties, plus 1 km.
+The records of a taxon include the records assigned to its synonyms,
+but not the records assigned to its descendants, that are analyzed
+separately.
+
+By default, the duplicates are printed in the standard output, one pair
+per line, with the taxon, the ID of both records, and the reason by which
+the records are taken as duplicates ('catalog' or 'collector'),
+separated by tabs.
+
+If the option --merge is defined, each pair of duplicates will be shown,
+and the user will be asked for the record to be kept. The empty values
+of the kept record will be filled with the values of the other record,
+the extern IDs of both records will be kept, and then the other record
+will be deleted. If both records have an extern ID of the same service,
+the records can not be merged, so the error will be printed and the
+pair will be skipped. The merges are written to the database only when
+all the duplicates are reviewed, or when the user exits (e). If the
+user quits (q), the program ends without making any change.
+
+Options are:
+
+    -m
+    --merge
+      If set, the duplicates will be merged interactively.
+
+    <name>
+      If set, only the records of the indicated taxon (and its
+      descendants) will be searched.
+
+Import records from a Darwin Core Archive
+
+Usage:
+
+	biodv rec.dwca.import [-d|--dataset <title>] [-g|--georef]
+		[-l|--locatable] <archive>
+
+Command rec.dwca.import adds the records stored in a Darwin Core Archive
+(DwC-A) to the specimen records database. The archive can be a zip file,
+or a directory with the unzipped archive, and it must have an occurrence
+core. If the archive does not have a meta.xml file, the first text file
+of the archive will be read as the core, and the first line of that file
+will be read as the column names.
+
+The following Darwin Core terms are recognized:
+
+    occurrenceID (or the core ID)       stored as the record ID.
+    institutionCode, collectionCode,    stored as the catalog, in the
+    catalogNumber                       form <inst>:<coll>:<catalog>
+                                        (as in the gbif driver, codes
+                                        that are repeated, or included
+                                        in the catalog number, are
+                                        omitted).
+    basisOfRecord                       stored as basis.
+    eventDate (or year, month, day)     stored as date.
+    countryCode                         stored as country.
+    stateProvince                       stored as state.
+    county                              stored as county.
+    locality                            stored as locality.
+    recordedBy                          stored as collector.
+    decimalLatitude, decimalLongitude   stored as the georeference.
+    coordinateUncertaintyInMeters       stored as uncertainty.
+    minimumElevationInMeters            stored as elevation.
+    georeferenceSources                 stored as geosource.
+    georeferenceVerificationStatus      stored as validation.
+    identifiedBy                        stored as determiner.
+    organismID                          stored as organism.
+    sex                                 stored as sex.
+    lifeStage                           stored as stage.
+    occurrenceRemarks                   stored as comment.
+    associatedReferences                stored as reference.
+
+Other terms are ignored.
+
+The scientific name of each record must be resolved in the taxonomy
+database. The scientific name is searched as given in the archive, then
+without the authorship, and then using the genus and the epithets
+terms. The names that cannot be resolved are printed in the standard
+output (one per line), and their records are not added.
+
+The records are assigned to the dataset described in the metadata (EML)
+file of the archive, that is added to the dataset database if it is not
+already present.
+
+Records already in the database (i.e. with the same ID or catalog code)
+are ignored.
+
+Options are:
+
+    -d <title>
+    --dataset <title>
+      If set, the records will be assigned to the indicated dataset,
+      instead of the dataset described in the archive metadata.
+
+    -g
+    --georef
+      If set, only the records with a valid georefence will be added.
+
+    -l
+    --locatable
+      If set, only records that can be locatable (i.e either
+      georeferenced or with a complete description of the locality)
+      will be stored.
+
+    <archive>
+      The Darwin Core Archive file (or directory) to be imported.
+
 Edit records interactively
 
 Usage:
@@ -357,6 +788,117 @@ The commands understood by rec.ed are:
     write
       Write the database on the hard disk.
 
+Flag records with georeference problems
+
+Usage:
+
+	biodv rec.flag [-b|--borders <file>] [-c|--clear]
+		[-m|--marine] [<name>]
+
+Command rec.flag makes a set of tests to detect common problems in the
+georeferences of the specimen records, and stores the flags of the
+failed tests in the 'flags' field of each record. The flagged records
+are printed in the standard output, with the record ID, the taxon, and
+the flags, separated by tabs.
+
+The following tests are made:
+
+    zero       latitude and longitude are both zero.
+    equal      latitude and longitude have the same absolute value.
+    swapped    the record is outside its country, but it is inside the
+               country if latitude and longitude are swapped.
+    inverted   the record is outside its country, but it is inside the
+               country if the sign of the latitude, the longitude, or
+               both, are inverted.
+    centroid   the record is less than 1 km of the centroid of its
+               country.
+    capital    the record is less than 10 km of a country capital.
+    sea        the record is in the sea. Records with a negative z value
+               (i.e. sampled at a given depth) are not flagged.
+    precision  both latitude and longitude have less than two decimals.
+
+The tests swapped, inverted, centroid, and sea use the country borders
+(see 'biodv help rec.validate'). By default, the file
+'geography/borders.json' of the project is used. If the file is not
+available, a simplified version of the Natural Earth borders, included
+with biodv, is used.
+
+Each time the command is run, the previous flags of these tests are
+replaced. Other flags (for example, the flags set by rec.outliers) are
+kept. Use rec.table with the option --flag to filter the records by
+their flags.
+
+Options are:
+
+    -b <file>
+    --borders <file>
+      Sets the file with the country borders.
+
+    -c
+    --clear
+      If set, the flags of the tests will be removed from the records,
+      and no test will be made.
+
+    -m
+    --marine
+      If set, records in the sea will not be flagged. Use this option
+      when flagging the records of marine taxa.
+
+    <name>
+      If set, only the records of the indicated taxon (and its
+      descendants) will be flagged.
+
+Check the administrative data of georeferenced records
+
+Usage:
+
+	biodv rec.geocheck [-s|--service <service>] [-f|--fill]
+		[<name>]
+
+Command rec.geocheck uses a gazetteer service to search the
+administrative data (country, state, and county) of the georeference of
+each record, and compares it with the administrative data stored in the
+record.
+
+The records that do not match are printed in the standard output, one
+per line, as a table (separated by tabs) with the following columns:
+
+	ID       record ID
+	Taxon    name of the taxon of the record
+	Field    the field that does not match (country, state, or
+	         county)
+	Stored   the value stored in the record
+	Found    the value found with the gazetteer
+
+Names of the states and counties are compared ignoring case, diacritics,
+and punctuation, and a name is accepted if it contains the other (e.g.
+'Provincia de Salta' and 'Salta' are taken as the same name). States are
+only compared if the country matches, and counties are only compared if
+the state matches. Empty values are never reported.
+
+If the option -f or --fill is defined, the missing administrative data
+of the records will be filled with the values found with the gazetteer.
+Values already stored in the records are never replaced.
+
+Options are:
+
+    -s <service>
+    --service <service>
+      The gazetteer service to be used. The service must implement
+      reverse searches. The available services can be listed with the
+      command 'biodv db.drivers gazetteer'. By default the service is
+      'geonames' (i.e. a local copy of GeoNames, stored in the
+      'geonames' directory of the project).
+
+    -f
+    --fill
+      If set, missing country, state, and county values of the records
+      will be filled with the values found with the gazetteer.
+
+    <name>
+      If set, only the records for the indicated taxon (and its
+      descendants) will be checked.
+
 Set the georeference of an specimen record
 
 Usage:
@@ -439,7 +981,11 @@ Options are:
 
     -s <service>
     --service <service>
-      A required parameter. The gazetteer service to be used.
+      A required parameter. The gazetteer service to be used. The
+      available services can be listed with the command
+      'biodv db.drivers gazetteer'. Parameters of the service are given
+      after a colon, for example 'geonames:/data/geonames' will use a
+      local copy of GeoNames stored at '/data/geonames'.
 
     -u <number>
     --uncertainty <number>
@@ -476,7 +1022,7 @@ Usage:
 
 	biodv rec.map [--db <database>] [--id] [-e|--exact]
 		[-h|--heath] [-m|--map <imagemap>] [-o|--out <suffix>]
-		[-s|--size <number>] [<taxon>]
+		[-s|--size <number>] [-w|--where <expression>] [<taxon>]
 
 Command rec.map produces a image map using a provided image map, and the
 georeferenced records of the indicated taxon. If no taxon is given, it
@@ -507,6 +1053,10 @@ records.
 
 The option -s or --size controls the size of the output points.
 
+If the option -w or --where is defined, only the records that match the
+given filter expression will be used (see 'biodv help rec.select' for
+the syntax of the filter expressions).
+
 Options are:
 
     -db <database>
@@ -544,12 +1094,141 @@ Options are:
     --size <number>
       Defines the size (in pixels) of each record in the map. Default = 2.
 
+    -w <expression>
+    --where <expression>
+      If set, only the records that match the expression will be used
+      to produce the map.
+
     <taxon>
       If set, the map will be based on the indicated taxon. If the
       name is ambiguous, the ID of the ambiguous taxa will be printed.
       If the option --id is set, it must be a taxon ID instead of a
       taxon name.
 
+Detect geographic outliers
+
+Usage:
+
+	biodv rec.outliers [-m|--method <method>] [-k|--mult <value>]
+		[-d|--distance <value>] [-f|--flag] [<name>]
+
+Command rec.outliers searches, for each taxon, the specimen records that
+are far away from the other records of the taxon. Such records are
+usually misidentified specimens, or records with a wrong georeference.
+
+The outliers are detected using the distance of each record to its
+nearest neighbour (i.e. the nearest record of the same taxon). The
+records of a taxon include the records assigned to its synonyms, but
+not the records assigned to its descendants, that are analyzed
+separately. Records without a georeference are ignored.
+
+By default, the outliers are printed in the standard output, one per
+line, with the record ID, the taxon, and the distance (in meters) to
+its nearest neighbour, separated by tabs. If the option --flag is
+defined, the 'outlier' flag will be set in the 'flags' field of the
+outlier records (and removed from the other records of the analyzed
+taxa). Use rec.table with the option --flag to filter the records by
+their flags.
+
+The following methods are available:
+
+    mad   a record is an outlier if its distance to its nearest
+          neighbour is greater than the median of the nearest
+          neighbour distances, plus k times the median absolute
+          deviation (MAD) of the distances. Taxa with less than 7
+          georeferenced records are not analyzed. This is the default
+          method.
+    dist  a record is an outlier if its distance to its nearest
+          neighbour is greater than a fixed distance.
+
+Options are:
+
+    -m <method>
+    --method <method>
+      Sets the method used to detect the outliers. Valid values are
+      'mad' and 'dist'. By default 'mad' is used.
+
+    -k <value>
+    --mult <value>
+      Sets the multiplier of the median absolute deviation, when the
+      'mad' method is used. Default value is 5.
+
+    -d <value>
+    --distance <value>
+      Sets the distance, in meters, used by the 'dist' method. Default
+      value is 1000000 (i.e. 1000 km).
+
+    -f
+    --flag
+      If set, the outlier records will be flagged.
+
+    <name>
+      If set, only the records of the indicated taxon (and its
+      descendants) will be analyzed.
+
+Select records using a filter expression
+
+Usage:
+
+	biodv rec.select [--db <database>] [-n|--noheader]
+		<expression>
+
+Command rec.select prints a table (separated by tabs) of the records of
+all the taxa in the database that match a filter expression. The columns
+of the table are the same as in rec.table.
+
+A filter expression is made of terms, joined by the logical operators
+AND, OR, and NOT (case insensitive), and grouped with parenthesis. AND
+has precedence over OR. A term is a field name, an operator, and a
+value, for example:
+
+    basis=preserved AND country=AR AND date>=1990 AND georef AND uncertainty<5000
+
+Valid operators are:
+
+    =    equal
+    !=   not equal
+    <    less than
+    <=   less than or equal
+    >    greater than
+    >=   greater than or equal
+    ~    contains
+
+Values with spaces or operator characters must be quoted (remember to
+quote the whole expression when using the shell). String comparisons
+are case insensitive, and if both values are numbers, they are compared
+as numbers.
+
+The field names are the names used in the records database (for
+example 'catalog', 'collector', or 'flags', see 'biodv help records'),
+with the additional fields 'lat' and 'lon' for the latitude and the
+longitude. Dates can be given as a year ('1990'), a year and a month
+('1990-05'), or a full date ('1990-05-12'). For fields with a list of
+values (as 'flags' or 'extern'), the operators '=' and '!=' compare
+against each element of the list.
+
+A field without operator and value is true if the field is defined in
+the record. The special field 'georef' is true if the record has a
+valid georeference.
+
+The same filter expressions can be used in rec.table and rec.map with
+the option --where.
+
+Options are:
+
+    -db <database>
+    --db <database>
+      If set, the indicated database will be used to produce the table.
+      To see the available databases use the command ‘db.drivers’.
+      The database should include drivers for a taxonomy and records.
+
+    -n
+    --noheader
+      If set, the table will be printed without the columns header.
+
+    <expression>
+      The filter expression used to select the records.
+
 Set an specimen record value
 
 Usage:
@@ -620,7 +1299,8 @@ Print a table of records
 Usage:
 
 	biodv rec.table [--db <database>] [--id] [-e|--exact]
-		[-g|--georef] [-n|--noheader] [<taxon>]
+		[-f|--flag <flag>] [-g|--georef] [-n|--noheader]
+		[-w|--where <expression>] [<taxon>]
 
 Command rec.table prints a table (separated by tabs) of the records of
 a given taxon in a given database.  If no taxon is given, it will make
@@ -634,6 +1314,14 @@ printed.
 If the option -g or --georef is defined, only records with valid
 georeferences will be printed.
 
+If the option -f or --flag is defined, only records with the indicated
+flag (as set by rec.flag) will be printed. If the flag is 'none', only
+records without flags will be printed.
+
+If the option -w or --where is defined, only the records that match the
+given filter expression will be printed (see 'biodv help rec.select'
+for the syntax of the filter expressions).
+
 By default, the table will be printed with the column header. If the
 option -n or --noheader is defined, then no header will be printed. The
 order of columns is:
@@ -662,6 +1350,12 @@ Options are:
       If set, only the records explicitly assigned to the indicated
       taxon will be printed.
 
+    -f <flag>
+    --flag <flag>
+      If set, only the records with the indicated flag will be printed.
+      If the flag is 'none', only the records without flags will be
+      printed.
+
     -g
     --georef
       If set, only the records with a valid georeference will be
@@ -671,6 +1365,11 @@ Options are:
     --noheader
       If set, the table will be printed without the columns header.
 
+    -w <expression>
+    --where <expression>
+      If set, only the records that match the expression will be
+      printed.
+
     <taxon>
       If set, the table will be based on the indicated taxon. If the
       name is ambiguous, the ID of the ambiguous taxa will be printed.
@@ -681,11 +1380,83 @@ Validate an specimen records database
 
 Usage:
 
-	biodv rec.validate
+	biodv rec.validate [-b|--borders <file>] [-m|--marine]
+		[--stanza] [<name>]
 
 Command rec.validate validates a records database. It is useful to test
-if a biodv database from a third party is correct. If there are no
-errors, it will finish silently.
+if a biodv database from a third party is correct.
+
+The records are read directly from the database files, and all the
+problems found are reported. The records with problems will be printed
+in the standard output, one problem per line, with the record ID, the
+taxon, and the problem, separated by tabs. If there are no problems,
+it will finish silently, otherwise, it will finish with an error (i.e.
+with a non-zero exit status), so it can be used in scripts. The
+following problems are reported:
+
+    no id                the record does not have an ID.
+    no taxon             the record does not have a taxon.
+    duplicated id        the ID of the record is used by another
+                         record.
+    duplicated catalog   the catalog number of the record is used by
+                         another record.
+    unknown taxon        the taxon of the record is not in the
+                         taxonomy.
+    synonym              the taxon of the record is a synonym.
+    invalid basis        the basis of record is unknown.
+    no catalog           the record is a preserved specimen without a
+                         catalog number.
+    invalid country      the country code is not a valid ISO 3166-1
+                         alpha-2 code.
+    invalid coordinates  the latitude or the longitude are invalid.
+    invalid date         the date can not be read.
+    future date          the date is after the current time.
+    old date             the date is before the year 1700.
+    unknown dataset      the dataset of the record is not in the
+                         dataset database.
+
+The georeferences of the records are validated using the country
+borders, and the following problems are reported:
+
+    outside country      the georeference of the record is outside
+                         the borders of the country of the record.
+    in the sea           the georeference of the record is in the sea.
+                         Records with a negative z value (i.e.
+                         sampled at a given depth) are not reported.
+
+A point is taken as inside a country (or inland) if its distance to the
+border is smaller than its uncertainty plus 10 km, as border files are
+usually simplified.
+
+The borders file is a GeoJSON file with the polygons of each country,
+with the ISO 3166-1 alpha-2 code of the country stored in the property
+'ISO_A2' (as in the Natural Earth admin 0 countries files, available at
+<http://www.naturalearthdata.com/>). By default, the file
+'geography/borders.json' of the project is used. If the file is not
+available, a simplified version of the Natural Earth 1:10m borders,
+included with biodv, is used.
+
+The taxonomy of the project should be valid (see tax.validate).
+
+Options are:
+
+    -b <file>
+    --borders <file>
+      Sets the file with the country borders.
+
+    -m
+    --marine
+      If set, records in the sea will not be reported. Use this option
+      when validating the records of marine taxa.
+
+    --stanza
+      If set, the problems will be printed in the stanza format, with
+      the fields 'id', 'taxon', 'problem', and 'value' (the value with
+      the problem, if any).
+
+    <name>
+      If set, only the records of the indicated taxon (and its
+      descendants) will be validated.
 
 Get an specimen record value
 
@@ -769,11 +1540,53 @@ The following fields are recognized by the biodv records commands:
     sex          sex of the organism.
     altitude     in flying specimens, the altitude above ground when
                  the observation was made.
+    flags        a list of flags (separated by spaces) of problems
+                 detected in the record (e.g. with rec.flag).
 
 Most biodv commands assume that the specimen records datafiles are well
 formatted. In the case of an untrusted database, it can be validated with
 the command rec.validate.
 
+Add a dataset to the database
+
+Usage:
+
+	biodv set.add <title>
+
+Command set.add adds a new dataset to the database. The title of the
+dataset will be used as its ID, so it must be unique in the database.
+
+To set the values of the dataset, use set.set.
+
+Options are:
+
+    <title>
+      The title of the new dataset.
+
+Eliminate a dataset from the database
+
+Usage:
+
+	biodv set.del [--to <dataset>] <dataset>
+
+Command set.del removes a dataset from the database.
+
+If there are specimen records that reference the dataset, the dataset
+will not be deleted, unless the option --to is used. In that case, the
+records will be reassigned to the indicated dataset before deleting the
+dataset.
+
+Options are:
+
+    --to <dataset>
+      If set, the records of the deleted dataset will be assigned
+      to the indicated dataset, that must be already in the
+      database.
+
+    <dataset>
+      The dataset to be deleted, it can be the dataset ID, or one of
+      its extern IDs.
+
 Print dataset information
 
 Usage:
@@ -794,6 +1607,64 @@ Options are:
     <value>
       The ID of the dataset.
 
+Print a list of datasets
+
+Usage:
+
+	biodv set.list
+
+Command set.list prints the list of the datasets in the database, with
+the number of specimen records of each dataset, separated by a tab.
+
+Records that reference a dataset that is not in the database are
+counted in an additional line with the ID of that dataset, and records
+without dataset are not counted.
+
+Set a dataset value
+
+Usage:
+
+	biodv set.set -k|--key <key> [-v|--value <value>] <dataset>
+
+Command set.set sets the value of a given key for the indicated dataset,
+overwritting any previous value. If the value is empty, the content of
+the key will be eliminated.
+
+The title of a dataset can not be changed.
+
+Except for some standard keys, no content of the values will be evaluated
+by the program or the database.
+
+Options are:
+
+    -k <key>
+    --key <key>
+      A key, a required parameter. Keys must be in lower case and
+      without spaces  (it will be reformatted to lower case, and spaces
+      between words replaced by the dash ‘-’ character). Any key can
+      be stored, but the recognized keys (and their expected values)
+      are:
+        about       a description of the dataset.
+        extern      extern identifier of the dataset, in the form
+                    <service>:<key>, for example 'gbif:1234'. Only
+                    one extern ID per service is stored. If the key is
+                    empty (for example 'gbif:') the extern ID of that
+                    service will be eliminated.
+        license     the license of the dataset.
+        publisher   the publisher of the dataset.
+        reference   a bibliographic reference of the dataset.
+        url         the URL of the dataset.
+      For a set of available keys of a given dataset, use set.info.
+
+    -v <value>
+    --value <value>
+      The value to set. If no value is defined, or an empty string is
+      given, the value on that key will be deleted.
+
+    <dataset>
+      The dataset to be set, it can be the dataset ID, or one of its
+      extern IDs.
+
 Stanza file format
 
 In biodv data is stored using the stanza format. The stanza format is an
@@ -868,6 +1739,14 @@ assumes that each line contains a taxon name (empty lines, or lines
 starting with the sharp symbol ( # ) or semicolon character ( ; ) will
 be ignored).
 
+The name can include its authors and year, for example:
+
+	Puma concolor cougar (Kerr, 1792)
+
+in which case, the authors will be stored as the author of the taxon.
+Subgenus names (in parentheses after the genus), and the rank markers
+of infraspecific names (subsp., var., f.) are removed from the name.
+
 By default, taxons will be added to the root of the taxonomy (without
 parent), as correct names, and rankless.
 
@@ -884,15 +1763,20 @@ Options are:
       should be concordant with the parent's rank.
       Valid ranks are:
         unranked
-        kingdom
-        class
-        order
-        family
-        genus
-        species
+        kingdom      subkingdom
+        phylum       subphylum
+        superclass   class        subclass     infraclass
+        superorder   order        suborder     infraorder
+        superfamily  family       subfamily    tribe     subtribe
+        genus        subgenus
+        species      subspecies   variety      form
+      The abbreviations subsp. (or ssp.), var., and f. are also
+      accepted.
       If the rank is set to species, it will automatically interpret the
       first part of the name as a genus, and adds the species as a child
-      of that genus.
+      of that genus. If the rank is set to subspecies, variety, or form,
+      it will interpret the first two words of the name as a species,
+      and adds the taxon as a child of that species.
 
     -s
     --synonym
@@ -959,6 +1843,11 @@ In the input file, it is assumed that each line contains a taxon name
 (empty lines, or lines starting with the sharp symbol ( # ) or
 semicolon character ( ; ) will be ignored).
 
+The name can include its authors and year (e.g. 'Puma concolor cougar
+(Kerr, 1792)'). Only the name is searched in the external database,
+and if the external database does not have the author of the taxon, the
+authors of the input will be stored as the author of the taxon.
+
 If the option -u or --uprank is given, it will add additional parents up
 to the given rank.
 
@@ -974,12 +1863,14 @@ Options are:
       If set, parent taxons, up to the given rank, will be added to
       the database.
       Valid ranks are:
-        kingdom
-        class
-        order
-        family
-        genus     [default value]
-        species
+        kingdom      subkingdom
+        phylum       subphylum
+        superclass   class        subclass     infraclass
+        superorder   order        suborder     infraorder
+        superfamily  family       subfamily    tribe     subtribe
+        genus        subgenus
+        species      subspecies   variety      form
+      By default, the rank is genus.
 
 Add taxons from an external DB
 
@@ -1100,6 +1991,100 @@ Options are:
     <name>
       The taxon to be deleted.
 
+Edit the taxonomy interactively
+
+Usage:
+
+	biodv tax.ed
+
+Command tax.ed implements a simple interactive taxonomy editor.
+
+In most commands, a taxon can be given by its name. The special values
+'.' (the current taxon), '..' (the parent of the current taxon), and '/'
+(the root of the taxonomy) are also accepted.
+
+The commands understood by tax.ed are:
+
+    a <name>
+    add <name>
+      Add a taxon as a correct child of the current taxon.
+
+    as <name>
+      Add a taxon as a synonym of the current taxon.
+
+    c [<taxon>]
+    correct [<taxon>]
+      Set the current taxon as a correct name.
+
+    del [<taxon>]
+    delete [<taxon>]
+      Delete a taxon.
+
+    d [<taxon>]
+    desc [<taxon>]
+      List the correct children of a taxon.
+
+    e
+    exit
+      Shorthand for 'write' and 'quit'.
+
+    h [<command>]
+    help [<command>]
+      Print command help.
+
+    m <taxon>
+    move <taxon>
+      Move the current taxon to a new parent.
+
+    n
+    next
+      Move to the next sibling taxon.
+
+    nv
+      Shorthand for 'next' and 'view'.
+
+    p
+    prev
+      Move to the previous sibling taxon.
+
+    pv
+      Shorthand for 'prev' and 'view'.
+
+    q
+    quit
+      Quit the program, without making any change.
+
+    rk [<rank>]
+    rank [<rank>]
+      Print or set the rank of the current taxon.
+
+    s <key> <value>
+    set <key> <value>
+      Set a value of the current taxon.
+
+    sv <key> <value>
+      Shorthand for 'set' and 'view'.
+
+    sy [<taxon>]
+    synonyms [<taxon>]
+      List the synonyms of a taxon.
+
+    syn <taxon>
+    synonymize <taxon>
+      Set the current taxon as a synonym of another taxon.
+
+    t <taxon>
+    taxon <taxon>
+      Move to the indicated taxon.
+
+    v [<taxon>]
+    view [<taxon>]
+      Show taxon data.
+
+    w
+    write
+      Write the database on the hard disk.
+
 Synonymize rankless taxa
 
 Usage:
@@ -1109,7 +2094,9 @@ Usage:
 Command tax.format search for all unranked taxons (except from taxons
 attached to the root), and make them synonyms, so the taxonomy will only
 have as correct names, names with some rank. This is useful to, for
-example, collapse subspecies as synonyms of a species.
+example, collapse unranked infraspecific names as synonyms of a species.
+Names with an explicit infraspecific rank (i.e. subspecies, variety, or
+form) are kept as correct names.
 
 Options are:
 
@@ -1207,6 +2194,41 @@ Options are:
       will be printed. If the option --id is set, it must be a taxon ID
       instead of a taxon name.
 
+Match names with the taxonomy
+
+Usage:
+
+	biodv tax.match [-c|--candidates <number>] [--noheader]
+
+Command tax.match reads taxon names from the standard input, and
+searches the taxonomy database for the taxa that match each name,
+even if the name is misspelled, or the gender ending of an epithet
+is changed. It assumes that each line contains a taxon name (empty
+lines, or lines starting with the sharp symbol ( # ) or semicolon
+character ( ; ) will be ignored). The name can include its authors.
+
+The matching is inspired by Taxamatch (Rees 2014, PLoS ONE 9: e107510):
+each word of the name is compared with the words of the taxa in the
+database, and two words are matched if they are equal, if they are
+equal after removing the gender ending (in epithets), if they have the
+same phonetic key, or if they have a small edit distance. Each match
+has a score, from 0 to 1 (an exact match).
+
+The output is a table separated by tabs, with the name, the matched
+taxon, the score, and the accepted name of the taxon, if it is a
+synonym. If a name has no matches, it will be printed with empty
+columns.
+
+Options are:
+
+    -c <number>
+    --candidates <number>
+      Sets the maximum number of candidates printed for each name. By
+      default only the best candidate is printed.
+
+    --noheader
+      If set, the table will be printed without the columns header.
+
 Change a taxon parent
 
 Usage:
@@ -1258,7 +2280,8 @@ Usage:
 
 Command tax.rank sets a new rank to a given taxon. If no rank is
 defined, it will set the taxon as unranked. The new rank should be
-compatible with the current taxonomy.
+compatible with the current taxonomy. Taxons with an infraspecific rank
+(subspecies, variety, or form) should be placed below a species.
 
 Options are:
 
@@ -1266,13 +2289,16 @@ Options are:
     --rank <rank>
       Sets the new rank of the taxon.
       Valid ranks are:
-        unranked  (default)
-        kingdom
-        class
-        order
-        family
-        genus
-        species
+        unranked     (default)
+        kingdom      subkingdom
+        phylum       subphylum
+        superclass   class        subclass     infraclass
+        superorder   order        suborder     infraorder
+        superfamily  family       subfamily    tribe     subtribe
+        genus        subgenus
+        species      subspecies   variety      form
+      The abbreviations subsp. (or ssp.), var., and f. are also
+      accepted.
 
     <name>
       The taxon to be reranked. This parameter is required.
@@ -1324,11 +2350,40 @@ Validate a taxonomy database
 
 Usage:
 
-	biodv tax.validate
+	biodv tax.validate [--stanza]
 
 Command tax.validate validates a taxonomy database. It is useful to test
-if a biodov database from a third party is correct.  If there are no
-errors, it will finish silently.
+if a biodv database from a third party is correct.
+
+The taxa are read directly from the database file, and all the problems
+found are reported. The taxa with problems will be printed in the
+standard output, one problem per line, with the taxon name, and the
+problem, separated by tabs. If there are no problems, it will finish
+silently, otherwise, it will finish with an error (i.e. with a non-zero
+exit status), so it can be used in scripts. The following problems are
+reported:
+
+    no name                 the taxon does not have a name.
+    duplicated name         the name is used by another taxon.
+    invalid rank            the rank is unknown.
+    unknown parent          the parent of the taxon is not in the
+                            taxonomy.
+    parent after taxon      the parent is defined after the taxon in
+                            the database file.
+    synonym parent          the parent of the taxon is a synonym.
+    synonym without parent  the taxon is a synonym attached to the
+                            root of the taxonomy.
+    rank inversion          the rank of the taxon is not lower than
+                            the rank of its parents (synonyms can have
+                            the rank of its parent, if both ranks are
+                            from the same nomenclatural group).
+
+Options are:
+
+    --stanza
+      If set, the problems will be printed in the stanza format, with
+      the fields 'name', 'problem', and 'value' (the value with the
+      problem, if any).
 
 Get a taxon data value
 
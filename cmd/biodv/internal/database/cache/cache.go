@@ -0,0 +1,64 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package cache implements the db.cache command,
+// i.e. prune the cache of web answers.
+package cache
+
+import (
+	"fmt"
+
+	"github.com/js-arias/biodv/cmdapp"
+	"github.com/js-arias/biodv/driver/web"
+
+	"github.com/pkg/errors"
+)
+
+var cmd = &cmdapp.Command{
+	UsageLine: "db.cache [-a|--all]",
+	Short:     "prune the cache of web answers",
+	Long: `
+Command db.cache removes the expired answers from the cache of web
+answers used by the drivers of web services (for example, gbif), i.e.
+the answers older than the time to keep cached answers (by default, 24
+hours, it can be changed with the option -cache before the command
+name, see 'biodv help database').
+
+The number of removed files is printed in the standard output.
+
+Options are:
+
+    -a
+    --all
+      If set, all the answers will be removed from the cache.
+	`,
+	Run:           run,
+	RegisterFlags: register,
+}
+
+func init() {
+	cmdapp.Add(cmd)
+}
+
+var all bool
+
+func register(c *cmdapp.Command) {
+	c.Flag.BoolVar(&all, "all", false, "")
+	c.Flag.BoolVar(&all, "a", false, "")
+}
+
+func run(c *cmdapp.Command, args []string) error {
+	age := web.TTL
+	if all {
+		age = 0
+	}
+	n, err := web.Prune(age)
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	fmt.Printf("%d files removed\n", n)
+	return nil
+}
@@ -0,0 +1,571 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package diff implements the db.diff command,
+// i.e. compare the database of another project.
+package diff
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/js-arias/biodv"
+	"github.com/js-arias/biodv/cmdapp"
+	"github.com/js-arias/biodv/dataset"
+	"github.com/js-arias/biodv/encoding/stanza"
+	"github.com/js-arias/biodv/geography"
+
+	"github.com/pkg/errors"
+)
+
+var cmd = &cmdapp.Command{
+	UsageLine: "db.diff [--stanza] <dir>",
+	Short:     "compare the database of another project",
+	Long: `
+Command db.diff compares the taxonomy, the specimen records, and the
+datasets of the current project, with the biodv project stored in
+another directory, and prints the changes required to transform the
+current project into the other project.
+
+The elements are compared by its IDs, and by its values, rather than
+by the text of the database files, so the order of the elements, or
+of its fields, is ignored.
+
+By default, the changes are printed as text, one change per line. An
+element of the other project not found in the current project is
+printed as:
+
+    + <kind> <id>
+
+An element of the current project not found in the other project is
+printed as:
+
+    - <kind> <id>
+
+And a changed value of an element found in both projects is printed
+as:
+
+    ~ <kind> <id>: <change>: <current value> -> <other value>
+
+in which the kind is 'taxon', 'record', or 'dataset'. Kinds of changes
+are:
+
+    georef  the georeference of a record was changed.
+    moved   the parent of a taxon, or the taxon of a record, was
+            changed.
+    rank    the rank of a taxon was changed.
+    status  the status of a taxon (correct or synonym) was changed.
+
+Any other change is the change of the value of a key, and it is
+printed with the name of the key.
+
+Options are:
+
+    --stanza
+      If set, the changes will be printed in the stanza format, with
+      the fields 'kind', 'id', 'change' (with the values 'added',
+      'removed', or the kind of the change), 'key', 'old' (the value
+      in the current project), and 'new' (the value in the other
+      project).
+
+    <dir>
+      The directory of the project to be compared. Required.
+	`,
+	Run:           run,
+	RegisterFlags: register,
+}
+
+func init() {
+	cmdapp.Add(cmd)
+}
+
+var stzOut bool
+
+func register(c *cmdapp.Command) {
+	c.Flag.BoolVar(&stzOut, "stanza", false, "")
+}
+
+// Kinds of elements.
+const (
+	taxonKind  = "taxon"
+	recordKind = "record"
+	setKind    = "dataset"
+)
+
+// Kinds of changes.
+const (
+	added   = "added"
+	removed = "removed"
+	georef  = "georef"
+	moved   = "moved"
+	rank    = "rank"
+	status  = "status"
+	keyed   = "key"
+)
+
+// A Change is a difference
+// between an element
+// of both projects.
+type change struct {
+	kind   string
+	id     string
+	change string
+	key    string
+	old    string
+	new    string
+}
+
+// A Project stores the databases
+// of a project.
+type project struct {
+	txm  biodv.Taxonomy
+	recs biodv.RecDB
+	sets biodv.SetDB
+}
+
+func run(c *cmdapp.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.Errorf("%s: a project directory should be given", c.Name())
+	}
+	dir := args[0]
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		return errors.Errorf("%s: %s: not a project directory", c.Name(), dir)
+	}
+
+	old, err := openProject("")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	new, err := openProject(dir)
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	ch, err := diff(old, new)
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+
+	if stzOut {
+		err = writeStanza(ch)
+	} else {
+		err = writeText(ch)
+	}
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	return nil
+}
+
+// OpenProject opens the databases
+// of the project in the given path.
+func openProject(path string) (*project, error) {
+	txm, err := biodv.OpenTax("biodv", path)
+	if err != nil {
+		return nil, err
+	}
+	recs, err := biodv.OpenRec("biodv", path)
+	if err != nil {
+		return nil, err
+	}
+	sets, err := biodv.OpenSet("biodv", path)
+	if err != nil {
+		return nil, err
+	}
+	return &project{txm, recs, sets}, nil
+}
+
+// A TaxLister is a records database
+// that can list the taxa with records.
+type taxLister interface {
+	Taxa() []string
+}
+
+// A SetLister is a dataset database
+// that can list its datasets.
+type setLister interface {
+	List() []*dataset.Dataset
+}
+
+// Diff returns the changes
+// between two projects.
+func diff(old, new *project) ([]change, error) {
+	otx, err := taxa(old.txm)
+	if err != nil {
+		return nil, err
+	}
+	ntx, err := taxa(new.txm)
+	if err != nil {
+		return nil, err
+	}
+	ch := compare(taxonKind, taxValues(otx), taxValues(ntx))
+
+	orc, err := recs(old.recs, otx)
+	if err != nil {
+		return nil, err
+	}
+	nrc, err := recs(new.recs, ntx)
+	if err != nil {
+		return nil, err
+	}
+	ch = append(ch, compare(recordKind, recValues(orc), recValues(nrc))...)
+
+	ost, err := sets(old.sets, orc)
+	if err != nil {
+		return nil, err
+	}
+	nst, err := sets(new.sets, nrc)
+	if err != nil {
+		return nil, err
+	}
+	ch = append(ch, compare(setKind, setValues(ost), setValues(nst))...)
+	return ch, nil
+}
+
+// Taxa returns all the taxa
+// of a taxonomy.
+func taxa(txm biodv.Taxonomy) (map[string]biodv.Taxon, error) {
+	m := make(map[string]biodv.Taxon)
+	if err := addTaxa(txm, m, ""); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// AddTaxa adds the children,
+// and the synonyms,
+// of a taxon.
+func addTaxa(txm biodv.Taxonomy, m map[string]biodv.Taxon, id string) error {
+	ls, err := biodv.TaxList(txm.Children(id))
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		syns, err := biodv.TaxList(txm.Synonyms(id))
+		if err != nil {
+			return err
+		}
+		ls = append(ls, syns...)
+	}
+	for _, tax := range ls {
+		if _, ok := m[tax.ID()]; ok {
+			continue
+		}
+		m[tax.ID()] = tax
+		if err := addTaxa(txm, m, tax.ID()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Recs returns all the records
+// of a record database.
+// Records are searched
+// in the taxa of the taxonomy,
+// and,
+// if the database can list its taxa,
+// also in the taxa without records.
+func recs(rdb biodv.RecDB, txm map[string]biodv.Taxon) (map[string]biodv.Record, error) {
+	ids := make(map[string]bool, len(txm))
+	for id := range txm {
+		ids[id] = true
+	}
+	if db, ok := rdb.(taxLister); ok {
+		for _, id := range db.Taxa() {
+			ids[id] = true
+		}
+	}
+
+	m := make(map[string]biodv.Record)
+	for id := range ids {
+		sc := rdb.TaxRecs(id)
+		for sc.Scan() {
+			r := sc.Record()
+			m[r.ID()] = r
+		}
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Sets returns the datasets
+// of a dataset database,
+// that are referenced by the records,
+// or,
+// if the database can list its datasets,
+// all the datasets of the database.
+func sets(sdb biodv.SetDB, recs map[string]biodv.Record) (map[string]biodv.Dataset, error) {
+	m := make(map[string]biodv.Dataset)
+	if db, ok := sdb.(setLister); ok {
+		for _, set := range db.List() {
+			m[set.ID()] = set
+		}
+	}
+	for _, r := range recs {
+		id := r.Value(biodv.RecDataset)
+		if id == "" {
+			continue
+		}
+		if _, ok := m[id]; ok {
+			continue
+		}
+		set, err := sdb.SetID(id)
+		if err != nil {
+			return nil, err
+		}
+		if set == nil {
+			continue
+		}
+		m[set.ID()] = set
+	}
+	return m, nil
+}
+
+// Fields used for the values
+// of the compared elements.
+const (
+	parentField  = "parent"
+	rankField    = "rank"
+	correctField = "correct"
+	taxonField   = "taxon"
+	latlonField  = "latlon"
+	titleField   = "title"
+)
+
+// TaxValues returns the values
+// of a list of taxa.
+func taxValues(ls map[string]biodv.Taxon) map[string]map[string]string {
+	vals := make(map[string]map[string]string, len(ls))
+	for id, tax := range ls {
+		m := values(tax.Keys(), tax.Value)
+		m[parentField] = tax.Parent()
+		m[rankField] = tax.Rank().String()
+		m[correctField] = "correct"
+		if !tax.IsCorrect() {
+			m[correctField] = "synonym"
+		}
+		vals[id] = m
+	}
+	return vals
+}
+
+// RecValues returns the values
+// of a list of records.
+func recValues(ls map[string]biodv.Record) map[string]map[string]string {
+	vals := make(map[string]map[string]string, len(ls))
+	for id, r := range ls {
+		m := values(r.Keys(), r.Value)
+		m[taxonField] = r.Taxon()
+		m["basis"] = r.Basis().String()
+
+		ev := r.CollEvent()
+		if !ev.Date.IsZero() {
+			m["date"] = ev.Date.Format(time.RFC3339)
+		}
+		m["country"] = ev.CountryCode()
+		m["state"] = ev.State()
+		m["county"] = ev.County()
+		m["locality"] = ev.Locality
+		m["collector"] = ev.Collector
+		if ev.Z != 0 {
+			m["z"] = strconv.Itoa(ev.Z)
+		}
+
+		geo := r.GeoRef()
+		if geo.IsValid() {
+			m[latlonField] = fmt.Sprintf("%s %s", strconv.FormatFloat(geo.Lat, 'f', -1, 64), strconv.FormatFloat(geo.Lon, 'f', -1, 64))
+			if geo.Uncertainty != 0 {
+				m["uncertainty"] = strconv.Itoa(int(geo.Uncertainty))
+			}
+			if geo.Elevation != 0 {
+				m["elevation"] = strconv.Itoa(int(geo.Elevation))
+			}
+			m["geosource"] = geo.Source
+			m["validation"] = geo.Validation
+		}
+		vals[id] = m
+	}
+	return vals
+}
+
+// SetValues returns the values
+// of a list of datasets.
+func setValues(ls map[string]biodv.Dataset) map[string]map[string]string {
+	vals := make(map[string]map[string]string, len(ls))
+	for id, set := range ls {
+		m := values(set.Keys(), set.Value)
+		m[titleField] = set.Title()
+		vals[id] = m
+	}
+	return vals
+}
+
+// Values returns the values
+// of a list of keys.
+func values(keys []string, value func(string) string) map[string]string {
+	m := make(map[string]string, len(keys))
+	for _, k := range keys {
+		m[k] = value(k)
+	}
+	return m
+}
+
+// Compare returns the changes
+// between the elements of two projects.
+func compare(kind string, old, new map[string]map[string]string) []change {
+	ids := make(map[string]bool)
+	for id := range old {
+		ids[id] = true
+	}
+	for id := range new {
+		ids[id] = true
+	}
+	ls := make([]string, 0, len(ids))
+	for id := range ids {
+		ls = append(ls, id)
+	}
+	sort.Strings(ls)
+
+	var ch []change
+	for _, id := range ls {
+		o, inOld := old[id]
+		n, inNew := new[id]
+		if !inOld {
+			ch = append(ch, change{kind, id, added, "", "", ""})
+			continue
+		}
+		if !inNew {
+			ch = append(ch, change{kind, id, removed, "", "", ""})
+			continue
+		}
+
+		keys := make(map[string]bool)
+		for k := range o {
+			keys[k] = true
+		}
+		for k := range n {
+			keys[k] = true
+		}
+		kl := make([]string, 0, len(keys))
+		for k := range keys {
+			kl = append(kl, k)
+		}
+		sort.Strings(kl)
+		for _, k := range kl {
+			ov := strings.TrimSpace(o[k])
+			nv := strings.TrimSpace(n[k])
+			if ov == nv {
+				continue
+			}
+			c := change{kind, id, changeKind(kind, k), k, ov, nv}
+			if c.change == georef && sameGeoRef(ov, nv) {
+				continue
+			}
+			ch = append(ch, c)
+		}
+	}
+	return ch
+}
+
+// ChangeKind returns the kind of change
+// of a key.
+func changeKind(kind, key string) string {
+	switch kind {
+	case taxonKind:
+		switch key {
+		case parentField:
+			return moved
+		case rankField:
+			return rank
+		case correctField:
+			return status
+		}
+	case recordKind:
+		switch key {
+		case taxonField:
+			return moved
+		case latlonField:
+			return georef
+		}
+	}
+	return keyed
+}
+
+// SameGeoRef returns true
+// if two georeferences are equal
+// under the precision
+// of the geography package.
+func sameGeoRef(a, b string) bool {
+	pa, pb := parseGeoRef(a), parseGeoRef(b)
+	return pa.Equal(pb)
+}
+
+func parseGeoRef(s string) geography.Position {
+	p := geography.NewPosition()
+	v := strings.Fields(s)
+	if len(v) != 2 {
+		return p
+	}
+	lat, err := strconv.ParseFloat(v[0], 64)
+	if err != nil {
+		return p
+	}
+	lon, err := strconv.ParseFloat(v[1], 64)
+	if err != nil {
+		return p
+	}
+	p.Lat, p.Lon = lat, lon
+	return p
+}
+
+// WriteText writes the changes
+// in the standard output
+// as text.
+func writeText(ch []change) error {
+	w := bufio.NewWriter(os.Stdout)
+	for _, c := range ch {
+		switch c.change {
+		case added:
+			fmt.Fprintf(w, "+ %s %s\n", c.kind, c.id)
+		case removed:
+			fmt.Fprintf(w, "- %s %s\n", c.kind, c.id)
+		case keyed:
+			fmt.Fprintf(w, "~ %s %s: %s: %q -> %q\n", c.kind, c.id, c.key, c.old, c.new)
+		default:
+			fmt.Fprintf(w, "~ %s %s: %s: %s -> %s\n", c.kind, c.id, c.change, c.old, c.new)
+		}
+	}
+	return w.Flush()
+}
+
+// WriteStanza writes the changes
+// in the standard output
+// in the stanza format.
+func writeStanza(ch []change) error {
+	w := stanza.NewWriter(os.Stdout)
+	w.SetFields([]string{"kind", "id", "change", "key", "old", "new"})
+	for _, c := range ch {
+		rec := map[string]string{
+			"kind":   c.kind,
+			"id":     c.id,
+			"change": c.change,
+			"key":    c.key,
+			"old":    c.old,
+			"new":    c.new,
+		}
+		if err := w.Write(rec); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
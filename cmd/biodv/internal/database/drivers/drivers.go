@@ -31,6 +31,7 @@ Options are:
       printed.
       Valid database kinds are:
       	dataset   dataset databases
+        gazetteer gazetteer services
         records   specimen record databases
         taxonomy  taxonomic names databases
 	`,
@@ -50,8 +51,11 @@ func run(c *cmdapp.Command, args []string) error {
 		taxDrivers()
 	case "dataset":
 		setDrivers()
+	case "gazetteer":
+		gzDrivers()
 	default:
 		setDrivers()
+		gzDrivers()
 		recDrivers()
 		taxDrivers()
 	}
@@ -81,3 +85,11 @@ func taxDrivers() {
 		fmt.Printf("    %-16s %s\n", dv, biodv.TaxAbout(dv))
 	}
 }
+
+func gzDrivers() {
+	ls := biodv.GzDrivers()
+	fmt.Printf("Gazetteer drivers:\n")
+	for _, dv := range ls {
+		fmt.Printf("    %-16s %s\n", dv, biodv.GzAbout(dv))
+	}
+}
@@ -0,0 +1,395 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package export implements the db.export command,
+// i.e. export the database as a Darwin Core Archive.
+package export
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/js-arias/biodv"
+	"github.com/js-arias/biodv/cmdapp"
+	"github.com/js-arias/biodv/encoding/dwca"
+
+	"github.com/pkg/errors"
+)
+
+var cmd = &cmdapp.Command{
+	UsageLine: `db.export [-o|--output <file>] [-t|--title <title>]
+		[<name>]`,
+	Short: "export the database as a Darwin Core Archive",
+	Long: `
+Command db.export writes the specimen records of the database as a
+Darwin Core Archive (DwC-A), i.e. a zip file that can be published or
+read by any tool that accepts Darwin Core Archives.
+
+The archive contains an occurrence core (occurrence.txt) with the
+specimen records, a taxon extension (taxon.txt) with the taxonomic data
+of the taxon assigned to each record (including its rank, authorship,
+parent, and accepted name if the taxon is a synonym), and an EML
+metadata file (eml.xml) that lists the datasets referenced by the
+records as the sources of the archive.
+
+The taxonID of each taxon is its name in the taxonomy database.
+
+Options are:
+
+    -o <file>
+    --output <file>
+      Sets the name of the archive file. By default the archive will be
+      called 'dwca.zip'.
+
+    -t <title>
+    --title <title>
+      Sets the title of the archive. If the title is a dataset in the
+      dataset database, its metadata will be used as the metadata of
+      the archive. By default the title is 'biodv database'.
+
+    <name>
+      If set, only the records of the indicated taxon (and its
+      descendants) will be exported.
+	`,
+	Run:           run,
+	RegisterFlags: register,
+}
+
+func init() {
+	cmdapp.Add(cmd)
+}
+
+var output string
+var title string
+
+func register(c *cmdapp.Command) {
+	c.Flag.StringVar(&output, "output", "", "")
+	c.Flag.StringVar(&output, "o", "", "")
+	c.Flag.StringVar(&title, "title", "", "")
+	c.Flag.StringVar(&title, "t", "", "")
+}
+
+const dwcNS = "http://rs.tdwg.org/dwc/terms/"
+
+var occTerms = []string{
+	"occurrenceID",
+	"basisOfRecord",
+	"institutionCode",
+	"collectionCode",
+	"catalogNumber",
+	"datasetName",
+	"scientificName",
+	"taxonID",
+	"eventDate",
+	"countryCode",
+	"stateProvince",
+	"county",
+	"locality",
+	"recordedBy",
+	"decimalLatitude",
+	"decimalLongitude",
+	"geodeticDatum",
+	"coordinateUncertaintyInMeters",
+	"minimumElevationInMeters",
+	"georeferenceSources",
+	"georeferenceVerificationStatus",
+	"identifiedBy",
+	"organismID",
+	"sex",
+	"lifeStage",
+	"occurrenceRemarks",
+	"associatedReferences",
+}
+
+var taxTerms = []string{
+	"taxonID",
+	"scientificName",
+	"scientificNameAuthorship",
+	"taxonRank",
+	"taxonomicStatus",
+	"parentNameUsageID",
+	"acceptedNameUsageID",
+}
+
+// BasisTerms are the Darwin Core terms
+// for each basis of record.
+var basisTerms = map[biodv.BasisOfRecord]string{
+	biodv.Preserved:   "PreservedSpecimen",
+	biodv.Fossil:      "FossilSpecimen",
+	biodv.Observation: "HumanObservation",
+	biodv.Machine:     "MachineObservation",
+}
+
+func terms(ls []string) []string {
+	t := make([]string, len(ls))
+	for i, v := range ls {
+		t[i] = dwcNS + v
+	}
+	return t
+}
+
+type exporter struct {
+	txm  biodv.Taxonomy
+	recs biodv.RecDB
+	occ  *dwca.TableWriter
+	tax  *dwca.TableWriter
+	rows []map[string]string // taxon extension rows
+	sets map[string]bool
+}
+
+func run(c *cmdapp.Command, args []string) error {
+	if output == "" {
+		output = "dwca.zip"
+	}
+	if title == "" {
+		title = "biodv database"
+	}
+
+	txm, err := biodv.OpenTax("biodv", "")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	recs, err := biodv.OpenRec("biodv", "")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	sets, err := biodv.OpenSet("biodv", "")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+
+	var ls []biodv.Taxon
+	if nm := strings.Join(args, " "); nm != "" {
+		tax, _ := txm.TaxID(nm)
+		if tax == nil {
+			return errors.Errorf("%s: taxon %q not found", c.Name(), nm)
+		}
+		ls = append(ls, tax)
+	} else {
+		ls, err = biodv.TaxList(txm.Children(""))
+		if err != nil {
+			return errors.Wrap(err, c.Name())
+		}
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	defer f.Close()
+
+	w := dwca.NewWriter(f)
+	ex := &exporter{
+		txm:  txm,
+		recs: recs,
+		sets: make(map[string]bool),
+	}
+	ex.occ, err = w.Create("occurrence.txt", dwcNS+"Occurrence", terms(occTerms))
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	for _, tax := range ls {
+		if err := ex.procTaxon(tax); err != nil {
+			return errors.Wrap(err, c.Name())
+		}
+	}
+
+	ex.tax, err = w.Create("taxon.txt", dwcNS+"Taxon", terms(taxTerms))
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	for _, row := range ex.rows {
+		if err := ex.tax.Write(row); err != nil {
+			return errors.Wrap(err, c.Name())
+		}
+	}
+
+	md, src := ex.metadata(sets)
+	if err := w.WriteEML(md, src); err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	if err := w.Close(); err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	return nil
+}
+
+// ProcTaxon exports the records of a taxon
+// and its descendants.
+func (ex *exporter) procTaxon(tax biodv.Taxon) error {
+	var taxRow map[string]string
+	sc := ex.recs.TaxRecs(tax.ID())
+	for sc.Scan() {
+		r := sc.Record()
+		if taxRow == nil {
+			taxRow = ex.taxonRow(tax)
+		}
+		if err := ex.occ.Write(ex.occRow(r, tax)); err != nil {
+			sc.Close()
+			return err
+		}
+		row := make(map[string]string, len(taxRow))
+		for k, v := range taxRow {
+			row[k] = v
+		}
+		row[dwca.IDKey] = r.ID()
+		ex.rows = append(ex.rows, row)
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+
+	children, err := biodv.TaxList(ex.txm.Children(tax.ID()))
+	if err != nil {
+		return err
+	}
+	syns, err := biodv.TaxList(ex.txm.Synonyms(tax.ID()))
+	if err != nil {
+		return err
+	}
+	children = append(children, syns...)
+	for _, c := range children {
+		if err := ex.procTaxon(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ex *exporter) occRow(r biodv.Record, tax biodv.Taxon) map[string]string {
+	row := map[string]string{
+		dwca.IDKey:             r.ID(),
+		"occurrenceID":         r.ID(),
+		"basisOfRecord":        basisTerms[r.Basis()],
+		"scientificName":       strings.TrimSpace(tax.Name() + " " + tax.Value(biodv.TaxAuthor)),
+		"taxonID":              tax.Name(),
+		"identifiedBy":         r.Value(biodv.RecDeterm),
+		"organismID":           r.Value(biodv.RecOrganism),
+		"sex":                  r.Value(biodv.RecSex),
+		"lifeStage":            r.Value(biodv.RecStage),
+		"occurrenceRemarks":    r.Value(biodv.RecComment),
+		"associatedReferences": r.Value(biodv.RecRef),
+	}
+
+	if cat := r.Value(biodv.RecCatalog); cat != "" {
+		v := strings.SplitN(cat, ":", 3)
+		if len(v) == 3 {
+			row["institutionCode"] = v[0]
+			row["collectionCode"] = v[1]
+			row["catalogNumber"] = v[2]
+		} else {
+			row["catalogNumber"] = cat
+		}
+	}
+	if set := r.Value(biodv.RecDataset); set != "" {
+		row["datasetName"] = set
+		ex.sets[set] = true
+	}
+
+	ev := r.CollEvent()
+	if !ev.Date.IsZero() {
+		d := ev.Date.UTC()
+		if d.Hour() == 0 && d.Minute() == 0 && d.Second() == 0 {
+			row["eventDate"] = d.Format("2006-01-02")
+		} else {
+			row["eventDate"] = ev.Date.Format(time.RFC3339)
+		}
+	}
+	row["countryCode"] = ev.CountryCode()
+	row["stateProvince"] = ev.State()
+	row["county"] = ev.County()
+	row["locality"] = ev.Locality
+	row["recordedBy"] = ev.Collector
+
+	geo := r.GeoRef()
+	if geo.IsValid() {
+		row["decimalLatitude"] = strconv.FormatFloat(geo.Lat, 'f', -1, 64)
+		row["decimalLongitude"] = strconv.FormatFloat(geo.Lon, 'f', -1, 64)
+		row["geodeticDatum"] = "WGS84"
+		if geo.Uncertainty > 0 {
+			row["coordinateUncertaintyInMeters"] = strconv.Itoa(int(geo.Uncertainty))
+		}
+		row["georeferenceSources"] = geo.Source
+		row["georeferenceVerificationStatus"] = geo.Validation
+	}
+	if geo.Elevation > 0 {
+		row["minimumElevationInMeters"] = strconv.Itoa(int(geo.Elevation))
+	}
+	return row
+}
+
+func (ex *exporter) taxonRow(tax biodv.Taxon) map[string]string {
+	row := map[string]string{
+		"taxonID":                  tax.Name(),
+		"scientificName":           strings.TrimSpace(tax.Name() + " " + tax.Value(biodv.TaxAuthor)),
+		"scientificNameAuthorship": tax.Value(biodv.TaxAuthor),
+	}
+	if tax.Rank() != biodv.Unranked {
+		row["taxonRank"] = tax.Rank().String()
+	}
+
+	var p biodv.Taxon
+	if tax.Parent() != "" {
+		p, _ = ex.txm.TaxID(tax.Parent())
+	}
+	if tax.IsCorrect() {
+		row["taxonomicStatus"] = "accepted"
+		row["acceptedNameUsageID"] = tax.Name()
+		if p != nil {
+			row["parentNameUsageID"] = p.Name()
+		}
+		return row
+	}
+	row["taxonomicStatus"] = "synonym"
+	if p != nil {
+		row["acceptedNameUsageID"] = p.Name()
+		if pp, _ := ex.txm.TaxID(p.Parent()); pp != nil {
+			row["parentNameUsageID"] = pp.Name()
+		}
+	}
+	return row
+}
+
+// Metadata returns the metadata of the archive
+// and of the datasets referenced by the records.
+func (ex *exporter) metadata(sets biodv.SetDB) (*dwca.Dataset, []*dwca.Dataset) {
+	md := &dwca.Dataset{Title: title}
+	if set, _ := sets.SetID(title); set != nil {
+		md = toEML(set)
+	}
+
+	ids := make([]string, 0, len(ex.sets))
+	for id := range ex.sets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var src []*dwca.Dataset
+	for _, id := range ids {
+		set, _ := sets.SetID(id)
+		if set == nil {
+			src = append(src, &dwca.Dataset{Title: id})
+			continue
+		}
+		src = append(src, toEML(set))
+	}
+	return md, src
+}
+
+func toEML(set biodv.Dataset) *dwca.Dataset {
+	return &dwca.Dataset{
+		Title:     set.Title(),
+		Abstract:  set.Value(biodv.SetAboutKey),
+		License:   set.Value(biodv.SetLicense),
+		URL:       set.Value(biodv.SetURLKey),
+		Publisher: set.Value(biodv.SetPublisher),
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package log implements the db.log command,
+// i.e. print the change log of the database.
+package log
+
+import (
+	"encoding/csv"
+	"os"
+	"strings"
+
+	"github.com/js-arias/biodv/cmdapp"
+	"github.com/js-arias/biodv/journal"
+
+	"github.com/pkg/errors"
+)
+
+var cmd = &cmdapp.Command{
+	UsageLine: `db.log [-n|--number <number>] [--id <id>]
+		[--noheader]`,
+	Short: "print the change log of the database",
+	Long: `
+Command db.log prints the changes made on the database, as a table
+separated by tabs. Each row of the table is a change of a value, with
+the time of the change, the command used to made the change, the
+database (taxonomy, records, or dataset), the ID of the changed
+element, the key, and the old and new values.
+
+When an element (a taxon, a record, or a dataset) is added, all of its
+values are printed with an empty old value, and when an element is
+deleted, all of its values are printed with an empty new value.
+
+Changes are grouped in operations, i.e. the set of changes committed
+by a single command. Operations made with db.undo are printed with the
+command 'db.undo'.
+
+Options are:
+
+    -n <number>
+    --number <number>
+      If set, only the last indicated number of operations will be
+      printed.
+
+    --id <id>
+      If set, only the changes of the element with the indicated ID
+      will be printed.
+
+    --noheader
+      If set, the table will be printed without the columns header.
+	`,
+	Run:           run,
+	RegisterFlags: register,
+}
+
+func init() {
+	cmdapp.Add(cmd)
+}
+
+var number int
+var id string
+var nohead bool
+
+func register(c *cmdapp.Command) {
+	c.Flag.IntVar(&number, "number", 0, "")
+	c.Flag.IntVar(&number, "n", 0, "")
+	c.Flag.StringVar(&id, "id", "", "")
+	c.Flag.BoolVar(&nohead, "noheader", false, "")
+}
+
+func run(c *cmdapp.Command, args []string) error {
+	ops, err := journal.ReadLog("")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	if number > 0 && number < len(ops) {
+		ops = ops[len(ops)-number:]
+	}
+	id = strings.TrimSpace(id)
+
+	w := csv.NewWriter(os.Stdout)
+	w.Comma = '\t'
+	w.UseCRLF = true
+	if !nohead {
+		if err := w.Write([]string{"Time", "Command", "Database", "ID", "Key", "Old", "New"}); err != nil {
+			return errors.Wrap(err, c.Name())
+		}
+	}
+	for _, o := range ops {
+		for _, ch := range o.Changes {
+			if id != "" && ch.ID != id {
+				continue
+			}
+			row := []string{o.ID, o.Command, ch.DB, ch.ID, ch.Key, ch.Old, ch.New}
+			if err := w.Write(row); err != nil {
+				return errors.Wrap(err, c.Name())
+			}
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	return nil
+}
@@ -0,0 +1,474 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package merge implements the db.merge command,
+// i.e. merge the database of another project.
+package merge
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/js-arias/biodv"
+	"github.com/js-arias/biodv/cmdapp"
+	"github.com/js-arias/biodv/dataset"
+	"github.com/js-arias/biodv/journal"
+	"github.com/js-arias/biodv/records"
+	"github.com/js-arias/biodv/taxonomy"
+
+	"github.com/pkg/errors"
+)
+
+var cmd = &cmdapp.Command{
+	UsageLine: "db.merge [-n|--noheader] <dir>",
+	Short:     "merge the database of another project",
+	Long: `
+Command db.merge adds the taxonomy, the specimen records, and the
+datasets of the biodv project stored in another directory, into the
+current project.
+
+A taxon of the other project is matched with a taxon of the current
+project if any of its extern IDs is used by a taxon of the current
+project, or if both taxa have the same name, and the same author (if
+both taxa have an author). If the taxa have the same name, but
+different authors, the taxa are homonyms, and the taxon of the other
+project, and its descendants, are not added. Empty values of the
+matched taxa will be filled with the values of the other project, and
+taxa without a match will be added to the current project, with the
+same rank and status, attached to the match of its parent. Datasets
+are matched by its ID, or its extern IDs.
+
+Specimen records of the other project are added to the match of its
+taxon. If the ID of a record is already used in the current project,
+the record will be added with a new ID (usually its catalog number).
+Records with a catalog number, or an extern ID, already used in the
+current project are not added, as they are likely duplicates of
+records of the current project.
+
+Conflicts between both projects are printed in the standard output
+as a table separated by tabs, with the kind of the element ('taxon',
+'record', or 'dataset'), its ID in the other project, the kind of the
+conflict, and the value in the current project, and in the other
+project. Kinds of conflicts are:
+
+    author      the taxa, matched by an extern ID, have different
+                authors.
+    correct     the taxa have different status (correct or synonym).
+    extern      an extern ID is matched with a different element.
+    homonym     the taxa have the same name, but different authors.
+    not-added   the element was not added.
+    parent      the taxa have different parents.
+    rank        the taxa have different ranks.
+    re-keyed    the record was added with a new ID.
+
+In all conflicts, except for 're-keyed', 'homonym', and 'not-added',
+the values of the current project are kept. The conflicts of taxa
+should be reviewed by the user, for example with tax.ed.
+
+Options are:
+
+    -n
+    --noheader
+      If set, the conflicts table will be printed without the columns
+      header.
+
+    <dir>
+      The directory of the project to be merged. Required.
+	`,
+	Run:           run,
+	RegisterFlags: register,
+}
+
+func init() {
+	cmdapp.Add(cmd)
+}
+
+var nohead bool
+
+func register(c *cmdapp.Command) {
+	c.Flag.BoolVar(&nohead, "noheader", false, "")
+	c.Flag.BoolVar(&nohead, "n", false, "")
+}
+
+// Merger stores the databases
+// of both projects.
+type merger struct {
+	txm, otxm   *taxonomy.DB
+	recs, orecs *records.DB
+	sets, osets *dataset.DB
+
+	// local names of taxa
+	// of the other project
+	taxa map[string]string
+
+	// local IDs of datasets
+	// of the other project
+	dsets map[string]string
+
+	w *csv.Writer
+}
+
+func run(c *cmdapp.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.Errorf("%s: a project directory should be given", c.Name())
+	}
+	dir := args[0]
+	if sameDir(dir, ".") {
+		return errors.Errorf("%s: can not merge a project with itself", c.Name())
+	}
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		return errors.Errorf("%s: %s: not a project directory", c.Name(), dir)
+	}
+
+	m := &merger{
+		taxa:  make(map[string]string),
+		dsets: make(map[string]string),
+	}
+	var err error
+	if m.otxm, err = taxonomy.Open(dir); err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	if m.orecs, err = records.Open(dir); err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	if m.osets, err = dataset.Open(dir); err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	if m.txm, err = taxonomy.Open(""); err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	if m.recs, err = records.Open(""); err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	if m.sets, err = dataset.Open(""); err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+
+	m.w = csv.NewWriter(os.Stdout)
+	m.w.Comma = '\t'
+	m.w.UseCRLF = true
+	if !nohead {
+		m.report("Kind", "ID", "Conflict", "Local", "Merged")
+	}
+
+	for _, tax := range m.otxm.TaxList("") {
+		if err := m.mergeTaxon(tax, ""); err != nil {
+			return errors.Wrap(err, c.Name())
+		}
+	}
+	for _, set := range m.osets.List() {
+		if err := m.mergeSet(set); err != nil {
+			return errors.Wrap(err, c.Name())
+		}
+	}
+	for _, id := range m.orecs.Taxa() {
+		for _, r := range m.orecs.RecList(id) {
+			if err := m.mergeRecord(r); err != nil {
+				return errors.Wrap(err, c.Name())
+			}
+		}
+	}
+
+	m.w.Flush()
+	if err := m.w.Error(); err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+
+	tx, err := journal.Begin("")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	if err := m.txm.CommitTx(tx); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, c.Name())
+	}
+	if err := m.recs.CommitTx(tx); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, c.Name())
+	}
+	if err := m.sets.CommitTx(tx); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, c.Name())
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	return nil
+}
+
+// SameDir returns true
+// if two paths are the same directory.
+func sameDir(a, b string) bool {
+	fa, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	fb, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	if os.SameFile(fa, fb) {
+		return true
+	}
+	pa, _ := filepath.Abs(a)
+	pb, _ := filepath.Abs(b)
+	return pa == pb
+}
+
+// Report writes a conflict
+// in the report.
+func (m *merger) report(kind, id, conflict, local, other string) {
+	m.w.Write([]string{kind, id, conflict, local, other})
+}
+
+// MergeTaxon merges a taxon,
+// and its descendants,
+// attached to the given local parent.
+func (m *merger) mergeTaxon(tax *taxonomy.Taxon, parent string) error {
+	loc, hom := m.matchTaxon(tax)
+	if hom != nil {
+		m.report("taxon", tax.Name(), "homonym", hom.Value(biodv.TaxAuthor), tax.Value(biodv.TaxAuthor))
+		m.skipTaxon(tax)
+		return nil
+	}
+	if loc == nil {
+		var err error
+		loc, err = m.txm.Add(tax.Name(), parent, tax.Rank(), tax.IsCorrect())
+		if err != nil {
+			m.report("taxon", tax.Name(), "not-added", "", err.Error())
+			m.skipTaxon(tax)
+			return nil
+		}
+	} else {
+		m.compareTaxon(loc, tax, parent)
+	}
+	m.taxa[tax.Name()] = loc.Name()
+
+	for _, k := range tax.Keys() {
+		v := tax.Value(k)
+		if k == biodv.TaxExtern {
+			for _, e := range strings.Fields(v) {
+				if m.txm.TaxEd(e) != nil {
+					continue
+				}
+				if hasService(loc.Value(k), e) {
+					continue
+				}
+				if err := loc.Set(k, e); err != nil {
+					m.report("taxon", tax.Name(), "extern", "", e)
+				}
+			}
+			continue
+		}
+		if loc.Value(k) != "" {
+			continue
+		}
+		if err := loc.Set(k, v); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range m.otxm.TaxList(tax.Name()) {
+		if err := m.mergeTaxon(c, loc.Name()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SkipTaxon reports the descendants
+// of a taxon that is not added.
+func (m *merger) skipTaxon(tax *taxonomy.Taxon) {
+	for _, c := range m.otxm.TaxList(tax.Name()) {
+		m.report("taxon", c.Name(), "not-added", "", "parent not added")
+		m.skipTaxon(c)
+	}
+}
+
+// MatchTaxon returns the local taxon
+// that match a taxon of the other project.
+// If a local taxon has the same name,
+// but a different author,
+// it is returned as an homonym.
+func (m *merger) matchTaxon(tax *taxonomy.Taxon) (loc, homonym *taxonomy.Taxon) {
+	byName := m.txm.TaxEd(tax.Name())
+	for _, e := range strings.Fields(tax.Value(biodv.TaxExtern)) {
+		loc := m.txm.TaxEd(e)
+		if loc == nil {
+			continue
+		}
+		if byName != nil && byName != loc {
+			m.report("taxon", tax.Name(), "extern", loc.Name(), e)
+		}
+		return loc, nil
+	}
+	if byName == nil {
+		return nil, nil
+	}
+	la, ta := byName.Value(biodv.TaxAuthor), tax.Value(biodv.TaxAuthor)
+	if la != "" && ta != "" && normAuthor(la) != normAuthor(ta) {
+		return nil, byName
+	}
+	return byName, nil
+}
+
+// CompareTaxon reports the differences
+// between a matched taxon.
+func (m *merger) compareTaxon(loc, tax *taxonomy.Taxon, parent string) {
+	la, ta := loc.Value(biodv.TaxAuthor), tax.Value(biodv.TaxAuthor)
+	if la != "" && ta != "" && normAuthor(la) != normAuthor(ta) {
+		m.report("taxon", tax.Name(), "author", la, ta)
+	}
+	if loc.Parent() != parent {
+		m.report("taxon", tax.Name(), "parent", loc.Parent(), parent)
+	}
+	if loc.Rank() != tax.Rank() {
+		m.report("taxon", tax.Name(), "rank", loc.Rank().String(), tax.Rank().String())
+	}
+	if loc.IsCorrect() != tax.IsCorrect() {
+		m.report("taxon", tax.Name(), "correct", strconv.FormatBool(loc.IsCorrect()), strconv.FormatBool(tax.IsCorrect()))
+	}
+}
+
+// NormAuthor returns an author string
+// without spaces, parenthesis,
+// and punctuation.
+func normAuthor(a string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, a)
+}
+
+// HasService returns true
+// if an extern ID of the same service
+// is in a list of extern IDs.
+//
+// If the ID has no service,
+// it returns true
+// only if the same ID is in the list.
+func hasService(ls, id string) bool {
+	i := strings.Index(id, ":")
+	if i < 0 {
+		for _, e := range strings.Fields(ls) {
+			if e == id {
+				return true
+			}
+		}
+		return false
+	}
+	srv := id[:i+1]
+	for _, e := range strings.Fields(ls) {
+		if strings.HasPrefix(e, srv) {
+			return true
+		}
+	}
+	return false
+}
+
+// MergeSet merges a dataset.
+func (m *merger) mergeSet(set *dataset.Dataset) error {
+	loc := m.sets.SetEd(set.ID())
+	for _, e := range strings.Fields(set.Value(biodv.SetExtern)) {
+		if l := m.sets.SetEd(e); l != nil {
+			if loc != nil && loc != l {
+				m.report("dataset", set.ID(), "extern", l.ID(), e)
+			}
+			loc = l
+			break
+		}
+	}
+	if loc == nil {
+		var err error
+		loc, err = m.sets.Add(set.Title())
+		if err != nil {
+			return err
+		}
+	}
+	m.dsets[set.ID()] = loc.ID()
+
+	for _, k := range set.Keys() {
+		v := set.Value(k)
+		if k == biodv.SetExtern {
+			for _, e := range strings.Fields(v) {
+				if m.sets.SetEd(e) != nil {
+					continue
+				}
+				if hasService(loc.Value(k), e) {
+					continue
+				}
+				if err := loc.Set(k, e); err != nil {
+					m.report("dataset", set.ID(), "extern", "", e)
+				}
+			}
+			continue
+		}
+		if loc.Value(k) != "" {
+			continue
+		}
+		if err := loc.Set(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MergeRecord merges a specimen record.
+func (m *merger) mergeRecord(r *records.Record) error {
+	tax := m.taxa[r.Taxon()]
+	if tax == "" {
+		m.report("record", r.ID(), "not-added", "", "taxon "+r.Taxon()+" not added")
+		return nil
+	}
+	cat := r.Value(biodv.RecCatalog)
+	if cat != "" {
+		if l := m.recs.Record(cat); l != nil {
+			m.report("record", r.ID(), "not-added", l.ID(), "catalog "+cat+" already in use")
+			return nil
+		}
+	}
+	for _, e := range strings.Fields(r.Value(biodv.RecExtern)) {
+		if l := m.recs.Record(e); l != nil {
+			m.report("record", r.ID(), "not-added", l.ID(), "extern "+e+" already in use")
+			return nil
+		}
+	}
+
+	id := r.ID()
+	if m.recs.Record(id) != nil {
+		id = ""
+	}
+	geo := r.GeoRef()
+	rec, err := m.recs.Add(tax, id, cat, r.Basis(), geo.Lat, geo.Lon)
+	if err != nil {
+		return err
+	}
+	if rec.ID() != r.ID() {
+		m.report("record", r.ID(), "re-keyed", "", rec.ID())
+	}
+	rec.SetCollEvent(r.CollEvent())
+	rec.SetGeoRef(geo)
+	for _, k := range r.Keys() {
+		v := r.Value(k)
+		if k == biodv.RecDataset {
+			if set := m.osets.SetEd(v); set != nil {
+				if id, ok := m.dsets[set.ID()]; ok {
+					v = id
+				}
+			}
+		}
+		if err := rec.Set(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
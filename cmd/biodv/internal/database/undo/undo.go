@@ -0,0 +1,147 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package undo implements the db.undo command,
+// i.e. revert the last operations on the database.
+package undo
+
+import (
+	"strconv"
+
+	"github.com/js-arias/biodv/cmdapp"
+	"github.com/js-arias/biodv/dataset"
+	"github.com/js-arias/biodv/journal"
+	"github.com/js-arias/biodv/records"
+	"github.com/js-arias/biodv/taxonomy"
+
+	"github.com/pkg/errors"
+)
+
+var cmd = &cmdapp.Command{
+	UsageLine: "db.undo [<number>]",
+	Short:     "revert the last operations on the database",
+	Long: `
+Command db.undo reverts the last operations stored in the change log
+of the database (see db.log). An operation is the set of changes
+committed by a single command. By default only the last operation is
+reverted.
+
+The reversion is itself stored in the change log, and the reverted
+operations (as well as the db.undo operations) will be skipped by
+subsequent calls to db.undo, so calling db.undo twice reverts the last
+two operations.
+
+Changes made on the database files without using biodv are not stored
+in the change log, so reverting operations on files modified by hand
+might produce unexpected results.
+
+Options are:
+
+    <number>
+      If set, the indicated number of operations will be reverted.
+	`,
+	Run: run,
+}
+
+func init() {
+	cmdapp.Add(cmd)
+}
+
+func run(c *cmdapp.Command, args []string) error {
+	n := 1
+	if len(args) > 1 {
+		return errors.Errorf("%s: too many arguments", c.Name())
+	}
+	if len(args) == 1 {
+		v, err := strconv.Atoi(args[0])
+		if err != nil || v < 1 {
+			return errors.Errorf("%s: invalid number of operations: %s", c.Name(), args[0])
+		}
+		n = v
+	}
+
+	// the database is locked
+	// while reading the log
+	tx, err := journal.Begin("")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	if err := revert(tx, n); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, c.Name())
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	return nil
+}
+
+// Revert reverts the last n operations.
+func revert(tx *journal.Tx, n int) error {
+	ops, err := journal.ReadLog("")
+	if err != nil {
+		return err
+	}
+	ops = journal.Undoable(ops, n)
+	if len(ops) == 0 {
+		return errors.New("no operations to revert")
+	}
+
+	var ch []journal.Change
+	var ids []string
+	dbs := make(map[string]bool)
+	for _, o := range ops {
+		ch = append(ch, o.Changes...)
+		ids = append(ids, o.ID)
+		for _, e := range o.Changes {
+			dbs[e.DB] = true
+		}
+	}
+	tx.Reverts(ids...)
+	return undo(tx, dbs, ch)
+}
+
+// Undo reverts the changes
+// on each database.
+func undo(tx *journal.Tx, dbs map[string]bool, ch []journal.Change) error {
+	if dbs["taxonomy"] {
+		txm, err := taxonomy.Open("")
+		if err != nil {
+			return err
+		}
+		if err := txm.Undo(ch); err != nil {
+			return err
+		}
+		if err := txm.CommitTx(tx); err != nil {
+			return err
+		}
+	}
+	if dbs["records"] {
+		recs, err := records.Open("")
+		if err != nil {
+			return err
+		}
+		if err := recs.Undo(ch); err != nil {
+			return err
+		}
+		if err := recs.CommitTx(tx); err != nil {
+			return err
+		}
+	}
+	if dbs["dataset"] {
+		sets, err := dataset.Open("")
+		if err != nil {
+			return err
+		}
+		if err := sets.Undo(ch); err != nil {
+			return err
+		}
+		if err := sets.CommitTx(tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
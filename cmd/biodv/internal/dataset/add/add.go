@@ -0,0 +1,58 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package add implements the set.add command,
+// i.e. add a dataset to the database.
+package add
+
+import (
+	"strings"
+
+	"github.com/js-arias/biodv/cmdapp"
+	"github.com/js-arias/biodv/dataset"
+
+	"github.com/pkg/errors"
+)
+
+var cmd = &cmdapp.Command{
+	UsageLine: "set.add <title>",
+	Short:     "add a dataset to the database",
+	Long: `
+Command set.add adds a new dataset to the database. The title of the
+dataset will be used as its ID, so it must be unique in the database.
+
+To set the values of the dataset, use set.set.
+
+Options are:
+
+    <title>
+      The title of the new dataset.
+	`,
+	Run: run,
+}
+
+func init() {
+	cmdapp.Add(cmd)
+}
+
+func run(c *cmdapp.Command, args []string) error {
+	title := strings.Join(args, " ")
+	if title == "" {
+		return errors.Errorf("%s: a dataset title should be given", c.Name())
+	}
+
+	sets, err := dataset.Open("")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	if _, err := sets.Add(title); err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	if err := sets.Commit(); err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package del implements the set.del command,
+// i.e. eliminate a dataset from the database.
+package del
+
+import (
+	"strings"
+
+	"github.com/js-arias/biodv"
+	"github.com/js-arias/biodv/cmdapp"
+	"github.com/js-arias/biodv/dataset"
+	"github.com/js-arias/biodv/journal"
+	"github.com/js-arias/biodv/records"
+
+	"github.com/pkg/errors"
+)
+
+var cmd = &cmdapp.Command{
+	UsageLine: "set.del [--to <dataset>] <dataset>",
+	Short:     "eliminate a dataset from the database",
+	Long: `
+Command set.del removes a dataset from the database.
+
+If there are specimen records that reference the dataset, the dataset
+will not be deleted, unless the option --to is used. In that case, the
+records will be reassigned to the indicated dataset before deleting the
+dataset.
+
+Options are:
+
+    --to <dataset>
+      If set, the records of the deleted dataset will be assigned
+      to the indicated dataset, that must be already in the
+      database.
+
+    <dataset>
+      The dataset to be deleted, it can be the dataset ID, or one of
+      its extern IDs.
+	`,
+	Run:           run,
+	RegisterFlags: register,
+}
+
+func init() {
+	cmdapp.Add(cmd)
+}
+
+var to string
+
+func register(c *cmdapp.Command) {
+	c.Flag.StringVar(&to, "to", "", "")
+}
+
+func run(c *cmdapp.Command, args []string) error {
+	id := strings.Join(args, " ")
+	if id == "" {
+		return errors.Errorf("%s: a dataset should be defined", c.Name())
+	}
+
+	sets, err := dataset.Open("")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	set := sets.SetEd(id)
+	if set == nil {
+		return errors.Errorf("%s: dataset %q not in database", c.Name(), id)
+	}
+	var dest *dataset.Dataset
+	if to != "" {
+		dest = sets.SetEd(to)
+		if dest == nil {
+			return errors.Errorf("%s: dataset %q not in database", c.Name(), to)
+		}
+		if dest == set {
+			return errors.Errorf("%s: dataset %q can not be reassigned to itself", c.Name(), set.ID())
+		}
+	}
+
+	recs, err := records.Open("")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	var ls []*records.Record
+	for _, tid := range recs.Taxa() {
+		for _, r := range recs.RecList(tid) {
+			if sets.SetEd(r.Value(biodv.RecDataset)) == set {
+				ls = append(ls, r)
+			}
+		}
+	}
+
+	if len(ls) > 0 {
+		if dest == nil {
+			return errors.Errorf("%s: dataset %q has %d records, use --to to reassign them", c.Name(), set.ID(), len(ls))
+		}
+		for _, r := range ls {
+			if err := r.Set(biodv.RecDataset, dest.ID()); err != nil {
+				return errors.Wrap(err, c.Name())
+			}
+		}
+	}
+	sets.Delete(set.ID())
+
+	tx, err := journal.Begin("")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	if err := recs.CommitTx(tx); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, c.Name())
+	}
+	if err := sets.CommitTx(tx); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, c.Name())
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package list implements the set.list command,
+// i.e. print a list of datasets.
+package list
+
+import (
+	"fmt"
+
+	"github.com/js-arias/biodv"
+	"github.com/js-arias/biodv/cmdapp"
+	"github.com/js-arias/biodv/dataset"
+	"github.com/js-arias/biodv/records"
+
+	"github.com/pkg/errors"
+)
+
+var cmd = &cmdapp.Command{
+	UsageLine: "set.list",
+	Short:     "print a list of datasets",
+	Long: `
+Command set.list prints the list of the datasets in the database, with
+the number of specimen records of each dataset, separated by a tab.
+
+Records that reference a dataset that is not in the database are
+counted in an additional line with the ID of that dataset, and records
+without dataset are not counted.
+	`,
+	Run: run,
+}
+
+func init() {
+	cmdapp.Add(cmd)
+}
+
+func run(c *cmdapp.Command, args []string) error {
+	sets, err := dataset.Open("")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	recs, err := records.Open("")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+
+	count := make(map[string]int)
+	var unknown []string
+	for _, tid := range recs.Taxa() {
+		for _, r := range recs.RecList(tid) {
+			v := r.Value(biodv.RecDataset)
+			if v == "" {
+				continue
+			}
+			id := v
+			if set := sets.SetEd(v); set != nil {
+				id = set.ID()
+			} else if _, ok := count[v]; !ok {
+				unknown = append(unknown, v)
+			}
+			count[id]++
+		}
+	}
+
+	for _, set := range sets.List() {
+		fmt.Printf("%s\t%d\n", set.ID(), count[set.ID()])
+	}
+	for _, id := range unknown {
+		fmt.Printf("%s\t%d\n", id, count[id])
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package set implements the set.set command,
+// i.e. set a dataset value.
+package set
+
+import (
+	"strings"
+
+	"github.com/js-arias/biodv/cmdapp"
+	"github.com/js-arias/biodv/dataset"
+
+	"github.com/pkg/errors"
+)
+
+var cmd = &cmdapp.Command{
+	UsageLine: "set.set -k|--key <key> [-v|--value <value>] <dataset>",
+	Short:     "set a dataset value",
+	Long: `
+Command set.set sets the value of a given key for the indicated dataset,
+overwritting any previous value. If the value is empty, the content of
+the key will be eliminated.
+
+The title of a dataset can not be changed.
+
+Except for some standard keys, no content of the values will be evaluated
+by the program or the database.
+
+Options are:
+
+    -k <key>
+    --key <key>
+      A key, a required parameter. Keys must be in lower case and
+      without spaces  (it will be reformatted to lower case, and spaces
+      between words replaced by the dash ‘-’ character). Any key can
+      be stored, but the recognized keys (and their expected values)
+      are:
+        about       a description of the dataset.
+        extern      extern identifier of the dataset, in the form
+                    <service>:<key>, for example 'gbif:1234'. Only
+                    one extern ID per service is stored. If the key is
+                    empty (for example 'gbif:') the extern ID of that
+                    service will be eliminated.
+        license     the license of the dataset.
+        publisher   the publisher of the dataset.
+        reference   a bibliographic reference of the dataset.
+        url         the URL of the dataset.
+      For a set of available keys of a given dataset, use set.info.
+
+    -v <value>
+    --value <value>
+      The value to set. If no value is defined, or an empty string is
+      given, the value on that key will be deleted.
+
+    <dataset>
+      The dataset to be set, it can be the dataset ID, or one of its
+      extern IDs.
+	`,
+	Run:           run,
+	RegisterFlags: register,
+}
+
+func init() {
+	cmdapp.Add(cmd)
+}
+
+var key string
+var value string
+
+func register(c *cmdapp.Command) {
+	c.Flag.StringVar(&key, "key", "", "")
+	c.Flag.StringVar(&key, "k", "", "")
+	c.Flag.StringVar(&value, "value", "", "")
+	c.Flag.StringVar(&value, "v", "", "")
+}
+
+func run(c *cmdapp.Command, args []string) error {
+	if key == "" {
+		return errors.Errorf("%s: a key should be defined", c.Name())
+	}
+	key = strings.ToLower(key)
+
+	id := strings.Join(args, " ")
+	if id == "" {
+		return errors.Errorf("%s: a dataset should be defined", c.Name())
+	}
+
+	sets, err := dataset.Open("")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	set := sets.SetEd(id)
+	if set == nil {
+		return errors.Errorf("%s: dataset %q not in database", c.Name(), id)
+	}
+	if err := set.Set(key, value); err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	if err := sets.Commit(); err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	return nil
+}
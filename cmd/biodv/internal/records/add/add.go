@@ -10,6 +10,7 @@ package add
 
 import (
 	"encoding/csv"
+	"fmt"
 	"io"
 	"os"
 	"strconv"
@@ -20,13 +21,15 @@ import (
 	"github.com/js-arias/biodv/cmdapp"
 	"github.com/js-arias/biodv/geography"
 	"github.com/js-arias/biodv/records"
+	"github.com/js-arias/biodv/taxonomy"
 
 	"github.com/pkg/errors"
 )
 
 var cmd = &cmdapp.Command{
-	UsageLine: "rec.add [-g|--georef] [-l|--locatable] [<file>...]",
-	Short:     "add specimen records",
+	UsageLine: `rec.add [-g|--georef] [-l|--locatable] [-m|--match]
+		[<file>...]`,
+	Short: "add specimen records",
 	Long: `
 Command rec.add adds one or more records from the indicated files, or
 the standard input (if no file is defined) to the specimen records
@@ -91,11 +94,22 @@ Options are:
       georeferenced or with a complete description of the locality)
       will be stored.
 
+    -m
+    --match
+      If set, the taxon names that are not in the taxonomy database
+      will be searched with approximate matching (see tax.match), so
+      misspelled names, or names with a different gender ending, will
+      be assigned to the taxon in the database. A name will be replaced
+      only if it has a single best candidate, and a warning will be
+      printed in the standard error. Names without a match, or with
+      ambiguous matches, are kept as given.
+
     <file>
       One or more files to be processed by rec.add. If no file is given,
       the data will be read from the standard input.
 	`,
-	Run: run,
+	Run:           run,
+	RegisterFlags: register,
 }
 
 func init() {
@@ -104,12 +118,15 @@ func init() {
 
 var georef bool
 var locatable bool
+var match bool
 
 func register(c *cmdapp.Command) {
 	c.Flag.BoolVar(&georef, "georef", false, "")
 	c.Flag.BoolVar(&georef, "g", false, "")
 	c.Flag.BoolVar(&locatable, "locatable", false, "")
 	c.Flag.BoolVar(&locatable, "l", false, "")
+	c.Flag.BoolVar(&match, "match", false, "")
+	c.Flag.BoolVar(&match, "m", false, "")
 }
 
 func run(c *cmdapp.Command, args []string) error {
@@ -117,13 +134,20 @@ func run(c *cmdapp.Command, args []string) error {
 	if err != nil {
 		return errors.Wrap(err, c.Name())
 	}
+	var txm *taxonomy.DB
+	if match {
+		txm, err = taxonomy.Open("")
+		if err != nil {
+			return errors.Wrap(err, c.Name())
+		}
+	}
 
 	if len(args) == 0 {
 		args = append(args, "-")
 	}
 	for _, a := range args {
 		if a == "-" {
-			if err := read(recs, os.Stdin); err != nil {
+			if err := read(recs, os.Stdin, txm); err != nil {
 				return errors.Wrapf(err, "%s: while reading from stdin", c.Name())
 			}
 			continue
@@ -132,7 +156,7 @@ func run(c *cmdapp.Command, args []string) error {
 		if err != nil {
 			return errors.Wrapf(err, "%s: unable to open %s", c.Name(), a)
 		}
-		err = read(recs, f)
+		err = read(recs, f, txm)
 		f.Close()
 		if err != nil {
 			return errors.Wrapf(err, "%s: while reading from %s", c.Name(), a)
@@ -145,7 +169,11 @@ func run(c *cmdapp.Command, args []string) error {
 	return nil
 }
 
-func read(recs *records.DB, in io.Reader) error {
+// Read reads the records from a reader.
+// If txm is not nil,
+// the taxon names not found in the taxonomy
+// are replaced by its best match.
+func read(recs *records.DB, in io.Reader, txm *taxonomy.DB) error {
 	r := csv.NewReader(in)
 	r.Comma = '\t'
 	r.Comment = '#'
@@ -183,6 +211,9 @@ func read(recs *records.DB, in io.Reader) error {
 		if nm == "" {
 			continue
 		}
+		if txm != nil {
+			nm = matchName(txm, nm, i)
+		}
 		id := ""
 		if c, ok := cols["id"]; ok {
 			id = row[c]
@@ -278,6 +309,28 @@ func read(recs *records.DB, in io.Reader) error {
 	}
 }
 
+// MatchName returns the name of the taxon
+// that matches a name,
+// or the same name,
+// if it is in the taxonomy,
+// or it has no unambiguous match.
+func matchName(txm *taxonomy.DB, nm string, row int) string {
+	if tax, _ := txm.TaxID(nm); tax != nil {
+		return nm
+	}
+	ls := txm.Match(nm)
+	if len(ls) == 0 {
+		fmt.Fprintf(os.Stderr, "warning: row %d: taxon %q without match\n", row, nm)
+		return nm
+	}
+	if len(ls) > 1 && ls[0].Score == ls[1].Score {
+		fmt.Fprintf(os.Stderr, "warning: row %d: taxon %q ambiguous\n", row, nm)
+		return nm
+	}
+	fmt.Fprintf(os.Stderr, "warning: row %d: taxon %q matched as %q (score %.2f)\n", row, nm, ls[0].Taxon.Name(), ls[0].Score)
+	return ls[0].Taxon.Name()
+}
+
 // IsLocatable returns true if the record is locatable.
 func isLocatable(pt geography.Position, ev biodv.CollectionEvent) bool {
 	if pt.IsValid() {
@@ -42,7 +42,7 @@ func TestRecAddCmdRead(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if err := read(recs, r); err != nil {
+	if err := read(recs, r, nil); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	ls, err := getRecList(recs.TaxRecs("Rhododendron kawakamii"))
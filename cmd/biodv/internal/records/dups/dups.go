@@ -0,0 +1,268 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package dups implements the rec.dups command,
+// i.e. find duplicated specimen records.
+package dups
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/js-arias/biodv"
+	"github.com/js-arias/biodv/cmdapp"
+	"github.com/js-arias/biodv/records"
+
+	"github.com/pkg/errors"
+)
+
+var cmd = &cmdapp.Command{
+	UsageLine: "rec.dups [-m|--merge] [<name>]",
+	Short:     "find duplicated specimen records",
+	Long: `
+Command rec.dups searches, for each taxon, the specimen records that are
+likely duplicates, for example, a specimen added from a collection
+database with rec.add, and then added again from an extern service
+with rec.db.add.
+
+Two records are taken as duplicates if their catalog numbers are
+equivalent (i.e. ignoring case, punctuation, leading zeros, and
+institution or collection prefixes, so 'MLP:ENT:0123' is equivalent to
+'ent 123', but a bare number, as '123', is only equivalent to the same
+number), or if they do not have catalog numbers (or only one of them
+has it) and they were collected at the same date by the same collector.
+In both cases, if both records are georeferenced, the distance between
+them should be smaller than the sum of their uncertainties, plus 1 km.
+The records of a taxon include the records assigned to its synonyms,
+but not the records assigned to its descendants, that are analyzed
+separately.
+
+By default, the duplicates are printed in the standard output, one pair
+per line, with the taxon, the ID of both records, and the reason by which
+the records are taken as duplicates ('catalog' or 'collector'),
+separated by tabs.
+
+If the option --merge is defined, each pair of duplicates will be shown,
+and the user will be asked for the record to be kept. The empty values
+of the kept record will be filled with the values of the other record,
+the extern IDs of both records will be kept, and then the other record
+will be deleted. If both records have an extern ID of the same service,
+the records can not be merged, so the error will be printed and the
+pair will be skipped. The merges are written to the database only when
+all the duplicates are reviewed, or when the user exits (e). If the
+user quits (q), the program ends without making any change.
+
+Options are:
+
+    -m
+    --merge
+      If set, the duplicates will be merged interactively.
+
+    <name>
+      If set, only the records of the indicated taxon (and its
+      descendants) will be searched.
+	`,
+	Run:           run,
+	RegisterFlags: register,
+}
+
+func init() {
+	cmdapp.Add(cmd)
+}
+
+var merge bool
+
+func register(c *cmdapp.Command) {
+	c.Flag.BoolVar(&merge, "merge", false, "")
+	c.Flag.BoolVar(&merge, "m", false, "")
+}
+
+var inter *cmdapp.Inter
+
+// ErrQuit is returned
+// when the user quits the merge,
+// and errExit when the user exits,
+// i.e. it quits,
+// keeping the merges made so far.
+var (
+	errQuit = errors.New("quit")
+	errExit = errors.New("exit")
+)
+
+func run(c *cmdapp.Command, args []string) error {
+	txm, err := biodv.OpenTax("biodv", "")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	recs, err := records.Open("")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+
+	var ls []biodv.Taxon
+	if nm := strings.Join(args, " "); nm != "" {
+		tax, _ := txm.TaxID(nm)
+		if tax == nil {
+			return errors.Errorf("%s: taxon %q not found", c.Name(), nm)
+		}
+		ls = append(ls, tax)
+	} else {
+		ls, err = biodv.TaxList(txm.Children(""))
+		if err != nil {
+			return errors.Wrap(err, c.Name())
+		}
+	}
+
+	if merge {
+		inter = cmdapp.NewInter(os.Stdin, nil)
+	}
+	for _, tax := range ls {
+		err = procTaxon(txm, recs, tax)
+		if err != nil {
+			break
+		}
+	}
+	if err == errQuit {
+		return nil
+	}
+	if err != nil && err != errExit {
+		return errors.Wrap(err, c.Name())
+	}
+
+	if !merge {
+		return nil
+	}
+	if err := recs.Commit(); err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	return nil
+}
+
+// ProcTaxon searches the duplicates
+// in the records of a taxon,
+// and its descendants.
+func procTaxon(txm biodv.Taxonomy, recs *records.DB, tax biodv.Taxon) error {
+	rs := recs.RecList(tax.ID())
+	syns, _ := biodv.TaxList(txm.Synonyms(tax.ID()))
+	for _, s := range syns {
+		rs = append(rs, recs.RecList(s.ID())...)
+	}
+
+	deleted := make(map[*records.Record]bool)
+	for i, r := range rs {
+		for _, o := range rs[i+1:] {
+			if deleted[r] {
+				break
+			}
+			if deleted[o] {
+				continue
+			}
+			reason := records.Duplicate(r, o)
+			if reason == "" {
+				continue
+			}
+			if !merge {
+				fmt.Printf("%s\t%s\t%s\t%s\n", tax.Name(), r.ID(), o.ID(), reason)
+				continue
+			}
+			del, err := mergeRecs(recs, r, o, reason)
+			if err != nil {
+				return err
+			}
+			if del != nil {
+				deleted[del] = true
+			}
+		}
+	}
+
+	children, _ := biodv.TaxList(txm.Children(tax.ID()))
+	for _, c := range children {
+		if err := procTaxon(txm, recs, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MergeRecs asks the user
+// to merge a pair of duplicates,
+// and returns the deleted record.
+func mergeRecs(recs *records.DB, a, b *records.Record, reason string) (*records.Record, error) {
+	fmt.Printf("\nduplicates (%s):\n", reason)
+	printRecs(a, b)
+	for {
+		ans := inter.GetAnswer("keep (a/b), skip (s), exit (e), or quit (q)?", false)
+		if len(ans) == 0 {
+			return nil, errQuit
+		}
+		switch k := strings.ToLower(ans[0]); k {
+		case "a", "b":
+			keep, del := a, b
+			if k == "b" {
+				keep, del = b, a
+			}
+			if err := recs.Merge(keep.ID(), del.ID()); err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				return nil, nil
+			}
+			return del, nil
+		case "s":
+			return nil, nil
+		case "e":
+			return nil, errExit
+		case "q":
+			return nil, errQuit
+		}
+	}
+}
+
+// PrintRecs prints the data
+// of two records.
+func printRecs(a, b *records.Record) {
+	va, vb := recValues(a), recValues(b)
+	keys := []string{"record", "basis", "date", "country", "state", "county", "locality", "collector", "latlon", "uncertainty"}
+	seen := make(map[string]bool)
+	for _, k := range append(a.Keys(), b.Keys()...) {
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		keys = append(keys, k)
+	}
+	for _, k := range keys {
+		fmt.Printf("%s:\n", k)
+		fmt.Printf("    a: %s\n", va[k])
+		fmt.Printf("    b: %s\n", vb[k])
+	}
+}
+
+// RecValues returns the values
+// of a record.
+func recValues(r *records.Record) map[string]string {
+	v := map[string]string{
+		"record": r.ID(),
+		"basis":  r.Basis().String(),
+	}
+	ev := r.CollEvent()
+	if !ev.Date.IsZero() {
+		v["date"] = ev.Date.Format(time.RFC3339)
+	}
+	v["country"] = ev.Admin.Country
+	v["state"] = ev.Admin.State
+	v["county"] = ev.Admin.County
+	v["locality"] = ev.Locality
+	v["collector"] = ev.Collector
+	if geo := r.GeoRef(); geo.IsValid() {
+		v["latlon"] = fmt.Sprintf("%f %f", geo.Lat, geo.Lon)
+		v["uncertainty"] = fmt.Sprintf("%d", geo.Uncertainty)
+	}
+	for _, k := range r.Keys() {
+		v[k] = r.Value(k)
+	}
+	return v
+}
@@ -0,0 +1,303 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package dwcaimport implements the rec.dwca.import command,
+// i.e. import records from a Darwin Core Archive.
+package dwcaimport
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/js-arias/biodv"
+	"github.com/js-arias/biodv/cmdapp"
+	"github.com/js-arias/biodv/dataset"
+	"github.com/js-arias/biodv/encoding/dwca"
+	"github.com/js-arias/biodv/geography"
+	"github.com/js-arias/biodv/journal"
+	"github.com/js-arias/biodv/records"
+
+	"github.com/pkg/errors"
+)
+
+var cmd = &cmdapp.Command{
+	UsageLine: `rec.dwca.import [-d|--dataset <title>] [-g|--georef]
+		[-l|--locatable] <archive>`,
+	Short: "import records from a Darwin Core Archive",
+	Long: `
+Command rec.dwca.import adds the records stored in a Darwin Core Archive
+(DwC-A) to the specimen records database. The archive can be a zip file,
+or a directory with the unzipped archive, and it must have an occurrence
+core. If the archive does not have a meta.xml file, the first text file
+of the archive will be read as the core, and the first line of that file
+will be read as the column names.
+
+The following Darwin Core terms are recognized:
+
+    occurrenceID (or the core ID)       stored as the record ID.
+    institutionCode, collectionCode,    stored as the catalog, in the
+    catalogNumber                       form <inst>:<coll>:<catalog>
+                                        (as in the gbif driver, codes
+                                        that are repeated, or included
+                                        in the catalog number, are
+                                        omitted).
+    basisOfRecord                       stored as basis.
+    eventDate (or year, month, day)     stored as date.
+    countryCode                         stored as country.
+    stateProvince                       stored as state.
+    county                              stored as county.
+    locality                            stored as locality.
+    recordedBy                          stored as collector.
+    decimalLatitude, decimalLongitude   stored as the georeference.
+    coordinateUncertaintyInMeters       stored as uncertainty.
+    minimumElevationInMeters            stored as elevation.
+    georeferenceSources                 stored as geosource.
+    georeferenceVerificationStatus      stored as validation.
+    identifiedBy                        stored as determiner.
+    organismID                          stored as organism.
+    sex                                 stored as sex.
+    lifeStage                           stored as stage.
+    occurrenceRemarks                   stored as comment.
+    associatedReferences                stored as reference.
+
+Other terms are ignored.
+
+The scientific name of each record must be resolved in the taxonomy
+database. The scientific name is searched as given in the archive, then
+without the authorship, and then using the genus and the epithets
+terms. The names that cannot be resolved are printed in the standard
+output (one per line), and their records are not added.
+
+The records are assigned to the dataset described in the metadata (EML)
+file of the archive, that is added to the dataset database if it is not
+already present.
+
+Records already in the database (i.e. with the same ID or catalog code)
+are ignored.
+
+Options are:
+
+    -d <title>
+    --dataset <title>
+      If set, the records will be assigned to the indicated dataset,
+      instead of the dataset described in the archive metadata.
+
+    -g
+    --georef
+      If set, only the records with a valid georefence will be added.
+
+    -l
+    --locatable
+      If set, only records that can be locatable (i.e either
+      georeferenced or with a complete description of the locality)
+      will be stored.
+
+    <archive>
+      The Darwin Core Archive file (or directory) to be imported.
+	`,
+	Run:           run,
+	RegisterFlags: register,
+}
+
+func init() {
+	cmdapp.Add(cmd)
+}
+
+var setTitle string
+var georef bool
+var locatable bool
+
+func register(c *cmdapp.Command) {
+	c.Flag.StringVar(&setTitle, "dataset", "", "")
+	c.Flag.StringVar(&setTitle, "d", "", "")
+	c.Flag.BoolVar(&georef, "georef", false, "")
+	c.Flag.BoolVar(&georef, "g", false, "")
+	c.Flag.BoolVar(&locatable, "locatable", false, "")
+	c.Flag.BoolVar(&locatable, "l", false, "")
+}
+
+func run(c *cmdapp.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.Errorf("%s: an archive should be defined", c.Name())
+	}
+	a, err := dwca.Open(args[0])
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	defer a.Close()
+
+	txm, err := biodv.OpenTax("biodv", "")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	recs, err := records.Open("")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	sets, err := dataset.Open("")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+
+	set, err := getDataset(a, sets)
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+
+	unres := make(map[string]int)
+	sc := records.NewArchiveScanner(a)
+	for sc.Scan() {
+		names := sc.Names()
+		r := sc.Record()
+		geo := r.GeoRef()
+		if georef && !geo.IsValid() {
+			continue
+		}
+		if locatable && !isLocatable(r) {
+			continue
+		}
+
+		tax := resolve(txm, names)
+		if tax == nil {
+			unres[r.Taxon()]++
+			continue
+		}
+		addRecord(recs, tax, r, set)
+	}
+	if err := sc.Err(); err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+
+	if len(unres) > 0 {
+		ls := make([]string, 0, len(unres))
+		n := 0
+		for nm, c := range unres {
+			ls = append(ls, nm)
+			n += c
+		}
+		sort.Strings(ls)
+		for _, nm := range ls {
+			fmt.Printf("%s\n", nm)
+		}
+		fmt.Fprintf(os.Stderr, "warning: %d records from %d unresolved names were not added\n", n, len(ls))
+	}
+
+	tx, err := journal.Begin("")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	if err := sets.CommitTx(tx); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, c.Name())
+	}
+	if err := recs.CommitTx(tx); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, c.Name())
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	return nil
+}
+
+// GetDataset returns the ID of the dataset
+// of the archive,
+// adding it to the dataset database
+// if it is not already present.
+func getDataset(a *dwca.Archive, sets *dataset.DB) (string, error) {
+	if setTitle != "" {
+		if set := sets.SetEd(setTitle); set != nil {
+			return set.ID(), nil
+		}
+		set, err := sets.Add(setTitle)
+		if err != nil {
+			return "", err
+		}
+		return set.ID(), nil
+	}
+
+	md, err := a.Dataset()
+	if err != nil {
+		return "", err
+	}
+	if md == nil || md.Title == "" {
+		return "", nil
+	}
+	if set := sets.SetEd(md.Title); set != nil {
+		return set.ID(), nil
+	}
+	set, err := sets.Add(md.Title)
+	if err != nil {
+		return "", err
+	}
+	set.Set(biodv.SetAboutKey, md.Abstract)
+	set.Set(biodv.SetLicense, md.License)
+	set.Set(biodv.SetURLKey, md.URL)
+	set.Set(biodv.SetPublisher, md.Publisher)
+	return set.ID(), nil
+}
+
+// Resolve returns the first taxon
+// that match a name from a list of names.
+func resolve(txm biodv.Taxonomy, names []string) biodv.Taxon {
+	for _, nm := range names {
+		if tax, _ := txm.TaxID(nm); tax != nil {
+			return tax
+		}
+	}
+	return nil
+}
+
+func addRecord(recs *records.DB, tax biodv.Taxon, r biodv.Record, set string) {
+	cat := r.Value(biodv.RecCatalog)
+	if ot, _ := recs.RecID(r.ID()); ot != nil {
+		return
+	}
+	if cat != "" {
+		if ot, _ := recs.RecID(cat); ot != nil {
+			return
+		}
+	}
+
+	geo := r.GeoRef()
+	rec, err := recs.Add(tax.Name(), r.ID(), cat, r.Basis(), geo.Lat, geo.Lon)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: when adding %q [%s]: %v\n", r.ID(), tax.Name(), err)
+		return
+	}
+	rec.SetCollEvent(r.CollEvent())
+	rec.SetGeoRef(geo)
+	for _, k := range r.Keys() {
+		if k == biodv.RecCatalog {
+			continue
+		}
+		if err := rec.Set(k, r.Value(k)); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: when updating %q [%s]: %v\n", r.ID(), tax.Name(), err)
+		}
+	}
+	if set != "" {
+		rec.Set(biodv.RecDataset, set)
+	}
+}
+
+// IsLocatable returns true if the record is locatable.
+func isLocatable(r biodv.Record) bool {
+	geo := r.GeoRef()
+	if geo.IsValid() {
+		return true
+	}
+
+	ev := r.CollEvent()
+	if !geography.IsValidCode(ev.CountryCode()) {
+		return false
+	}
+	if strings.TrimSpace(ev.Locality) != "" {
+		return true
+	}
+	return ev.State() != "" || ev.County() != ""
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package flag implements the rec.flag command,
+// i.e. flag records with georeference problems.
+package flag
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/js-arias/biodv"
+	"github.com/js-arias/biodv/cmdapp"
+	"github.com/js-arias/biodv/geography"
+	"github.com/js-arias/biodv/records"
+	"github.com/js-arias/biodv/records/quality"
+
+	"github.com/pkg/errors"
+)
+
+var cmd = &cmdapp.Command{
+	UsageLine: `rec.flag [-b|--borders <file>] [-c|--clear]
+		[-m|--marine] [<name>]`,
+	Short: "flag records with georeference problems",
+	Long: `
+Command rec.flag makes a set of tests to detect common problems in the
+georeferences of the specimen records, and stores the flags of the
+failed tests in the 'flags' field of each record. The flagged records
+are printed in the standard output, with the record ID, the taxon, and
+the flags, separated by tabs.
+
+The following tests are made:
+
+    zero       latitude and longitude are both zero.
+    equal      latitude and longitude have the same absolute value.
+    swapped    the record is outside its country, but it is inside the
+               country if latitude and longitude are swapped.
+    inverted   the record is outside its country, but it is inside the
+               country if the sign of the latitude, the longitude, or
+               both, are inverted.
+    centroid   the record is less than 1 km of the centroid of its
+               country.
+    capital    the record is less than 10 km of a country capital.
+    sea        the record is in the sea. Records with a negative z value
+               (i.e. sampled at a given depth) are not flagged.
+    precision  both latitude and longitude have less than two decimals.
+
+The tests swapped, inverted, centroid, and sea use the country borders
+(see 'biodv help rec.validate'). By default, the file
+'geography/borders.json' of the project is used. If the file is not
+available, a simplified version of the Natural Earth borders, included
+with biodv, is used.
+
+Each time the command is run, the previous flags of these tests are
+replaced. Other flags (for example, the flags set by rec.outliers) are
+kept. Use rec.table with the option --flag to filter the records by
+their flags.
+
+Options are:
+
+    -b <file>
+    --borders <file>
+      Sets the file with the country borders.
+
+    -c
+    --clear
+      If set, the flags of the tests will be removed from the records,
+      and no test will be made.
+
+    -m
+    --marine
+      If set, records in the sea will not be flagged. Use this option
+      when flagging the records of marine taxa.
+
+    <name>
+      If set, only the records of the indicated taxon (and its
+      descendants) will be flagged.
+	`,
+	Run:           run,
+	RegisterFlags: register,
+}
+
+func init() {
+	cmdapp.Add(cmd)
+}
+
+var bordersFile string
+var clear bool
+var marine bool
+
+func register(c *cmdapp.Command) {
+	c.Flag.StringVar(&bordersFile, "borders", "", "")
+	c.Flag.StringVar(&bordersFile, "b", "", "")
+	c.Flag.BoolVar(&clear, "clear", false, "")
+	c.Flag.BoolVar(&clear, "c", false, "")
+	c.Flag.BoolVar(&marine, "marine", false, "")
+	c.Flag.BoolVar(&marine, "m", false, "")
+}
+
+func run(c *cmdapp.Command, args []string) error {
+	if !clear {
+		if bordersFile == "" {
+			if _, err := os.Stat(geography.BordersFile); err == nil {
+				bordersFile = geography.BordersFile
+			}
+		}
+		if bordersFile != "" {
+			if err := geography.LoadBordersFile(bordersFile); err != nil {
+				return errors.Wrap(err, c.Name())
+			}
+		}
+	}
+	quality.Marine = marine
+
+	txm, err := biodv.OpenTax("biodv", "")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	recs, err := records.Open("")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+
+	var ls []biodv.Taxon
+	if nm := strings.Join(args, " "); nm != "" {
+		tax, _ := txm.TaxID(nm)
+		if tax == nil {
+			return errors.Errorf("%s: taxon %q not found", c.Name(), nm)
+		}
+		ls = append(ls, tax)
+	} else {
+		ls, err = biodv.TaxList(txm.Children(""))
+		if err != nil {
+			return errors.Wrap(err, c.Name())
+		}
+	}
+	for _, tax := range ls {
+		procTaxon(txm, recs, tax)
+	}
+
+	if err := recs.Commit(); err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	return nil
+}
+
+// ProcTaxon flags the records of a taxon,
+// and its descendants.
+func procTaxon(txm biodv.Taxonomy, recs *records.DB, tax biodv.Taxon) {
+	for _, r := range recs.RecList(tax.ID()) {
+		var flags []string
+		if !clear {
+			flags = quality.Check(r)
+		}
+		if err := r.Set(biodv.RecFlags, quality.Update(r.Value(biodv.RecFlags), flags)); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %s [tax: %s]: %v\n", r.ID(), tax.Name(), err)
+			continue
+		}
+		if len(flags) > 0 {
+			fmt.Printf("%s\t%s\t%s\n", r.ID(), tax.Name(), strings.Join(flags, " "))
+		}
+	}
+
+	children, _ := biodv.TaxList(txm.Children(tax.ID()))
+	syns, _ := biodv.TaxList(txm.Synonyms(tax.ID()))
+	children = append(children, syns...)
+	for _, c := range children {
+		procTaxon(txm, recs, c)
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package geocheck implements the rec.geocheck command,
+// i.e. check the administrative data of georeferenced records.
+package geocheck
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/js-arias/biodv"
+	"github.com/js-arias/biodv/cmdapp"
+	"github.com/js-arias/biodv/geography"
+	"github.com/js-arias/biodv/records"
+
+	"github.com/pkg/errors"
+)
+
+var cmd = &cmdapp.Command{
+	UsageLine: `rec.geocheck [-s|--service <service>] [-f|--fill]
+		[<name>]`,
+	Short: "check the administrative data of georeferenced records",
+	Long: `
+Command rec.geocheck uses a gazetteer service to search the
+administrative data (country, state, and county) of the georeference of
+each record, and compares it with the administrative data stored in the
+record.
+
+The records that do not match are printed in the standard output, one
+per line, as a table (separated by tabs) with the following columns:
+
+	ID       record ID
+	Taxon    name of the taxon of the record
+	Field    the field that does not match (country, state, or
+	         county)
+	Stored   the value stored in the record
+	Found    the value found with the gazetteer
+
+Names of the states and counties are compared ignoring case, diacritics,
+and punctuation, and a name is accepted if it contains the other (e.g.
+'Provincia de Salta' and 'Salta' are taken as the same name). States are
+only compared if the country matches, and counties are only compared if
+the state matches. Empty values are never reported.
+
+If the option -f or --fill is defined, the missing administrative data
+of the records will be filled with the values found with the gazetteer.
+Values already stored in the records are never replaced.
+
+Options are:
+
+    -s <service>
+    --service <service>
+      The gazetteer service to be used. The service must implement
+      reverse searches. The available services can be listed with the
+      command 'biodv db.drivers gazetteer'. By default the service is
+      'geonames' (i.e. a local copy of GeoNames, stored in the
+      'geonames' directory of the project).
+
+    -f
+    --fill
+      If set, missing country, state, and county values of the records
+      will be filled with the values found with the gazetteer.
+
+    <name>
+      If set, only the records for the indicated taxon (and its
+      descendants) will be checked.
+	`,
+	Run:           run,
+	RegisterFlags: register,
+}
+
+func init() {
+	cmdapp.Add(cmd)
+}
+
+var service string
+var fill bool
+
+func register(c *cmdapp.Command) {
+	c.Flag.StringVar(&service, "service", "", "")
+	c.Flag.StringVar(&service, "s", "", "")
+	c.Flag.BoolVar(&fill, "fill", false, "")
+	c.Flag.BoolVar(&fill, "f", false, "")
+}
+
+func run(c *cmdapp.Command, args []string) error {
+	if service == "" {
+		service = "geonames"
+	}
+	var param string
+	service, param = biodv.ParseDriverString(service)
+	gz, err := biodv.OpenGz(service, param)
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+
+	txm, err := biodv.OpenTax("biodv", "")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+
+	recs, err := records.Open("")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+
+	var ls []biodv.Taxon
+	if nm := strings.Join(args, " "); nm != "" {
+		tax, _ := txm.TaxID(nm)
+		if tax == nil {
+			return nil
+		}
+		ls = append(ls, tax)
+	} else {
+		ls, err = biodv.TaxList(txm.Children(""))
+		if err != nil {
+			return errors.Wrap(err, c.Name())
+		}
+	}
+	for _, tax := range ls {
+		procTaxon(txm, gz, recs, tax)
+	}
+
+	if !fill {
+		return nil
+	}
+	if err := recs.Commit(); err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	return nil
+}
+
+// ProcTaxon checks the records of a given taxon,
+// and its descendants.
+func procTaxon(txm biodv.Taxonomy, gz biodv.Gazetteer, recs *records.DB, tax biodv.Taxon) {
+	for _, r := range recs.RecList(tax.ID()) {
+		geo := r.GeoRef()
+		if !geo.IsValid() {
+			continue
+		}
+		adm, err := gz.Reverse(geo)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %s [tax: %s]: %v\n", r.ID(), tax.Name(), err)
+			continue
+		}
+		if adm.Country == "" {
+			continue
+		}
+		checkRecord(r, tax, adm)
+	}
+
+	children, _ := biodv.TaxList(txm.Children(tax.ID()))
+	syns, _ := biodv.TaxList(txm.Synonyms(tax.ID()))
+	children = append(children, syns...)
+	for _, c := range children {
+		procTaxon(txm, gz, recs, c)
+	}
+}
+
+// CheckRecord compares the administrative data
+// of a record with the data found with the gazetteer.
+func checkRecord(r *records.Record, tax biodv.Taxon, adm geography.Admin) {
+	ev := r.CollEvent()
+	changed := false
+	defer func() {
+		if changed {
+			r.SetCollEvent(ev)
+		}
+	}()
+
+	if ev.CountryCode() == "" {
+		if !fill {
+			return
+		}
+		ev.Admin = adm
+		changed = true
+		return
+	}
+	if !strings.EqualFold(ev.CountryCode(), adm.Country) {
+		report(r, tax, "country", strings.ToUpper(ev.CountryCode()), adm.Country)
+		return
+	}
+
+	if adm.State == "" {
+		return
+	}
+	if ev.State() == "" {
+		if !fill {
+			return
+		}
+		ev.Admin.State = adm.State
+		ev.Admin.County = adm.County
+		changed = true
+		return
+	}
+	if !sameAdmin(ev.State(), adm.State) {
+		report(r, tax, "state", ev.State(), adm.State)
+		return
+	}
+
+	if adm.County == "" {
+		return
+	}
+	if ev.County() == "" {
+		if !fill {
+			return
+		}
+		ev.Admin.County = adm.County
+		changed = true
+		return
+	}
+	if !sameAdmin(ev.County(), adm.County) {
+		report(r, tax, "county", ev.County(), adm.County)
+	}
+}
+
+func report(r *records.Record, tax biodv.Taxon, field, stored, found string) {
+	fmt.Printf("%s\t%s\t%s\t%s\t%s\n", r.ID(), tax.Name(), field, stored, found)
+}
+
+// SameAdmin returns true
+// if two names of an administrative division
+// can be taken as the same name.
+func sameAdmin(a, b string) bool {
+	a = geography.Normalize(a)
+	b = geography.Normalize(b)
+	if a == b {
+		return true
+	}
+	return strings.Contains(" "+a+" ", " "+b+" ") || strings.Contains(" "+b+" ", " "+a+" ")
+}
@@ -40,7 +40,11 @@ Options are:
 
     -s <service>
     --service <service>
-      A required parameter. The gazetteer service to be used.
+      A required parameter. The gazetteer service to be used. The
+      available services can be listed with the command
+      'biodv db.drivers gazetteer'. Parameters of the service are given
+      after a colon, for example 'geonames:/data/geonames' will use a
+      local copy of GeoNames stored at '/data/geonames'.
 
     -u <number>
     --uncertainty <number>
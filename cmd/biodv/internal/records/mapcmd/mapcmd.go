@@ -21,6 +21,7 @@ import (
 
 	"github.com/js-arias/biodv"
 	"github.com/js-arias/biodv/cmdapp"
+	"github.com/js-arias/biodv/records"
 
 	"github.com/pkg/errors"
 )
@@ -28,7 +29,7 @@ import (
 var cmd = &cmdapp.Command{
 	UsageLine: `rec.map [--db <database>] [--id] [-e|--exact]
 		[-h|--heath] [-m|--map <imagemap>] [-o|--out <suffix>]
-		[-s|--size <number>] [<taxon>]`,
+		[-s|--size <number>] [-w|--where <expression>] [<taxon>]`,
 	Short: "produce a map with georeferenced records",
 	Long: `
 Command rec.map produces a image map using a provided image map, and the
@@ -60,6 +61,10 @@ records.
 
 The option -s or --size controls the size of the output points.
 
+If the option -w or --where is defined, only the records that match the
+given filter expression will be used (see 'biodv help rec.select' for
+the syntax of the filter expressions).
+
 Options are:
 
     -db <database>
@@ -97,6 +102,11 @@ Options are:
     --size <number>
       Defines the size (in pixels) of each record in the map. Default = 2.
 
+    -w <expression>
+    --where <expression>
+      If set, only the records that match the expression will be used
+      to produce the map.
+
     <taxon>
       If set, the map will be based on the indicated taxon. If the
       name is ambiguous, the ID of the ambiguous taxa will be printed.
@@ -118,6 +128,7 @@ var heathOp bool
 var mapName string
 var outName string
 var recSize int
+var where string
 
 func register(c *cmdapp.Command) {
 	c.Flag.StringVar(&dbName, "db", "biodv", "")
@@ -132,12 +143,22 @@ func register(c *cmdapp.Command) {
 	c.Flag.StringVar(&outName, "o", "map.png", "")
 	c.Flag.IntVar(&recSize, "size", 2, "")
 	c.Flag.IntVar(&recSize, "s", 2, "")
+	c.Flag.StringVar(&where, "where", "", "")
+	c.Flag.StringVar(&where, "w", "", "")
 }
 
 var ids map[string][]point
+var filter *records.Filter
 
 func run(c *cmdapp.Command, args []string) error {
 	ids = make(map[string][]point)
+	if where != "" {
+		var err error
+		filter, err = records.ParseFilter(where)
+		if err != nil {
+			return errors.Wrap(err, c.Name())
+		}
+	}
 	if dbName == "" {
 		dbName = "biodv"
 	}
@@ -248,6 +269,9 @@ func searchPoints(id string, txm biodv.Taxonomy, recs biodv.RecDB) ([]point, err
 		if !geo.IsValid() {
 			continue
 		}
+		if !filter.Match(r) {
+			continue
+		}
 		p := point{lat: geo.Lat, lon: geo.Lon}
 
 		if r.Taxon() != id {
@@ -0,0 +1,201 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package outliers implements the rec.outliers command,
+// i.e. detect geographic outliers.
+package outliers
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/js-arias/biodv"
+	"github.com/js-arias/biodv/cmdapp"
+	"github.com/js-arias/biodv/geography"
+	"github.com/js-arias/biodv/records"
+	"github.com/js-arias/biodv/records/outlier"
+
+	"github.com/pkg/errors"
+)
+
+var cmd = &cmdapp.Command{
+	UsageLine: `rec.outliers [-m|--method <method>] [-k|--mult <value>]
+		[-d|--distance <value>] [-f|--flag] [<name>]`,
+	Short: "detect geographic outliers",
+	Long: `
+Command rec.outliers searches, for each taxon, the specimen records that
+are far away from the other records of the taxon. Such records are
+usually misidentified specimens, or records with a wrong georeference.
+
+The outliers are detected using the distance of each record to its
+nearest neighbour (i.e. the nearest record of the same taxon). The
+records of a taxon include the records assigned to its synonyms, but
+not the records assigned to its descendants, that are analyzed
+separately. Records without a georeference are ignored.
+
+By default, the outliers are printed in the standard output, one per
+line, with the record ID, the taxon, and the distance (in meters) to
+its nearest neighbour, separated by tabs. If the option --flag is
+defined, the 'outlier' flag will be set in the 'flags' field of the
+outlier records (and removed from the other records of the analyzed
+taxa). Use rec.table with the option --flag to filter the records by
+their flags.
+
+The following methods are available:
+
+    mad   a record is an outlier if its distance to its nearest
+          neighbour is greater than the median of the nearest
+          neighbour distances, plus k times the median absolute
+          deviation (MAD) of the distances. Taxa with less than 7
+          georeferenced records are not analyzed. This is the default
+          method.
+    dist  a record is an outlier if its distance to its nearest
+          neighbour is greater than a fixed distance.
+
+Options are:
+
+    -m <method>
+    --method <method>
+      Sets the method used to detect the outliers. Valid values are
+      'mad' and 'dist'. By default 'mad' is used.
+
+    -k <value>
+    --mult <value>
+      Sets the multiplier of the median absolute deviation, when the
+      'mad' method is used. Default value is 5.
+
+    -d <value>
+    --distance <value>
+      Sets the distance, in meters, used by the 'dist' method. Default
+      value is 1000000 (i.e. 1000 km).
+
+    -f
+    --flag
+      If set, the outlier records will be flagged.
+
+    <name>
+      If set, only the records of the indicated taxon (and its
+      descendants) will be analyzed.
+	`,
+	Run:           run,
+	RegisterFlags: register,
+}
+
+func init() {
+	cmdapp.Add(cmd)
+}
+
+var method string
+var mult float64
+var dist uint
+var flag bool
+
+func register(c *cmdapp.Command) {
+	c.Flag.StringVar(&method, "method", "mad", "")
+	c.Flag.StringVar(&method, "m", "mad", "")
+	c.Flag.Float64Var(&mult, "mult", 5, "")
+	c.Flag.Float64Var(&mult, "k", 5, "")
+	c.Flag.UintVar(&dist, "distance", 1000000, "")
+	c.Flag.UintVar(&dist, "d", 1000000, "")
+	c.Flag.BoolVar(&flag, "flag", false, "")
+	c.Flag.BoolVar(&flag, "f", false, "")
+}
+
+func run(c *cmdapp.Command, args []string) error {
+	var fn func([]geography.Position) []int
+	switch strings.ToLower(method) {
+	case "mad":
+		fn = func(pts []geography.Position) []int {
+			return outlier.MAD(pts, mult)
+		}
+	case "dist":
+		fn = func(pts []geography.Position) []int {
+			return outlier.Distance(pts, dist)
+		}
+	default:
+		return errors.Errorf("%s: unknown method %q", c.Name(), method)
+	}
+
+	txm, err := biodv.OpenTax("biodv", "")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	recs, err := records.Open("")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+
+	var ls []biodv.Taxon
+	if nm := strings.Join(args, " "); nm != "" {
+		tax, _ := txm.TaxID(nm)
+		if tax == nil {
+			return errors.Errorf("%s: taxon %q not found", c.Name(), nm)
+		}
+		ls = append(ls, tax)
+	} else {
+		ls, err = biodv.TaxList(txm.Children(""))
+		if err != nil {
+			return errors.Wrap(err, c.Name())
+		}
+	}
+	for _, tax := range ls {
+		procTaxon(txm, recs, tax, fn)
+	}
+
+	if !flag {
+		return nil
+	}
+	if err := recs.Commit(); err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	return nil
+}
+
+// ProcTaxon searches the outliers
+// in the records of a taxon,
+// and its descendants.
+func procTaxon(txm biodv.Taxonomy, recs *records.DB, tax biodv.Taxon, fn func([]geography.Position) []int) {
+	rs := recs.RecList(tax.ID())
+	syns, _ := biodv.TaxList(txm.Synonyms(tax.ID()))
+	for _, s := range syns {
+		rs = append(rs, recs.RecList(s.ID())...)
+	}
+
+	var geoRecs []*records.Record
+	var pts []geography.Position
+	for _, r := range rs {
+		geo := r.GeoRef()
+		if !geo.IsValid() {
+			continue
+		}
+		geoRecs = append(geoRecs, r)
+		pts = append(pts, geo)
+	}
+
+	out := make(map[int]bool)
+	if len(pts) > 0 {
+		nn := outlier.NNDist(pts)
+		for _, i := range fn(pts) {
+			out[i] = true
+			if !flag {
+				fmt.Printf("%s\t%s\t%d\n", geoRecs[i].ID(), tax.Name(), nn[i])
+			}
+		}
+	}
+	if flag {
+		for i, r := range geoRecs {
+			if err := r.Set(biodv.RecFlags, outlier.Update(r.Value(biodv.RecFlags), out[i])); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: %s [tax: %s]: %v\n", r.ID(), tax.Name(), err)
+			}
+		}
+	}
+
+	children, _ := biodv.TaxList(txm.Children(tax.ID()))
+	for _, c := range children {
+		procTaxon(txm, recs, c, fn)
+	}
+}
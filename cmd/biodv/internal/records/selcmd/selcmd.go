@@ -0,0 +1,196 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package selcmd implements the rec.select command,
+// i.e. select records using a filter expression.
+package selcmd
+
+import (
+	"encoding/csv"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/biodv"
+	"github.com/js-arias/biodv/cmdapp"
+	"github.com/js-arias/biodv/records"
+
+	"github.com/pkg/errors"
+)
+
+var cmd = &cmdapp.Command{
+	UsageLine: `rec.select [--db <database>] [-n|--noheader]
+		<expression>`,
+	Short: "select records using a filter expression",
+	Long: `
+Command rec.select prints a table (separated by tabs) of the records of
+all the taxa in the database that match a filter expression. The columns
+of the table are the same as in rec.table.
+
+A filter expression is made of terms, joined by the logical operators
+AND, OR, and NOT (case insensitive), and grouped with parenthesis. AND
+has precedence over OR. A term is a field name, an operator, and a
+value, for example:
+
+    basis=preserved AND country=AR AND date>=1990 AND georef AND uncertainty<5000
+
+Valid operators are:
+
+    =    equal
+    !=   not equal
+    <    less than
+    <=   less than or equal
+    >    greater than
+    >=   greater than or equal
+    ~    contains
+
+Values with spaces or operator characters must be quoted (remember to
+quote the whole expression when using the shell). String comparisons
+are case insensitive, and if both values are numbers, they are compared
+as numbers.
+
+The field names are the names used in the records database (for
+example 'catalog', 'collector', or 'flags', see 'biodv help records'),
+with the additional fields 'lat' and 'lon' for the latitude and the
+longitude. Dates can be given as a year ('1990'), a year and a month
+('1990-05'), or a full date ('1990-05-12'). For fields with a list of
+values (as 'flags' or 'extern'), the operators '=' and '!=' compare
+against each element of the list.
+
+A field without operator and value is true if the field is defined in
+the record. The special field 'georef' is true if the record has a
+valid georeference.
+
+The same filter expressions can be used in rec.table and rec.map with
+the option --where.
+
+Options are:
+
+    -db <database>
+    --db <database>
+      If set, the indicated database will be used to produce the table.
+      To see the available databases use the command ‘db.drivers’.
+      The database should include drivers for a taxonomy and records.
+
+    -n
+    --noheader
+      If set, the table will be printed without the columns header.
+
+    <expression>
+      The filter expression used to select the records.
+	`,
+	Run:           run,
+	RegisterFlags: register,
+}
+
+func init() {
+	cmdapp.Add(cmd)
+}
+
+var dbName string
+var nohead bool
+
+func register(c *cmdapp.Command) {
+	c.Flag.StringVar(&dbName, "db", "biodv", "")
+	c.Flag.BoolVar(&nohead, "noheader", false, "")
+	c.Flag.BoolVar(&nohead, "n", false, "")
+}
+
+func run(c *cmdapp.Command, args []string) error {
+	expr := strings.Join(args, " ")
+	if expr == "" {
+		return errors.Errorf("%s: a filter expression should be given", c.Name())
+	}
+	filter, err := records.ParseFilter(expr)
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+
+	if dbName == "" {
+		dbName = "biodv"
+	}
+	var param string
+	dbName, param = biodv.ParseDriverString(dbName)
+
+	txm, err := biodv.OpenTax(dbName, param)
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	recs, err := biodv.OpenRec(dbName, param)
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+
+	w := csv.NewWriter(os.Stdout)
+	w.Comma = '\t'
+	w.UseCRLF = true
+	if !nohead {
+		if err := w.Write([]string{"ID", "Taxon", "Lat", "Lon", "Catalog"}); err != nil {
+			return errors.Wrap(err, c.Name())
+		}
+	}
+
+	ls, err := biodv.TaxList(txm.Children(""))
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	for _, tax := range ls {
+		if err := procTaxon(w, txm, recs, filter, tax.ID()); err != nil {
+			return errors.Wrap(err, c.Name())
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	return nil
+}
+
+// ProcTaxon prints the records of a taxon,
+// and its descendants,
+// that match the filter.
+func procTaxon(w *csv.Writer, txm biodv.Taxonomy, recs biodv.RecDB, filter *records.Filter, id string) error {
+	sr := recs.TaxRecs(id)
+	for sr.Scan() {
+		r := sr.Record()
+		if !filter.Match(r) {
+			continue
+		}
+		row := []string{
+			r.ID(),
+			r.Taxon(),
+			"NA",
+			"NA",
+			r.Value(biodv.RecCatalog),
+		}
+		if geo := r.GeoRef(); geo.IsValid() {
+			row[2] = strconv.FormatFloat(geo.Lat, 'f', -1, 64)
+			row[3] = strconv.FormatFloat(geo.Lon, 'f', -1, 64)
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	if err := sr.Err(); err != nil {
+		return err
+	}
+
+	children, err := biodv.TaxList(txm.Children(id))
+	if err != nil {
+		return err
+	}
+	syns, err := biodv.TaxList(txm.Synonyms(id))
+	if err != nil {
+		return err
+	}
+	children = append(children, syns...)
+	for _, c := range children {
+		if err := procTaxon(w, txm, recs, filter, c.ID()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -19,13 +19,15 @@ import (
 
 	"github.com/js-arias/biodv"
 	"github.com/js-arias/biodv/cmdapp"
+	"github.com/js-arias/biodv/records"
 
 	"github.com/pkg/errors"
 )
 
 var cmd = &cmdapp.Command{
 	UsageLine: `rec.table [--db <database>] [--id] [-e|--exact]
-		[-g|--georef] [-n|--noheader] [<taxon>]`,
+		[-f|--flag <flag>] [-g|--georef] [-n|--noheader]
+		[-w|--where <expression>] [<taxon>]`,
 	Short: "print a table of records",
 	Long: `
 Command rec.table prints a table (separated by tabs) of the records of
@@ -40,6 +42,14 @@ printed.
 If the option -g or --georef is defined, only records with valid
 georeferences will be printed.
 
+If the option -f or --flag is defined, only records with the indicated
+flag (as set by rec.flag) will be printed. If the flag is 'none', only
+records without flags will be printed.
+
+If the option -w or --where is defined, only the records that match the
+given filter expression will be printed (see 'biodv help rec.select'
+for the syntax of the filter expressions).
+
 By default, the table will be printed with the column header. If the
 option -n or --noheader is defined, then no header will be printed. The
 order of columns is:
@@ -68,6 +78,12 @@ Options are:
       If set, only the records explicitly assigned to the indicated
       taxon will be printed.
 
+    -f <flag>
+    --flag <flag>
+      If set, only the records with the indicated flag will be printed.
+      If the flag is 'none', only the records without flags will be
+      printed.
+
     -g
     --georef
       If set, only the records with a valid georeference will be
@@ -77,6 +93,11 @@ Options are:
     --noheader
       If set, the table will be printed without the columns header.
 
+    -w <expression>
+    --where <expression>
+      If set, only the records that match the expression will be
+      printed.
+
     <taxon>
       If set, the table will be based on the indicated taxon. If the
       name is ambiguous, the ID of the ambiguous taxa will be printed.
@@ -94,26 +115,40 @@ func init() {
 var dbName string
 var id bool
 var exact bool
+var flag string
 var georef bool
 var nohead bool
+var where string
 
 func register(c *cmdapp.Command) {
 	c.Flag.StringVar(&dbName, "db", "biodv", "")
 	c.Flag.BoolVar(&id, "id", false, "")
 	c.Flag.BoolVar(&exact, "exact", false, "")
 	c.Flag.BoolVar(&exact, "e", false, "")
+	c.Flag.StringVar(&flag, "flag", "", "")
+	c.Flag.StringVar(&flag, "f", "", "")
 	c.Flag.BoolVar(&georef, "georef", false, "")
 	c.Flag.BoolVar(&georef, "g", false, "")
 	c.Flag.BoolVar(&nohead, "noheader", false, "")
 	c.Flag.BoolVar(&nohead, "n", false, "")
+	c.Flag.StringVar(&where, "where", "", "")
+	c.Flag.StringVar(&where, "w", "", "")
 }
 
 var ids map[string]bool
 var rows map[string][]string
+var filter *records.Filter
 
 func run(c *cmdapp.Command, args []string) error {
 	ids = make(map[string]bool)
 	rows = make(map[string][]string)
+	if where != "" {
+		var err error
+		filter, err = records.ParseFilter(where)
+		if err != nil {
+			return errors.Wrap(err, c.Name())
+		}
+	}
 	if dbName == "" {
 		dbName = "biodv"
 	}
@@ -244,6 +279,12 @@ func printSearch(w *csv.Writer, id string, txm biodv.Taxonomy, recs biodv.RecDB)
 		} else if georef {
 			continue
 		}
+		if !hasFlag(r) {
+			continue
+		}
+		if !filter.Match(r) {
+			continue
+		}
 
 		if r.Taxon() != id {
 			ids[r.Taxon()] = true
@@ -308,3 +349,22 @@ func searchChildren(w *csv.Writer, id string, txm biodv.Taxonomy, recs biodv.Rec
 	}
 	return nil
 }
+
+// HasFlag returns true
+// if the record has the flag
+// defined in the options.
+func hasFlag(r biodv.Record) bool {
+	if flag == "" {
+		return true
+	}
+	flags := strings.Fields(r.Value(biodv.RecFlags))
+	if strings.ToLower(flag) == "none" {
+		return len(flags) == 0
+	}
+	for _, f := range flags {
+		if strings.EqualFold(f, flag) {
+			return true
+		}
+	}
+	return false
+}
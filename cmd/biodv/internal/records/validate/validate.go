@@ -9,30 +9,288 @@
 package validate
 
 import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/js-arias/biodv"
 	"github.com/js-arias/biodv/cmdapp"
+	"github.com/js-arias/biodv/dataset"
+	"github.com/js-arias/biodv/encoding/stanza"
+	"github.com/js-arias/biodv/geography"
 	"github.com/js-arias/biodv/records"
+	"github.com/js-arias/biodv/taxonomy"
 
 	"github.com/pkg/errors"
 )
 
 var cmd = &cmdapp.Command{
-	UsageLine: "rec.validate",
-	Short:     "validate an specimen records database",
+	UsageLine: `rec.validate [-b|--borders <file>] [-m|--marine]
+		[--stanza] [<name>]`,
+	Short: "validate an specimen records database",
 	Long: `
 Command rec.validate validates a records database. It is useful to test
-if a biodv database from a third party is correct. If there are no
-errors, it will finish silently.
+if a biodv database from a third party is correct.
+
+The records are read directly from the database files, and all the
+problems found are reported. The records with problems will be printed
+in the standard output, one problem per line, with the record ID, the
+taxon, and the problem, separated by tabs. If there are no problems,
+it will finish silently, otherwise, it will finish with an error (i.e.
+with a non-zero exit status), so it can be used in scripts. The
+following problems are reported:
+
+    no id                the record does not have an ID.
+    no taxon             the record does not have a taxon.
+    duplicated id        the ID of the record is used by another
+                         record.
+    duplicated catalog   the catalog number of the record is used by
+                         another record.
+    unknown taxon        the taxon of the record is not in the
+                         taxonomy.
+    synonym              the taxon of the record is a synonym.
+    invalid basis        the basis of record is unknown.
+    no catalog           the record is a preserved specimen without a
+                         catalog number.
+    invalid country      the country code is not a valid ISO 3166-1
+                         alpha-2 code.
+    invalid coordinates  the latitude or the longitude are invalid.
+    invalid date         the date can not be read.
+    future date          the date is after the current time.
+    old date             the date is before the year 1700.
+    unknown dataset      the dataset of the record is not in the
+                         dataset database.
+
+The georeferences of the records are validated using the country
+borders, and the following problems are reported:
+
+    outside country      the georeference of the record is outside
+                         the borders of the country of the record.
+    in the sea           the georeference of the record is in the sea.
+                         Records with a negative z value (i.e.
+                         sampled at a given depth) are not reported.
+
+A point is taken as inside a country (or inland) if its distance to the
+border is smaller than its uncertainty plus 10 km, as border files are
+usually simplified.
+
+The borders file is a GeoJSON file with the polygons of each country,
+with the ISO 3166-1 alpha-2 code of the country stored in the property
+'ISO_A2' (as in the Natural Earth admin 0 countries files, available at
+<http://www.naturalearthdata.com/>). By default, the file
+'geography/borders.json' of the project is used. If the file is not
+available, a simplified version of the Natural Earth 1:10m borders,
+included with biodv, is used.
+
+The taxonomy of the project should be valid (see tax.validate).
+
+Options are:
+
+    -b <file>
+    --borders <file>
+      Sets the file with the country borders.
+
+    -m
+    --marine
+      If set, records in the sea will not be reported. Use this option
+      when validating the records of marine taxa.
+
+    --stanza
+      If set, the problems will be printed in the stanza format, with
+      the fields 'id', 'taxon', 'problem', and 'value' (the value with
+      the problem, if any).
+
+    <name>
+      If set, only the records of the indicated taxon (and its
+      descendants) will be validated.
 	`,
-	Run: run,
+	Run:           run,
+	RegisterFlags: register,
 }
 
 func init() {
 	cmdapp.Add(cmd)
 }
 
+var bordersFile string
+var marine bool
+var stzOut bool
+
+func register(c *cmdapp.Command) {
+	c.Flag.StringVar(&bordersFile, "borders", "", "")
+	c.Flag.StringVar(&bordersFile, "b", "", "")
+	c.Flag.BoolVar(&marine, "marine", false, "")
+	c.Flag.BoolVar(&marine, "m", false, "")
+	c.Flag.BoolVar(&stzOut, "stanza", false, "")
+}
+
+// Problems found
+// when validating the georeferences.
+const (
+	probCountry = "outside country"
+	probSea     = "in the sea"
+)
+
 func run(c *cmdapp.Command, args []string) error {
-	if _, err := records.Open(""); err != nil {
+	txm, err := taxonomy.Open("")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	sets, err := dataset.Open("")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+
+	var ls []biodv.Taxon
+	var taxa map[string]bool
+	if nm := strings.Join(args, " "); nm != "" {
+		tax, _ := txm.TaxID(nm)
+		if tax == nil {
+			return errors.Errorf("%s: taxon %q not found", c.Name(), nm)
+		}
+		ls = append(ls, tax)
+		taxa = make(map[string]bool)
+		addTaxa(txm, tax, taxa)
+	} else {
+		ls, err = biodv.TaxList(txm.Children(""))
+		if err != nil {
+			return errors.Wrap(err, c.Name())
+		}
+	}
+
+	probs, err := records.Validate("", txm, sets)
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	if taxa != nil {
+		var fp []records.Problem
+		for _, p := range probs {
+			if taxa[biodv.TaxCanon(p.Taxon)] {
+				fp = append(fp, p)
+			}
+		}
+		probs = fp
+	}
+
+	gp, geoErr := geoProblems(txm, ls)
+	probs = append(probs, gp...)
+
+	if stzOut {
+		err = writeStanza(probs)
+	} else {
+		err = writeText(probs)
+	}
+	if err != nil {
 		return errors.Wrap(err, c.Name())
 	}
+	if geoErr != nil {
+		return errors.Wrap(geoErr, c.Name())
+	}
+	if len(probs) > 0 {
+		return errors.Errorf("%s: problems found: %d", c.Name(), len(probs))
+	}
 	return nil
 }
+
+// AddTaxa adds a taxon,
+// and its descendants,
+// to a set of taxa.
+func addTaxa(txm biodv.Taxonomy, tax biodv.Taxon, taxa map[string]bool) {
+	taxa[tax.ID()] = true
+	children, _ := biodv.TaxList(txm.Children(tax.ID()))
+	syns, _ := biodv.TaxList(txm.Synonyms(tax.ID()))
+	children = append(children, syns...)
+	for _, c := range children {
+		addTaxa(txm, c, taxa)
+	}
+}
+
+// GeoProblems validates the georeferences
+// of the records of a list of taxa.
+func geoProblems(txm biodv.Taxonomy, ls []biodv.Taxon) ([]records.Problem, error) {
+	if bordersFile == "" {
+		if _, err := os.Stat(geography.BordersFile); err == nil {
+			bordersFile = geography.BordersFile
+		}
+	}
+	if bordersFile != "" {
+		if err := geography.LoadBordersFile(bordersFile); err != nil {
+			return nil, err
+		}
+	}
+	recs, err := records.Open("")
+	if err != nil {
+		return nil, err
+	}
+
+	var probs []records.Problem
+	for _, tax := range ls {
+		probs = procTaxon(txm, recs, tax, probs)
+	}
+	return probs, nil
+}
+
+// ProcTaxon validates the georeferences
+// of the records of a taxon,
+// and its descendants.
+func procTaxon(txm biodv.Taxonomy, recs *records.DB, tax biodv.Taxon, probs []records.Problem) []records.Problem {
+	for _, r := range recs.RecList(tax.ID()) {
+		geo := r.GeoRef()
+		if !geo.IsValid() {
+			continue
+		}
+		ev := r.CollEvent()
+		if !marine && ev.Z >= 0 && geography.InSea(geo) {
+			probs = append(probs, records.Problem{r.ID(), tax.Name(), probSea, ""})
+			continue
+		}
+		if geography.IsValidCode(ev.CountryCode()) && !ev.Admin.Contains(geo) {
+			found := geography.CountryAt(geo)
+			if found == "" {
+				found = "none"
+			}
+			v := fmt.Sprintf("%s (found: %s)", strings.ToUpper(ev.CountryCode()), found)
+			probs = append(probs, records.Problem{r.ID(), tax.Name(), probCountry, v})
+		}
+	}
+
+	children, _ := biodv.TaxList(txm.Children(tax.ID()))
+	syns, _ := biodv.TaxList(txm.Synonyms(tax.ID()))
+	children = append(children, syns...)
+	for _, c := range children {
+		probs = procTaxon(txm, recs, c, probs)
+	}
+	return probs
+}
+
+// WriteText writes the problems
+// in the standard output
+// as text.
+func writeText(probs []records.Problem) error {
+	w := bufio.NewWriter(os.Stdout)
+	for _, p := range probs {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", p.ID, p.Taxon, p)
+	}
+	return w.Flush()
+}
+
+// WriteStanza writes the problems
+// in the standard output
+// in the stanza format.
+func writeStanza(probs []records.Problem) error {
+	w := stanza.NewWriter(os.Stdout)
+	w.SetFields([]string{"id", "taxon", "problem", "value"})
+	for _, p := range probs {
+		rec := map[string]string{
+			"id":      p.ID,
+			"taxon":   p.Taxon,
+			"problem": p.Kind,
+			"value":   p.Value,
+		}
+		if err := w.Write(rec); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
@@ -34,6 +34,14 @@ assumes that each line contains a taxon name (empty lines, or lines
 starting with the sharp symbol ( # ) or semicolon character ( ; ) will
 be ignored).
 
+The name can include its authors and year, for example:
+
+	Puma concolor cougar (Kerr, 1792)
+
+in which case, the authors will be stored as the author of the taxon.
+Subgenus names (in parentheses after the genus), and the rank markers
+of infraspecific names (subsp., var., f.) are removed from the name.
+
 By default, taxons will be added to the root of the taxonomy (without
 parent), as correct names, and rankless.
 
@@ -50,15 +58,20 @@ Options are:
       should be concordant with the parent's rank.
       Valid ranks are:
         unranked
-        kingdom
-        class
-        order
-        family
-        genus
-        species
+        kingdom      subkingdom
+        phylum       subphylum
+        superclass   class        subclass     infraclass
+        superorder   order        suborder     infraorder
+        superfamily  family       subfamily    tribe     subtribe
+        genus        subgenus
+        species      subspecies   variety      form
+      The abbreviations subsp. (or ssp.), var., and f. are also
+      accepted.
       If the rank is set to species, it will automatically interpret the
       first part of the name as a genus, and adds the species as a child
-      of that genus.
+      of that genus. If the rank is set to subspecies, variety, or form,
+      it will interpret the first two words of the name as a species,
+      and adds the taxon as a child of that species.
 
     -s
     --synonym
@@ -111,7 +124,7 @@ func run(c *cmdapp.Command, args []string) error {
 		parent = p.Name()
 	}
 	if synonym && p == nil {
-		return errors.Errorf("%s: synonym taxons require a parent")
+		return errors.Errorf("%s: synonym taxons require a parent", c.Name())
 	}
 	if len(args) == 0 {
 		args = append(args, "-")
@@ -143,13 +156,15 @@ func run(c *cmdapp.Command, args []string) error {
 func read(db *taxonomy.DB, r io.Reader, rk biodv.Rank) error {
 	s := bufio.NewScanner(r)
 	for s.Scan() {
-		name := biodv.TaxCanon(s.Text())
-		if name == "" {
+		ln := strings.TrimSpace(s.Text())
+		if ln == "" {
 			continue
 		}
-		if nm, _ := utf8.DecodeRuneInString(name); nm == '#' || nm == ';' || !unicode.IsLetter(nm) {
+		if nm, _ := utf8.DecodeRuneInString(ln); nm == '#' || nm == ';' || !unicode.IsLetter(nm) {
 			continue
 		}
+		n := biodv.ParseName(ln)
+		name := n.Canon()
 
 		// skip taxons already in database
 		if tax, _ := db.TaxID(name); tax != nil {
@@ -157,28 +172,64 @@ func read(db *taxonomy.DB, r io.Reader, rk biodv.Rank) error {
 		}
 
 		pname := parent
-
-		// we known that the first part of a sepcies name is the genus
-		if rk == biodv.Species && !synonym {
-			g := strings.Fields(name)[0]
-			if p, _ := db.TaxID(g); p != nil {
-				pname = p.Name()
-			} else {
-				p, err := db.Add(g, pname, biodv.Genus, true)
-				if err != nil {
-					return err
-				}
-				pname = p.Name()
+		if !synonym {
+			p, err := impliedParent(db, name, rk)
+			if err != nil {
+				return err
+			}
+			if p != "" {
+				pname = p
 			}
 		}
 
-		if _, err := db.Add(name, pname, rk, !synonym); err != nil {
+		tax, err := db.Add(name, pname, rk, !synonym)
+		if err != nil {
 			return err
 		}
+		if a := n.Authorship(); a != "" {
+			if err := tax.Set(biodv.TaxAuthor, a); err != nil {
+				return err
+			}
+		}
 	}
 	return s.Err()
 }
 
+// ImpliedParent returns the parent implied by a name,
+// adding it to the database if it is not present.
+// We known that the first part of a species name is the genus,
+// and that the first two parts of an infraspecific name
+// are the species.
+func impliedParent(db *taxonomy.DB, name string, rk biodv.Rank) (string, error) {
+	f := strings.Fields(name)
+	var pname string
+	var prk biodv.Rank
+	switch {
+	case rk == biodv.Species && len(f) > 1:
+		pname, prk = f[0], biodv.Genus
+	case rk > biodv.Species && len(f) > 2:
+		pname, prk = f[0]+" "+f[1], biodv.Species
+	default:
+		return "", nil
+	}
+	if p, _ := db.TaxID(pname); p != nil {
+		return p.Name(), nil
+	}
+
+	gp, err := impliedParent(db, pname, prk)
+	if err != nil {
+		return "", err
+	}
+	if gp == "" {
+		gp = parent
+	}
+	p, err := db.Add(pname, gp, prk, true)
+	if err != nil {
+		return "", err
+	}
+	return p.Name(), nil
+}
+
 // IsRankValid returns true if the rank r
 // is compatible with the taxonomy.
 func isRankValid(db *taxonomy.DB, p biodv.Taxon, rk biodv.Rank) bool {
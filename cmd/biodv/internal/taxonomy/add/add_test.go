@@ -17,7 +17,7 @@ import (
 var blob = `
 
 Struthio camelus
-Rhea americana
+Rhea americana (Linnaeus, 1758)
 Pterocnemia pennata
 Casuarius casuarius
 Casuarius bennetti
@@ -70,4 +70,9 @@ func TestTaxAddCmdRead(t *testing.T) {
 	if tax, _ := db.TaxID("Pachyornis"); tax == nil {
 		t.Errorf("taxon \"Pachyornis\" should be on database")
 	}
+	if tax, _ := db.TaxID("Rhea americana"); tax == nil {
+		t.Errorf("taxon \"Rhea americana\" should be on database")
+	} else if a := tax.Value(biodv.TaxAuthor); a != "(Linnaeus, 1758)" {
+		t.Errorf("taxon \"Rhea americana\": author %q, want %q", a, "(Linnaeus, 1758)")
+	}
 }
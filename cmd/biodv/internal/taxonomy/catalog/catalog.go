@@ -193,7 +193,7 @@ func printSupra(tax biodv.Taxon, syns []biodv.Taxon) {
 		rk := tax.Rank()
 
 		if rk != biodv.Unranked {
-			if rk == biodv.Genus {
+			if rk == biodv.Genus || rk == biodv.Subgenus {
 				fmt.Printf("\n%s <strong><i>%s</i></strong> %s", strings.Title(rk.String()), nm, html.EscapeString(tax.Value(biodv.TaxAuthor)))
 			} else {
 				fmt.Printf("\n%s <strong>%s</strong> %s", strings.Title(rk.String()), nm, html.EscapeString(tax.Value(biodv.TaxAuthor)))
@@ -204,7 +204,7 @@ func printSupra(tax biodv.Taxon, syns []biodv.Taxon) {
 		fmt.Printf(" <font size=-1>[%s]</font>\n", ids)
 		for _, s := range syns {
 			sid := getIDsString(s)
-			if s.Rank() == biodv.Genus {
+			if s.Rank() == biodv.Genus || s.Rank() == biodv.Subgenus {
 				fmt.Printf("\t<font color=\"gray\"><i>%s</i> %s <font size=-1>[%s]</font></font>\n", html.EscapeString(s.Name()), html.EscapeString(s.Value(biodv.TaxAuthor)), sid)
 				continue
 			}
@@ -13,12 +13,14 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 
 	"github.com/js-arias/biodv"
 	"github.com/js-arias/biodv/cmdapp"
 	"github.com/js-arias/biodv/dataset"
+	"github.com/js-arias/biodv/journal"
 	"github.com/js-arias/biodv/taxonomy"
 
 	"github.com/pkg/errors"
@@ -42,6 +44,11 @@ In the input file, it is assumed that each line contains a taxon name
 (empty lines, or lines starting with the sharp symbol ( # ) or
 semicolon character ( ; ) will be ignored).
 
+The name can include its authors and year (e.g. 'Puma concolor cougar
+(Kerr, 1792)'). Only the name is searched in the external database,
+and if the external database does not have the author of the taxon, the
+authors of the input will be stored as the author of the taxon.
+
 If the option -u or --uprank is given, it will add additional parents up
 to the given rank.
 
@@ -57,12 +64,14 @@ Options are:
       If set, parent taxons, up to the given rank, will be added to
       the database.
       Valid ranks are:
-        kingdom
-        class
-        order
-        family
-        genus     [default value]
-        species
+        kingdom      subkingdom
+        phylum       subphylum
+        superclass   class        subclass     infraclass
+        superorder   order        suborder     infraorder
+        superfamily  family       subfamily    tribe     subtribe
+        genus        subgenus
+        species      subspecies   variety      form
+      By default, the rank is genus.
 	`,
 	Run:           run,
 	RegisterFlags: register,
@@ -169,25 +178,36 @@ func openDBs() (*databases, error) {
 }
 
 func commit(dbs *databases) error {
+	tx, err := journal.Begin("")
+	if err != nil {
+		return err
+	}
 	if dbs.sets != nil {
-		if err := dbs.sets.Commit(); err != nil {
+		if err := dbs.sets.CommitTx(tx); err != nil {
+			tx.Rollback()
 			return err
 		}
 	}
-	return dbs.db.Commit()
+	if err := dbs.db.CommitTx(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
 }
 
 // Read reads the data from a reader.
 func read(dbs *databases, r io.Reader, rk biodv.Rank) error {
 	s := bufio.NewScanner(r)
 	for s.Scan() {
-		name := biodv.TaxCanon(s.Text())
-		if name == "" {
+		ln := strings.TrimSpace(s.Text())
+		if ln == "" {
 			continue
 		}
-		if nm, _ := utf8.DecodeRuneInString(name); nm == '#' || nm == ';' || !unicode.IsLetter(nm) {
+		if nm, _ := utf8.DecodeRuneInString(ln); nm == '#' || nm == ';' || !unicode.IsLetter(nm) {
 			continue
 		}
+		n := biodv.ParseName(ln)
+		name := n.Canon()
 
 		// skip taxons already in database
 		if tax, _ := dbs.db.TaxID(name); tax != nil {
@@ -225,6 +245,11 @@ func read(dbs *databases, r io.Reader, rk biodv.Rank) error {
 		if tax.Name() != name {
 			fmt.Fprintf(os.Stderr, "warning: taxon %q added as %q [%s:%s]\n", name, tax.Name(), extName, tx.ID())
 		}
+		if a := n.Authorship(); a != "" && tax.Value(biodv.TaxAuthor) == "" {
+			if err := tax.Set(biodv.TaxAuthor, a); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: when updating %s: %v\n", tax.Name(), err)
+			}
+		}
 	}
 	return s.Err()
 }
@@ -18,6 +18,7 @@ import (
 	"github.com/js-arias/biodv"
 	"github.com/js-arias/biodv/cmdapp"
 	"github.com/js-arias/biodv/dataset"
+	"github.com/js-arias/biodv/journal"
 	"github.com/js-arias/biodv/taxonomy"
 
 	"github.com/pkg/errors"
@@ -133,7 +134,6 @@ type databases struct {
 
 func openDBs() (*databases, error) {
 	dbs := &databases{}
-	extName, param = biodv.ParseDriverString(extName)
 	var err error
 	dbs.ext, err = biodv.OpenTax(extName, param)
 	if err != nil {
@@ -166,12 +166,21 @@ func openDBs() (*databases, error) {
 }
 
 func commit(dbs *databases) error {
+	tx, err := journal.Begin("")
+	if err != nil {
+		return err
+	}
 	if dbs.sets != nil {
-		if err := dbs.sets.Commit(); err != nil {
+		if err := dbs.sets.CommitTx(tx); err != nil {
+			tx.Rollback()
 			return err
 		}
 	}
-	return dbs.db.Commit()
+	if err := dbs.db.CommitTx(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
 }
 
 func upProc(dbs *databases, nm string, rank biodv.Rank) {
@@ -16,6 +16,7 @@ import (
 	"github.com/js-arias/biodv"
 	"github.com/js-arias/biodv/cmdapp"
 	"github.com/js-arias/biodv/dataset"
+	"github.com/js-arias/biodv/journal"
 	"github.com/js-arias/biodv/taxonomy"
 
 	"github.com/pkg/errors"
@@ -150,12 +151,21 @@ func openDBs() (*databases, error) {
 }
 
 func commit(dbs *databases) error {
+	tx, err := journal.Begin("")
+	if err != nil {
+		return err
+	}
 	if dbs.sets != nil {
-		if err := dbs.sets.Commit(); err != nil {
+		if err := dbs.sets.CommitTx(tx); err != nil {
+			tx.Rollback()
 			return err
 		}
 	}
-	return dbs.db.Commit()
+	if err := dbs.db.CommitTx(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
 }
 
 // MakeMoves make all movements
@@ -0,0 +1,709 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package ed implements the tax.ed command,
+// i.e. edit the taxonomy interactively.
+package ed
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/js-arias/biodv"
+	"github.com/js-arias/biodv/cmdapp"
+	"github.com/js-arias/biodv/taxonomy"
+
+	"github.com/pkg/errors"
+)
+
+var cmd = &cmdapp.Command{
+	UsageLine: "tax.ed",
+	Short:     "edit the taxonomy interactively",
+	Long: `
+Command tax.ed implements a simple interactive taxonomy editor.
+
+In most commands, a taxon can be given by its name. The special values
+'.' (the current taxon), '..' (the parent of the current taxon), and '/'
+(the root of the taxonomy) are also accepted.
+
+The commands understood by tax.ed are:
+
+    a <name>
+    add <name>
+      Add a taxon as a correct child of the current taxon.
+
+    as <name>
+      Add a taxon as a synonym of the current taxon.
+
+    c [<taxon>]
+    correct [<taxon>]
+      Set the current taxon as a correct name.
+
+    del [<taxon>]
+    delete [<taxon>]
+      Delete a taxon.
+
+    d [<taxon>]
+    desc [<taxon>]
+      List the correct children of a taxon.
+
+    e
+    exit
+      Shorthand for 'write' and 'quit'.
+
+    h [<command>]
+    help [<command>]
+      Print command help.
+
+    m <taxon>
+    move <taxon>
+      Move the current taxon to a new parent.
+
+    n
+    next
+      Move to the next sibling taxon.
+
+    nv
+      Shorthand for 'next' and 'view'.
+
+    p
+    prev
+      Move to the previous sibling taxon.
+
+    pv
+      Shorthand for 'prev' and 'view'.
+
+    q
+    quit
+      Quit the program, without making any change.
+
+    rk [<rank>]
+    rank [<rank>]
+      Print or set the rank of the current taxon.
+
+    s <key> <value>
+    set <key> <value>
+      Set a value of the current taxon.
+
+    sv <key> <value>
+      Shorthand for 'set' and 'view'.
+
+    sy [<taxon>]
+    synonyms [<taxon>]
+      List the synonyms of a taxon.
+
+    syn <taxon>
+    synonymize <taxon>
+      Set the current taxon as a synonym of another taxon.
+
+    t <taxon>
+    taxon <taxon>
+      Move to the indicated taxon.
+
+    v [<taxon>]
+    view [<taxon>]
+      Show taxon data.
+
+    w
+    write
+      Write the database on the hard disk.
+	`,
+	Run: run,
+}
+
+func init() {
+	cmdapp.Add(cmd)
+}
+
+var txm *taxonomy.DB
+var tax *taxonomy.Taxon
+
+func run(c *cmdapp.Command, args []string) error {
+	var err error
+	txm, err = taxonomy.Open("")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+
+	i := cmdapp.NewInter(os.Stdin, prompt)
+	addCommands(i)
+
+	i.Loop()
+	return nil
+}
+
+func prompt() string {
+	if tax == nil {
+		return "root:"
+	}
+	return fmt.Sprintf("%s:", tax.Name())
+}
+
+func addCommands(i *cmdapp.Inter) {
+	i.Add(&cmdapp.Cmd{"a", "add", "add a taxon", addHelp, addCmd})
+	i.Add(&cmdapp.Cmd{"", "as", "add a synonym", asHelp, asCmd})
+	i.Add(&cmdapp.Cmd{"c", "correct", "set the taxon as a correct name", correctHelp, correctCmd})
+	i.Add(&cmdapp.Cmd{"del", "delete", "delete a taxon", deleteHelp, deleteCmd})
+	i.Add(&cmdapp.Cmd{"d", "desc", "list descendant taxons", descHelp, descCmd})
+	i.Add(&cmdapp.Cmd{"e", "exit", "shorthand for 'write' and 'quit'", exitHelp, exitCmd})
+	i.Add(&cmdapp.Cmd{"m", "move", "move the taxon to a new parent", moveHelp, moveCmd})
+	i.Add(&cmdapp.Cmd{"n", "next", "move to next sibling taxon", nextHelp, nextCmd})
+	i.Add(&cmdapp.Cmd{"", "nv", "shorthand for 'next' and 'view'", nvHelp, nvCmd})
+	i.Add(&cmdapp.Cmd{"p", "prev", "move to previous sibling taxon", prevHelp, prevCmd})
+	i.Add(&cmdapp.Cmd{"", "pv", "shorthand for 'prev' and 'view'", pvHelp, pvCmd})
+	i.Add(&cmdapp.Cmd{"q", "quit", "quit the program", quitHelp, func([]string) (bool, error) { return true, nil }})
+	i.Add(&cmdapp.Cmd{"rk", "rank", "print or set taxon rank", rankHelp, rankCmd})
+	i.Add(&cmdapp.Cmd{"s", "set", "set a value of the taxon", setHelp, setCmd})
+	i.Add(&cmdapp.Cmd{"", "sv", "shorthand for 'set' and 'view'", svHelp, svCmd})
+	i.Add(&cmdapp.Cmd{"sy", "synonyms", "list synonyms", synonymsHelp, synonymsCmd})
+	i.Add(&cmdapp.Cmd{"syn", "synonymize", "set the taxon as a synonym", synonymizeHelp, synonymizeCmd})
+	i.Add(&cmdapp.Cmd{"t", "taxon", "move to taxon", taxonHelp, taxonCmd})
+	i.Add(&cmdapp.Cmd{"v", "view", "print taxon data", viewHelp, viewCmd})
+	i.Add(&cmdapp.Cmd{"w", "write", "write the database on the hard disk", writeHelp, writeCmd})
+}
+
+// GetTaxon returns the taxon
+// indicated by a name,
+// or one of the special values
+// '.', '..', and '/'.
+// It returns true if the name is valid,
+// a nil taxon is the root of the taxonomy.
+func getTaxon(args []string) (*taxonomy.Taxon, bool) {
+	nm := strings.Join(args, " ")
+	switch nm {
+	case "", ".":
+		return tax, true
+	case "/":
+		return nil, true
+	case "..":
+		if tax == nil {
+			return nil, false
+		}
+		return txm.TaxEd(tax.Parent()), true
+	}
+	nt := txm.TaxEd(nm)
+	return nt, nt != nil
+}
+
+// IsDesc returns true
+// if a taxon is the current taxon,
+// or one of its descendants.
+func isDesc(t *taxonomy.Taxon) bool {
+	for ; t != nil; t = txm.TaxEd(t.Parent()) {
+		if t == tax {
+			return true
+		}
+	}
+	return false
+}
+
+var addHelp = `
+Usage:
+    a <name>
+    add <name>
+Add a new taxon as a correct child of the current taxon, and move to
+the new taxon. If the current taxon is the root, the new taxon will be
+added to the root of the taxonomy. The new taxon will be unranked (use
+'rank' to set its rank).
+`
+
+func addCmd(args []string) (bool, error) {
+	nm := strings.Join(args, " ")
+	if nm == "" {
+		return false, errors.New("expecting a taxon name")
+	}
+	var p string
+	if tax != nil {
+		if !tax.IsCorrect() {
+			return false, errors.New("a synonym can not be a parent")
+		}
+		p = tax.ID()
+	}
+	nt, err := txm.Add(nm, p, biodv.Unranked, true)
+	if err != nil {
+		return false, err
+	}
+	tax = nt
+	return false, nil
+}
+
+var asHelp = `
+Usage:
+    as <name>
+Add a new taxon as a synonym of the current taxon. The current taxon
+is not changed. The new synonym will be unranked (use 'rank' to set its
+rank).
+`
+
+func asCmd(args []string) (bool, error) {
+	nm := strings.Join(args, " ")
+	if nm == "" {
+		return false, errors.New("expecting a taxon name")
+	}
+	if tax == nil {
+		return false, errors.New("a synonym can not be added to the root")
+	}
+	if !tax.IsCorrect() {
+		return false, errors.New("a synonym can not be a parent")
+	}
+	if _, err := txm.Add(nm, tax.ID(), biodv.Unranked, false); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+var correctHelp = `
+Usage:
+    c [<taxon>]
+    correct [<taxon>]
+Set the current taxon as a correct name, as a child of the indicated
+taxon. If no taxon is given, the current taxon will be a child of the
+parent of its senior synonym. Use '/' to move the taxon to the root of
+the taxonomy.
+`
+
+func correctCmd(args []string) (bool, error) {
+	if tax == nil {
+		return false, errors.New("a taxon should be set")
+	}
+	var p *taxonomy.Taxon
+	if len(args) == 0 {
+		if tax.IsCorrect() {
+			return false, nil
+		}
+		p = txm.TaxEd(tax.Parent())
+		if p != nil {
+			p = txm.TaxEd(p.Parent())
+		}
+	} else {
+		var ok bool
+		p, ok = getTaxon(args)
+		if !ok {
+			return false, errors.Errorf("taxon '%s' not in database", strings.Join(args, " "))
+		}
+	}
+	if isDesc(p) {
+		return false, errors.New("a taxon can not be moved to itself or a descendant")
+	}
+	var pID string
+	if p != nil {
+		pID = p.ID()
+	}
+	return false, tax.Move(pID, true)
+}
+
+var deleteHelp = `
+Usage:
+    del [-r] [<taxon>]
+    delete [-r] [<taxon>]
+Removes the indicated taxon from the database. If no taxon is given, it
+will remove the current taxon, and move to its parent. By default,
+children and synonyms of the deleted taxon will be moved to its parent.
+If -r is given, all the descendants of the taxon will be deleted too.
+If the taxon is attached to the root, its synonyms will be also
+deleted.
+`
+
+func deleteCmd(args []string) (bool, error) {
+	rec := false
+	if len(args) > 0 && args[0] == "-r" {
+		rec = true
+		args = args[1:]
+	}
+	dt, ok := getTaxon(args)
+	if !ok {
+		return false, nil
+	}
+	if dt == nil {
+		return false, errors.New("the root can not be deleted")
+	}
+	p := txm.TaxEd(dt.Parent())
+	dt.Delete(rec)
+	if tax != nil && txm.TaxEd(tax.ID()) != tax {
+		tax = p
+	}
+	return false, nil
+}
+
+var descHelp = `
+Usage:
+    d [<taxon>]
+    desc [<taxon>]
+Without parameters shows the list of correct children of the current
+taxon. If a taxon is given, it will show the children of the indicated
+taxon. Use 'synonyms' to list the synonyms.
+`
+
+func descCmd(args []string) (bool, error) {
+	dt, ok := getTaxon(args)
+	if !ok {
+		return false, nil
+	}
+	var id string
+	if dt != nil {
+		id = dt.ID()
+	}
+	for _, c := range txm.TaxList(id) {
+		if !c.IsCorrect() {
+			continue
+		}
+		fmt.Printf("%s %s\n", c.Name(), c.Value(biodv.TaxAuthor))
+	}
+	return false, nil
+}
+
+var exitHelp = `
+Usage:
+    e
+    exit
+Perform 'write' and then 'quit' commands.
+`
+
+func exitCmd(args []string) (bool, error) {
+	if _, err := writeCmd(args); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+var moveHelp = `
+Usage:
+    m <taxon>
+    move <taxon>
+Move the current taxon to a new parent, keeping its current status
+(correct or synonym). Use '/' to move the taxon to the root of the
+taxonomy (only correct names can be attached to the root). If the
+current taxon is a synonym, and it has descendants, all of the
+descendants will be moved as children of the new parent.
+`
+
+func moveCmd(args []string) (bool, error) {
+	if tax == nil {
+		return false, errors.New("a taxon should be set")
+	}
+	if len(args) == 0 {
+		return false, errors.New("expecting a taxon")
+	}
+	p, ok := getTaxon(args)
+	if !ok {
+		return false, errors.Errorf("taxon '%s' not in database", strings.Join(args, " "))
+	}
+	if isDesc(p) {
+		return false, errors.New("a taxon can not be moved to itself or a descendant")
+	}
+	var pID string
+	if p != nil {
+		pID = p.ID()
+	}
+	return false, tax.Move(pID, tax.IsCorrect())
+}
+
+// Siblings returns the siblings
+// of the current taxon
+// (including the current taxon),
+// and the index of the current taxon.
+func siblings() ([]*taxonomy.Taxon, int) {
+	ls := txm.TaxList(tax.Parent())
+	for i, c := range ls {
+		if c == tax {
+			return ls, i
+		}
+	}
+	return ls, -1
+}
+
+var nextHelp = `
+Usage:
+    n
+    next
+Move to the next sibling of the current taxon. If the current taxon is
+the root, it will move to the first taxon of the root.
+`
+
+func nextCmd(args []string) (bool, error) {
+	if tax == nil {
+		ls := txm.TaxList("")
+		if len(ls) > 0 {
+			tax = ls[0]
+		}
+		return false, nil
+	}
+	ls, i := siblings()
+	if i+1 < len(ls) {
+		tax = ls[i+1]
+	}
+	return false, nil
+}
+
+var nvHelp = `
+Usage:
+    nv
+Perform 'next' and then 'view' commands.
+`
+
+func nvCmd(args []string) (bool, error) {
+	nextCmd(nil)
+	return viewCmd(nil)
+}
+
+var prevHelp = `
+Usage:
+    p
+    prev
+Move to the previous sibling of the current taxon. If the current taxon
+is the root, it will move to the last taxon of the root.
+`
+
+func prevCmd(args []string) (bool, error) {
+	if tax == nil {
+		ls := txm.TaxList("")
+		if len(ls) > 0 {
+			tax = ls[len(ls)-1]
+		}
+		return false, nil
+	}
+	ls, i := siblings()
+	if i > 0 {
+		tax = ls[i-1]
+	}
+	return false, nil
+}
+
+var pvHelp = `
+Usage:
+    pv
+Perform 'prev' and then 'view' commands.
+`
+
+func pvCmd(args []string) (bool, error) {
+	prevCmd(nil)
+	return viewCmd(nil)
+}
+
+var quitHelp = `
+Usage:
+    q
+    quit
+Ends the program without saving any change.
+`
+
+var rankHelp = `
+Usage:
+    rk [<rank>]
+    rank [<rank>]
+Without parameters, print the rank of the current taxon. If the taxon is
+unranked, the rank of the most inmediate ranked parent will be printed
+in parenthesis. If a rank is given, it will be set as the rank of the
+current taxon. The new rank should be compatible with the taxonomy.
+Valid ranks are:
+    unranked
+    kingdom      subkingdom
+    phylum       subphylum
+    superclass   class        subclass     infraclass
+    superorder   order        suborder     infraorder
+    superfamily  family       subfamily    tribe     subtribe
+    genus        subgenus
+    species      subspecies   variety      form
+`
+
+func rankCmd(args []string) (bool, error) {
+	if tax == nil {
+		return false, nil
+	}
+	if len(args) > 0 {
+		rs := strings.Join(args, " ")
+		r := biodv.GetRank(rs)
+		if r == biodv.Unranked && strings.ToLower(rs) != biodv.Unranked.String() {
+			return false, errors.Errorf("unknown rank %s", rs)
+		}
+		return false, tax.SetRank(r)
+	}
+
+	r := tax.Rank()
+	if r != biodv.Unranked {
+		fmt.Printf("%s\n", r)
+		return false, nil
+	}
+	for p := txm.TaxEd(tax.Parent()); p != nil; p = txm.TaxEd(p.Parent()) {
+		if p.Rank() != biodv.Unranked {
+			fmt.Printf("%s (%s)\n", biodv.Unranked, p.Rank())
+			return false, nil
+		}
+	}
+	fmt.Printf("%s\n", r)
+	return false, nil
+}
+
+var setHelp = `
+Usage:
+    s <key> <value>
+    set <key> <value>
+Set a value of a field of the current taxon. The name, rank, parent
+and status of the taxon can not be set (use 'rank', 'move',
+'correct', or 'synonymize').
+
+Any key can be stored, but the recognized keys (and their expected
+values are):
+        author     the taxon's author.
+        extern     an ID on an external database, in the form
+                   "<service>:<id>", if only "<service>:" is given, the
+                   extern ID of that service will be eliminated.
+        reference  a bibliographic reference.
+        source     the ID of the source of the taxonomic data.
+
+If value is set to ‘-’ then it will remove any value from the given
+key. If the value starts with a ‘+’ it will be append the value (in
+the case that append is valid).
+`
+
+func setCmd(args []string) (bool, error) {
+	if tax == nil {
+		return false, errors.New("a taxon should be set")
+	}
+	if len(args) < 2 {
+		return false, errors.New("expecing <key> <value> parameters")
+	}
+	key := strings.ToLower(args[0])
+	value := strings.Join(args[1:], " ")
+	if strings.HasPrefix(value, "+") && key != biodv.TaxExtern {
+		value = tax.Value(key) + "\n" + value[1:]
+	}
+	if value == "-" {
+		value = ""
+		if key == biodv.TaxExtern {
+			for _, e := range strings.Fields(tax.Value(key)) {
+				i := strings.Index(e, ":")
+				if err := tax.Set(key, e[:i+1]); err != nil {
+					return false, err
+				}
+			}
+			return false, nil
+		}
+	}
+	return false, tax.Set(key, value)
+}
+
+var svHelp = `
+Usage:
+    sv <key> <value>
+Perform 'set' and then 'view' commands.
+`
+
+func svCmd(args []string) (bool, error) {
+	if _, err := setCmd(args); err != nil {
+		return false, err
+	}
+	return viewCmd(nil)
+}
+
+var synonymsHelp = `
+Usage:
+    sy [<taxon>]
+    synonyms [<taxon>]
+Without parameters shows the list of synonyms of the current taxon. If
+a taxon is given, it will show the synonyms of the indicated taxon.
+`
+
+func synonymsCmd(args []string) (bool, error) {
+	dt, ok := getTaxon(args)
+	if !ok || dt == nil {
+		return false, nil
+	}
+	for _, c := range txm.TaxList(dt.ID()) {
+		if c.IsCorrect() {
+			continue
+		}
+		fmt.Printf("%s %s\n", c.Name(), c.Value(biodv.TaxAuthor))
+	}
+	return false, nil
+}
+
+var synonymizeHelp = `
+Usage:
+    syn <taxon>
+    synonymize <taxon>
+Set the current taxon as a synonym of the indicated taxon. If the
+current taxon has descendants, all of them will be moved as children
+of the indicated taxon.
+`
+
+func synonymizeCmd(args []string) (bool, error) {
+	if tax == nil {
+		return false, errors.New("a taxon should be set")
+	}
+	if len(args) == 0 {
+		return false, errors.New("expecting a taxon")
+	}
+	p, ok := getTaxon(args)
+	if !ok || p == nil {
+		return false, errors.Errorf("taxon '%s' not in database", strings.Join(args, " "))
+	}
+	if isDesc(p) {
+		return false, errors.New("a taxon can not be a synonym of itself or a descendant")
+	}
+	return false, tax.Move(p.ID(), false)
+}
+
+var taxonHelp = `
+Usage:
+    t <taxon>
+    taxon <taxon>
+Changes the current taxon to the indicated taxon. Use '..' to move to
+the parent, or use '/' to move to the root of the taxonomy.
+`
+
+func taxonCmd(args []string) (bool, error) {
+	nt, ok := getTaxon(args)
+	if !ok {
+		return false, nil
+	}
+	tax = nt
+	return false, nil
+}
+
+var viewHelp = `
+Usage:
+    v [<taxon>]
+    view [<taxon>]
+Shows the information stored on the indicated taxon. If no taxon is
+given, it will show the current taxon.
+`
+
+func viewCmd(args []string) (bool, error) {
+	vt, ok := getTaxon(args)
+	if !ok || vt == nil {
+		return false, nil
+	}
+
+	fmt.Printf("name:\t%s\n", vt.Name())
+	fmt.Printf("rank:\t%s\n", vt.Rank())
+	fmt.Printf("parent:\t%s\n", vt.Parent())
+	if vt.IsCorrect() {
+		fmt.Printf("correct:\ttrue\n")
+	} else {
+		fmt.Printf("correct:\tfalse\n")
+	}
+	for _, k := range vt.Keys() {
+		fmt.Printf("%s:\t%s\n", k, vt.Value(k))
+	}
+	return false, nil
+}
+
+var writeHelp = `
+Usage:
+    w
+    write
+Write all changes made to the database since the start of the
+edition season, or the last writing.
+`
+
+func writeCmd(args []string) (bool, error) {
+	if err := txm.Commit(); err != nil {
+		return false, err
+	}
+	return false, nil
+}
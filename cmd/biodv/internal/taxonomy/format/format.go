@@ -27,7 +27,9 @@ var cmd = &cmdapp.Command{
 Command tax.format search for all unranked taxons (except from taxons
 attached to the root), and make them synonyms, so the taxonomy will only
 have as correct names, names with some rank. This is useful to, for
-example, collapse subspecies as synonyms of a species.
+example, collapse unranked infraspecific names as synonyms of a species.
+Names with an explicit infraspecific rank (i.e. subspecies, variety, or
+form) are kept as correct names.
 
 Options are:
 
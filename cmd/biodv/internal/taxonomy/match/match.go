@@ -0,0 +1,134 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package match implements the tax.match command,
+// i.e. match names with the taxonomy.
+package match
+
+import (
+	"bufio"
+	"encoding/csv"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/js-arias/biodv/cmdapp"
+	"github.com/js-arias/biodv/taxonomy"
+
+	"github.com/pkg/errors"
+)
+
+var cmd = &cmdapp.Command{
+	UsageLine: "tax.match [-c|--candidates <number>] [--noheader]",
+	Short:     "match names with the taxonomy",
+	Long: `
+Command tax.match reads taxon names from the standard input, and
+searches the taxonomy database for the taxa that match each name,
+even if the name is misspelled, or the gender ending of an epithet
+is changed. It assumes that each line contains a taxon name (empty
+lines, or lines starting with the sharp symbol ( # ) or semicolon
+character ( ; ) will be ignored). The name can include its authors.
+
+The matching is inspired by Taxamatch (Rees 2014, PLoS ONE 9: e107510):
+each word of the name is compared with the words of the taxa in the
+database, and two words are matched if they are equal, if they are
+equal after removing the gender ending (in epithets), if they have the
+same phonetic key, or if they have a small edit distance. Each match
+has a score, from 0 to 1 (an exact match).
+
+The output is a table separated by tabs, with the name, the matched
+taxon, the score, and the accepted name of the taxon, if it is a
+synonym. If a name has no matches, it will be printed with empty
+columns.
+
+Options are:
+
+    -c <number>
+    --candidates <number>
+      Sets the maximum number of candidates printed for each name. By
+      default only the best candidate is printed.
+
+    --noheader
+      If set, the table will be printed without the columns header.
+	`,
+	Run:           run,
+	RegisterFlags: register,
+}
+
+func init() {
+	cmdapp.Add(cmd)
+}
+
+var candidates int
+var nohead bool
+
+func register(c *cmdapp.Command) {
+	c.Flag.IntVar(&candidates, "candidates", 1, "")
+	c.Flag.IntVar(&candidates, "c", 1, "")
+	c.Flag.BoolVar(&nohead, "noheader", false, "")
+}
+
+func run(c *cmdapp.Command, args []string) error {
+	db, err := taxonomy.Open("")
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	if candidates < 1 {
+		candidates = 1
+	}
+
+	w := csv.NewWriter(os.Stdout)
+	w.Comma = '\t'
+	w.UseCRLF = true
+	if !nohead {
+		if err := w.Write([]string{"Name", "Taxon", "Score", "Accepted"}); err != nil {
+			return errors.Wrap(err, c.Name())
+		}
+	}
+
+	s := bufio.NewScanner(os.Stdin)
+	for s.Scan() {
+		ln := strings.TrimSpace(s.Text())
+		if ln == "" {
+			continue
+		}
+		if r, _ := utf8.DecodeRuneInString(ln); r == '#' || r == ';' || !unicode.IsLetter(r) {
+			continue
+		}
+
+		ls := db.Match(ln)
+		if len(ls) == 0 {
+			if err := w.Write([]string{ln, "", "", ""}); err != nil {
+				return errors.Wrap(err, c.Name())
+			}
+			continue
+		}
+		if len(ls) > candidates {
+			ls = ls[:candidates]
+		}
+		for _, m := range ls {
+			acc := ""
+			if !m.Taxon.IsCorrect() {
+				acc = m.Taxon.Parent()
+			}
+			row := []string{ln, m.Taxon.Name(), strconv.FormatFloat(m.Score, 'f', 3, 64), acc}
+			if err := w.Write(row); err != nil {
+				return errors.Wrap(err, c.Name())
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return errors.Wrapf(err, "%s: while reading from stdin", c.Name())
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	return nil
+}
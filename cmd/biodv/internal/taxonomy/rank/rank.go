@@ -24,7 +24,8 @@ var cmd = &cmdapp.Command{
 	Long: `
 Command tax.rank sets a new rank to a given taxon. If no rank is
 defined, it will set the taxon as unranked. The new rank should be
-compatible with the current taxonomy.
+compatible with the current taxonomy. Taxons with an infraspecific rank
+(subspecies, variety, or form) should be placed below a species.
 
 Options are:
 
@@ -32,13 +33,16 @@ Options are:
     --rank <rank>
       Sets the new rank of the taxon.
       Valid ranks are:
-        unranked  (default)
-        kingdom
-        class
-        order
-        family
-        genus
-        species
+        unranked     (default)
+        kingdom      subkingdom
+        phylum       subphylum
+        superclass   class        subclass     infraclass
+        superorder   order        suborder     infraorder
+        superfamily  family       subfamily    tribe     subtribe
+        genus        subgenus
+        species      subspecies   variety      form
+      The abbreviations subsp. (or ssp.), var., and f. are also
+      accepted.
 
     <name>
       The taxon to be reranked. This parameter is required.
@@ -75,7 +79,7 @@ func run(c *cmdapp.Command, args []string) error {
 	}
 
 	r := biodv.GetRank(rankStr)
-	if r.String() != strings.ToLower(rankStr) {
+	if r == biodv.Unranked && strings.ToLower(rankStr) != biodv.Unranked.String() {
 		return errors.Errorf("%s: unknown rank %s", c.Name(), rankStr)
 	}
 	if err := tax.SetRank(r); err != nil {
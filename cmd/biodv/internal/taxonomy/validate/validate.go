@@ -9,30 +9,114 @@
 package validate
 
 import (
+	"bufio"
+	"fmt"
+	"os"
+
 	"github.com/js-arias/biodv/cmdapp"
+	"github.com/js-arias/biodv/encoding/stanza"
 	"github.com/js-arias/biodv/taxonomy"
 
 	"github.com/pkg/errors"
 )
 
 var cmd = &cmdapp.Command{
-	UsageLine: "tax.validate",
+	UsageLine: "tax.validate [--stanza]",
 	Short:     "validate a taxonomy database",
 	Long: `
 Command tax.validate validates a taxonomy database. It is useful to test
-if a biodov database from a third party is correct.  If there are no
-errors, it will finish silently.
+if a biodv database from a third party is correct.
+
+The taxa are read directly from the database file, and all the problems
+found are reported. The taxa with problems will be printed in the
+standard output, one problem per line, with the taxon name, and the
+problem, separated by tabs. If there are no problems, it will finish
+silently, otherwise, it will finish with an error (i.e. with a non-zero
+exit status), so it can be used in scripts. The following problems are
+reported:
+
+    no name                 the taxon does not have a name.
+    duplicated name         the name is used by another taxon.
+    invalid rank            the rank is unknown.
+    unknown parent          the parent of the taxon is not in the
+                            taxonomy.
+    parent after taxon      the parent is defined after the taxon in
+                            the database file.
+    synonym parent          the parent of the taxon is a synonym.
+    synonym without parent  the taxon is a synonym attached to the
+                            root of the taxonomy.
+    rank inversion          the rank of the taxon is not lower than
+                            the rank of its parents (synonyms can have
+                            the rank of its parent, if both ranks are
+                            from the same nomenclatural group).
+
+Options are:
+
+    --stanza
+      If set, the problems will be printed in the stanza format, with
+      the fields 'name', 'problem', and 'value' (the value with the
+      problem, if any).
 	`,
-	Run: run,
+	Run:           run,
+	RegisterFlags: register,
 }
 
 func init() {
 	cmdapp.Add(cmd)
 }
 
+var stzOut bool
+
+func register(c *cmdapp.Command) {
+	c.Flag.BoolVar(&stzOut, "stanza", false, "")
+}
+
 func run(c *cmdapp.Command, args []string) error {
-	if _, err := taxonomy.Open(""); err != nil {
+	probs, err := taxonomy.Validate("")
+	if err != nil {
 		return errors.Wrap(err, c.Name())
 	}
+
+	if stzOut {
+		err = writeStanza(probs)
+	} else {
+		err = writeText(probs)
+	}
+	if err != nil {
+		return errors.Wrap(err, c.Name())
+	}
+	if len(probs) > 0 {
+		return errors.Errorf("%s: problems found: %d", c.Name(), len(probs))
+	}
 	return nil
 }
+
+// WriteText writes the problems
+// in the standard output
+// as text.
+func writeText(probs []taxonomy.Problem) error {
+	w := bufio.NewWriter(os.Stdout)
+	for _, p := range probs {
+		fmt.Fprintf(w, "%s\t%s\n", p.ID, p)
+	}
+	return w.Flush()
+}
+
+// WriteStanza writes the problems
+// in the standard output
+// in the stanza format.
+func writeStanza(probs []taxonomy.Problem) error {
+	w := stanza.NewWriter(os.Stdout)
+	w.SetFields([]string{"name", "problem", "value"})
+	for _, p := range probs {
+		rec := map[string]string{
+			"name":    p.ID,
+			"problem": p.Kind,
+			"value":   p.Value,
+		}
+		if err := w.Write(rec); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
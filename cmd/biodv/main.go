@@ -8,7 +8,11 @@
 package main
 
 import (
+	"flag"
+
 	"github.com/js-arias/biodv/cmdapp"
+	"github.com/js-arias/biodv/driver/web"
+	"github.com/js-arias/biodv/journal"
 
 	// image drivers
 	_ "image/gif"
@@ -18,5 +22,8 @@ import (
 
 func main() {
 	cmdapp.Short = "Biodv is a tool for management and analysis of biodiveristy data."
+	flag.DurationVar(&journal.Timeout, "wait", journal.Timeout, "time to wait for a locked database")
+	flag.BoolVar(&web.Offline, "offline", false, "answer web requests only from the cache")
+	flag.DurationVar(&web.TTL, "cache", web.TTL, "time to keep cached web answers")
 	cmdapp.Main()
 }
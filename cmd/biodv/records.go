@@ -14,11 +14,17 @@ import (
 	_ "github.com/js-arias/biodv/cmd/biodv/internal/records/assign"
 	_ "github.com/js-arias/biodv/cmd/biodv/internal/records/dbadd"
 	_ "github.com/js-arias/biodv/cmd/biodv/internal/records/del"
+	_ "github.com/js-arias/biodv/cmd/biodv/internal/records/dups"
+	_ "github.com/js-arias/biodv/cmd/biodv/internal/records/dwcaimport"
 	_ "github.com/js-arias/biodv/cmd/biodv/internal/records/ed"
+	_ "github.com/js-arias/biodv/cmd/biodv/internal/records/flag"
+	_ "github.com/js-arias/biodv/cmd/biodv/internal/records/geocheck"
 	_ "github.com/js-arias/biodv/cmd/biodv/internal/records/georef"
 	_ "github.com/js-arias/biodv/cmd/biodv/internal/records/gzgeoref"
 	_ "github.com/js-arias/biodv/cmd/biodv/internal/records/info"
 	_ "github.com/js-arias/biodv/cmd/biodv/internal/records/mapcmd"
+	_ "github.com/js-arias/biodv/cmd/biodv/internal/records/outliers"
+	_ "github.com/js-arias/biodv/cmd/biodv/internal/records/selcmd"
 	_ "github.com/js-arias/biodv/cmd/biodv/internal/records/set"
 	_ "github.com/js-arias/biodv/cmd/biodv/internal/records/table"
 	_ "github.com/js-arias/biodv/cmd/biodv/internal/records/validate"
@@ -82,6 +88,8 @@ The following fields are recognized by the biodv records commands:
     sex          sex of the organism.
     altitude     in flying specimens, the altitude above ground when
                  the observation was made.
+    flags        a list of flags (separated by spaces) of problems
+                 detected in the record (e.g. with rec.flag).
 
 Most biodv commands assume that the specimen records datafiles are well
 formatted. In the case of an untrusted database, it can be validated with
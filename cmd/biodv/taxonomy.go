@@ -17,9 +17,11 @@ import (
 	_ "github.com/js-arias/biodv/cmd/biodv/internal/taxonomy/dbsync"
 	_ "github.com/js-arias/biodv/cmd/biodv/internal/taxonomy/dbupdate"
 	_ "github.com/js-arias/biodv/cmd/biodv/internal/taxonomy/del"
+	_ "github.com/js-arias/biodv/cmd/biodv/internal/taxonomy/ed"
 	_ "github.com/js-arias/biodv/cmd/biodv/internal/taxonomy/format"
 	_ "github.com/js-arias/biodv/cmd/biodv/internal/taxonomy/info"
 	_ "github.com/js-arias/biodv/cmd/biodv/internal/taxonomy/list"
+	_ "github.com/js-arias/biodv/cmd/biodv/internal/taxonomy/match"
 	_ "github.com/js-arias/biodv/cmd/biodv/internal/taxonomy/move"
 	_ "github.com/js-arias/biodv/cmd/biodv/internal/taxonomy/rank"
 	_ "github.com/js-arias/biodv/cmd/biodv/internal/taxonomy/set"
@@ -9,13 +9,13 @@
 package dataset
 
 import (
-	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 
 	"github.com/js-arias/biodv"
 	"github.com/js-arias/biodv/encoding/stanza"
+	"github.com/js-arias/biodv/journal"
 
 	"github.com/pkg/errors"
 )
@@ -36,8 +36,20 @@ type DB struct {
 	path    string
 	ids     map[string]*Dataset
 	changed bool // true if the database was modified
+
+	// version of the database directory
+	// at the last commit
+	version string
+
+	// values of the datasets
+	// at the last commit
+	orig map[string]map[string]string
 }
 
+// Name of the dataset database
+// in the change log.
+const logName = "dataset"
+
 // SetID returns a dataset with a given ID.
 // This function is for compatibility with biodv.SetDB interface.
 //
@@ -212,6 +224,12 @@ func open(path string) (biodv.SetDB, error) {
 // Open opens a DB
 // on a given path.
 func Open(path string) (*DB, error) {
+	lk, err := journal.Shared(path, setDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "dataset: open")
+	}
+	defer lk.Release()
+
 	db := &DB{
 		path: path,
 		ids:  make(map[string]*Dataset),
@@ -220,6 +238,8 @@ func Open(path string) (*DB, error) {
 		return nil, err
 	}
 	db.changed = false
+	db.orig = db.snapshot()
+	db.version = journal.Version(path, setDir)
 	return db, nil
 }
 
@@ -227,20 +247,74 @@ func Open(path string) (*DB, error) {
 // to load a database.
 func (db *DB) scan(sc *Scanner) error {
 	for sc.Scan() {
-		r := sc.Dataset()
-		set, err := db.Add(r.Title())
-		if err != nil {
+		if err := db.addSet(sc.Dataset()); err != nil {
 			sc.Close()
 			return err
 		}
-		keys := r.Keys()
-		for _, k := range keys {
-			if err := set.Set(k, r.Value(k)); err != nil {
-				return err
+	}
+	return sc.Err()
+}
+
+// AddSet adds a dataset,
+// and its values,
+// to the database.
+func (db *DB) addSet(r biodv.Dataset) error {
+	set, err := db.Add(r.Title())
+	if err != nil {
+		return err
+	}
+	keys := r.Keys()
+	for _, k := range keys {
+		if err := set.Set(k, r.Value(k)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Snapshot returns a copy
+// of the values of the datasets
+// in the database.
+func (db *DB) snapshot() map[string]map[string]string {
+	s := make(map[string]map[string]string)
+	for _, set := range db.List() {
+		m := make(map[string]string, len(set.data))
+		for k, v := range set.data {
+			if v == "" {
+				continue
 			}
+			m[k] = v
 		}
+		s[set.ID()] = m
 	}
-	return sc.Err()
+	return s
+}
+
+// Undo reverts a list of changes
+// stored in the change log,
+// ordered from the oldest to the newest.
+// Changes of other databases are ignored.
+func (db *DB) Undo(ch []journal.Change) error {
+	data := db.snapshot()
+	journal.Revert(logName, data, ch)
+
+	db.ids = make(map[string]*Dataset)
+	db.changed = true
+	ls := make([]string, 0, len(data))
+	for id := range data {
+		ls = append(ls, id)
+	}
+	sort.Strings(ls)
+	for _, id := range ls {
+		r := dataset(data[id])
+		if r.Title() == "" {
+			continue
+		}
+		if err := db.addSet(r); err != nil {
+			return errors.Wrap(err, "dataset: undo")
+		}
+	}
+	return nil
 }
 
 // Add adds a new dataset metadata to the DB.
@@ -262,50 +336,102 @@ func (db *DB) Add(title string) (*Dataset, error) {
 	return set, nil
 }
 
+// List returns the list of datasets
+// in the database,
+// sorted by its ID.
+func (db *DB) List() []*Dataset {
+	var ls []string
+	ids := make(map[string]bool)
+	for _, s := range db.ids {
+		if ids[s.ID()] {
+			continue
+		}
+		ls = append(ls, s.ID())
+		ids[s.ID()] = true
+	}
+	sort.Strings(ls)
+
+	sets := make([]*Dataset, 0, len(ls))
+	for _, id := range ls {
+		sets = append(sets, db.ids[id])
+	}
+	return sets
+}
+
+// Delete removes a dataset from the database.
+func (db *DB) Delete(id string) {
+	set := db.SetEd(id)
+	if set == nil {
+		return
+	}
+	delete(db.ids, set.ID())
+	for _, e := range strings.Fields(set.Value(biodv.SetExtern)) {
+		delete(db.ids, e)
+	}
+	db.changed = true
+}
+
 // Commit saves a dataset database to a file.
-func (db *DB) Commit() (err error) {
+func (db *DB) Commit() error {
 	if !db.changed {
 		return nil
 	}
+	tx, err := journal.Begin(db.path)
+	if err != nil {
+		return errors.Wrap(err, "dataset: db: commit")
+	}
+	if err := db.CommitTx(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "dataset: db: commit")
+	}
+	return nil
+}
 
-	if _, err := os.Lstat(filepath.Join(db.path, setDir)); err != nil {
-		if err := os.Mkdir(filepath.Join(db.path, setDir), os.ModeDir|os.ModePerm); err != nil {
-			return errors.Wrapf(err, "dataset: db: commit: unable to create %s directory", setDir)
-		}
+// CommitTx writes the dataset database
+// as part of a transaction,
+// so it can be committed
+// with other databases.
+// The transaction must be started
+// in the same path of the database.
+func (db *DB) CommitTx(tx *journal.Tx) (err error) {
+	if !db.changed {
+		return nil
 	}
 
-	file := filepath.Join(db.path, setDir, setFile)
-	f, err := os.Create(file)
+	v, err := tx.Update(setDir, db.version)
 	if err != nil {
 		return errors.Wrap(err, "dataset: db: commit")
 	}
+	f, err := tx.Create(filepath.Join(setDir, setFile))
+	if err != nil {
+		return errors.Wrap(err, "dataset: db: commit")
+	}
+	w := stanza.NewWriter(f)
 	defer func() {
-		e1 := f.Close()
+		e1 := w.Flush()
+		if e2 := f.Close(); e1 == nil {
+			e1 = e2
+		}
 		if err == nil && e1 != nil {
 			err = errors.Wrap(e1, "dataset: db: commit")
 		}
 	}()
 
-	w := stanza.NewWriter(f)
-	defer w.Flush()
-
-	var ls []string
-	ids := make(map[string]bool)
-	for _, s := range db.ids {
-		if ids[s.ID()] {
-			continue
-		}
-		ls = append(ls, s.ID())
-		ids[s.ID()] = true
-	}
-	sort.Strings(ls)
-
-	for _, id := range ls {
-		s := db.ids[id]
+	for _, s := range db.List() {
 		if err := s.encode(w); err != nil {
 			return errors.Wrap(err, "dataset: db: commit")
 		}
 	}
-	db.changed = false
+
+	cur := db.snapshot()
+	tx.Log(journal.Diff(logName, db.orig, cur)...)
+	tx.OnCommit(func() {
+		db.orig = cur
+		db.version = v
+		db.changed = false
+	})
 	return
 }
@@ -6,7 +6,11 @@
 
 package dataset
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/js-arias/biodv"
+)
 
 var testData = []struct {
 	title   string
@@ -60,3 +64,32 @@ func TestAdd(t *testing.T) {
 		}
 	}
 }
+
+func TestDelete(t *testing.T) {
+	db := &DB{ids: make(map[string]*Dataset)}
+	for _, d := range testData {
+		if _, err := db.Add(d.title); err != nil {
+			t.Fatalf("when adding %q: %v", d.title, err)
+		}
+	}
+	set := db.SetEd(testData[1].title)
+	if err := set.Set(biodv.SetExtern, "gbif:821cc27a-e3bb-4bc5-ac34-89ada245069d"); err != nil {
+		t.Fatalf("when setting extern: %v", err)
+	}
+
+	db.Delete("gbif:821cc27a-e3bb-4bc5-ac34-89ada245069d")
+	if db.SetEd(testData[1].title) != nil {
+		t.Errorf("dataset %q not deleted", testData[1].title)
+	}
+	if db.SetEd("gbif:821cc27a-e3bb-4bc5-ac34-89ada245069d") != nil {
+		t.Errorf("extern ID not deleted")
+	}
+
+	ls := db.List()
+	if len(ls) != 2 {
+		t.Fatalf("list: %d datasets, want %d", len(ls), 2)
+	}
+	if ls[0].ID() != testData[0].title || ls[1].ID() != testData[2].title {
+		t.Errorf("list: [%s %s], want [%s %s]", ls[0].ID(), ls[1].ID(), testData[0].title, testData[2].title)
+	}
+}
@@ -0,0 +1,15 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package col implements an interface
+// to the Catalogue of Life,
+// using the ChecklistBank webservice.
+package col
+
+import "github.com/js-arias/biodv/driver/web"
+
+// Client is the Client of the ChecklistBank webservice.
+var Client = web.New("col", "https://api.checklistbank.org/")
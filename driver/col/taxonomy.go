@@ -0,0 +1,287 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package col
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/biodv"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	biodv.RegisterTax("col", biodv.TaxDriver{OpenTax, TaxURL, aboutTaxCoL})
+}
+
+// TaxURL returns the url of a Catalogue of Life taxon.
+func TaxURL(id string) string {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return ""
+	}
+	return "https://www.catalogueoflife.org/data/taxon/" + url.PathEscape(id)
+}
+
+// LatestRelease is the ChecklistBank key
+// of the latest release of the Catalogue of Life.
+const LatestRelease = "3LR"
+
+// OpenTax returns the Catalogue of Life
+// taxonomy handler,
+// that implements the biodv.Taxonomy interface.
+//
+// The param is the key of a ChecklistBank dataset,
+// by default,
+// it uses the latest release
+// of the Catalogue of Life.
+func OpenTax(param string) (biodv.Taxonomy, error) {
+	key := strings.TrimSpace(param)
+	if key == "" {
+		key = LatestRelease
+	}
+	return taxDB{key: key}, nil
+}
+
+// TaxDB is the handler of a ChecklistBank dataset.
+type taxDB struct {
+	key string
+}
+
+// AboutTaxCoL returns a simple statement of the purpose of the driver.
+func aboutTaxCoL() string {
+	return "a driver for the Catalogue of Life taxonomy DB (ChecklistBank)"
+}
+
+// PageAnswer is the answer for a request
+// of a list of taxons.
+type pageAnswer struct {
+	Offset, Limit int64
+	Last          bool
+	Result        []*usage
+}
+
+// SearchAnswer is the answer for a name search.
+type searchAnswer struct {
+	Offset, Limit int64
+	Last          bool
+	Result        []struct {
+		Usage *usage
+	}
+}
+
+// SynAnswer is the answer for the synonyms request.
+type synAnswer struct {
+	Homotypic   []*usage
+	Heterotypic []*usage
+}
+
+// Usage stores the ChecklistBank taxonomic information
+// (a name usage).
+// It implements the biodv.Taxon interface.
+type usage struct {
+	Key              string `json:"id"` // id
+	ParentID         string // parent
+	Status           string // correct
+	SourceDatasetKey int64  // source
+	Accepted         *usage // parent of synonyms
+
+	SciName struct {
+		ScientificName string // name
+		Authorship     string // author
+		Rank           string // rank
+	} `json:"name"`
+}
+
+func (u *usage) Name() string {
+	return u.SciName.ScientificName
+}
+
+func (u *usage) ID() string {
+	return u.Key
+}
+
+func (u *usage) Parent() string {
+	if !u.IsCorrect() && u.Accepted != nil {
+		return u.Accepted.Key
+	}
+	return u.ParentID
+}
+
+func (u *usage) Rank() biodv.Rank {
+	return biodv.GetRank(u.SciName.Rank)
+}
+
+func (u *usage) IsCorrect() bool {
+	switch u.Status {
+	case "accepted", "provisionally accepted":
+		return true
+	}
+	return false
+}
+
+func (u *usage) Keys() []string {
+	return []string{
+		biodv.TaxAuthor,
+		biodv.TaxSource,
+	}
+}
+
+func (u *usage) Value(key string) string {
+	switch strings.ToLower(strings.TrimSpace(key)) {
+	case biodv.TaxAuthor:
+		return u.SciName.Authorship
+	case biodv.TaxSource:
+		if u.SourceDatasetKey == 0 {
+			return ""
+		}
+		return strconv.FormatInt(u.SourceDatasetKey, 10)
+	}
+	return ""
+}
+
+func (db taxDB) Taxon(name string) *biodv.TaxScan {
+	sc := biodv.NewTaxScan(100)
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		sc.Add(nil, errors.Errorf("col: taxonomy: empty taxon name"))
+		return sc
+	}
+	param := url.Values{}
+	param.Add("q", name)
+	param.Add("content", "SCIENTIFIC_NAME")
+	param.Add("type", "EXACT")
+	go db.search(sc, param, name)
+	return sc
+}
+
+func (db taxDB) Children(id string) *biodv.TaxScan {
+	sc := biodv.NewTaxScan(100)
+	id = strings.TrimSpace(id)
+	if id == "" {
+		param := url.Values{}
+		param.Add("rank", "kingdom")
+		param.Add("status", "accepted")
+		go db.search(sc, param, "")
+		return sc
+	}
+	go db.taxonList(sc, "taxon/"+url.PathEscape(id)+"/children")
+	return sc
+}
+
+func (db taxDB) Synonyms(id string) *biodv.TaxScan {
+	sc := biodv.NewTaxScan(100)
+	id = strings.TrimSpace(id)
+	if id == "" {
+		sc.Add(nil, errors.Errorf("col: taxonomy: invalid ID for synonyms"))
+		return sc
+	}
+	go func() {
+		resp := &synAnswer{}
+		if err := Client.GetJSON(db.req("taxon/"+url.PathEscape(id)+"/synonyms"), resp); err != nil {
+			sc.Add(nil, errors.Wrap(err, "col: taxonomy"))
+			return
+		}
+		for _, ls := range [][]*usage{resp.Homotypic, resp.Heterotypic} {
+			for _, u := range ls {
+				if u.ParentID == "" && u.Accepted == nil {
+					u.ParentID = id
+				}
+				if !sc.Add(u, nil) {
+					return
+				}
+			}
+		}
+		sc.Add(nil, nil)
+	}()
+	return sc
+}
+
+func (db taxDB) TaxID(id string) (biodv.Taxon, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return nil, errors.Errorf("col: taxonomy: empty taxon ID")
+	}
+	u := &usage{}
+	if err := Client.GetJSON(db.req("nameusage/"+url.PathEscape(id)), u); err != nil {
+		return nil, errors.Wrapf(err, "col: taxonomy: taxon %q", id)
+	}
+	return u, nil
+}
+
+// Req returns a request
+// on the dataset of the database.
+func (db taxDB) req(path string) string {
+	return "dataset/" + url.PathEscape(db.key) + "/" + path
+}
+
+// Search returns the taxons
+// found with a name search.
+// If name is not empty,
+// only the taxons with that name
+// are returned.
+func (db taxDB) search(sc *biodv.TaxScan, param url.Values, name string) {
+	param.Set("limit", "100")
+	seen := make(map[string]bool)
+	for off := int64(0); ; {
+		if off > 0 {
+			param.Set("offset", strconv.FormatInt(off, 10))
+		}
+		resp := &searchAnswer{}
+		if err := Client.GetListJSON(db.req("nameusage/search?"+param.Encode()), resp); err != nil {
+			sc.Add(nil, errors.Wrap(err, "col: taxonomy"))
+			return
+		}
+		for _, r := range resp.Result {
+			u := r.Usage
+			if u == nil || seen[u.Key] {
+				continue
+			}
+			if name != "" && !strings.EqualFold(u.Name(), name) {
+				continue
+			}
+			seen[u.Key] = true
+			if !sc.Add(u, nil) {
+				return
+			}
+		}
+		if resp.Last || len(resp.Result) == 0 {
+			break
+		}
+		off += int64(len(resp.Result))
+	}
+	sc.Add(nil, nil)
+}
+
+// TaxonList returns the taxons
+// of a paginated request.
+func (db taxDB) taxonList(sc *biodv.TaxScan, path string) {
+	param := url.Values{}
+	param.Set("limit", "100")
+	for off := int64(0); ; {
+		if off > 0 {
+			param.Set("offset", strconv.FormatInt(off, 10))
+		}
+		resp := &pageAnswer{}
+		if err := Client.GetListJSON(db.req(path+"?"+param.Encode()), resp); err != nil {
+			sc.Add(nil, errors.Wrap(err, "col: taxonomy"))
+			return
+		}
+		for _, u := range resp.Result {
+			if !sc.Add(u, nil) {
+				return
+			}
+		}
+		if resp.Last || len(resp.Result) == 0 {
+			break
+		}
+		off += int64(len(resp.Result))
+	}
+	sc.Add(nil, nil)
+}
@@ -0,0 +1,229 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package col
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/js-arias/biodv"
+	"github.com/js-arias/biodv/driver/web"
+)
+
+// Fixtures are answers recorded from ChecklistBank
+// (reduced to the fields used by the driver),
+// indexed by the request.
+var fixtures = map[string]string{
+	"/dataset/3LR/nameusage/search?content=SCIENTIFIC_NAME&limit=100&q=Rhea+americana&type=EXACT": rheaSearchBlob,
+	"/dataset/3LR/nameusage/search?limit=100&rank=kingdom&status=accepted":                        kingdomBlob,
+	"/dataset/3LR/nameusage/4QHKG":                rheaBlob,
+	"/dataset/3LR/nameusage/6Q7QN":                rheaSynBlob,
+	"/dataset/3LR/taxon/4QHKG/children?limit=100": rheaChildrenBlob,
+	"/dataset/3LR/taxon/4QHKG/synonyms":           rheaSynonymsBlob,
+}
+
+// NewTestServer starts a test server,
+// and sets the client to use it.
+// The returned function closes the server,
+// and restores the client.
+func newTestServer() func() {
+	base, wait, ttl := Client.Base, Client.Wait, web.TTL
+	Client.Wait = 0
+	web.TTL = 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		blob, ok := fixtures[r.URL.RequestURI()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(blob))
+	}))
+	Client.Base = srv.URL + "/"
+	return func() {
+		srv.Close()
+		Client.Base, Client.Wait, web.TTL = base, wait, ttl
+	}
+}
+
+func TestTaxon(t *testing.T) {
+	done := newTestServer()
+	defer done()
+
+	db, err := OpenTax("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sc := db.Taxon("Rhea americana")
+	var ls []biodv.Taxon
+	for sc.Scan() {
+		ls = append(ls, sc.Taxon())
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ls) != 1 {
+		t.Fatalf("found %d taxons, want %d", len(ls), 1)
+	}
+	tax := ls[0]
+	if tax.ID() != "4QHKG" {
+		t.Errorf("taxon ID %q, want %q", tax.ID(), "4QHKG")
+	}
+	if tax.Name() != "Rhea americana" {
+		t.Errorf("taxon name %q, want %q", tax.Name(), "Rhea americana")
+	}
+	if tax.Parent() != "6Q7QM" {
+		t.Errorf("taxon parent %q, want %q", tax.Parent(), "6Q7QM")
+	}
+	if tax.Rank() != biodv.Species {
+		t.Errorf("taxon rank %s, want %s", tax.Rank(), biodv.Species)
+	}
+	if !tax.IsCorrect() {
+		t.Errorf("taxon %s should be correct", tax.Name())
+	}
+	if a := tax.Value(biodv.TaxAuthor); a != "(Linnaeus, 1758)" {
+		t.Errorf("taxon author %q, want %q", a, "(Linnaeus, 1758)")
+	}
+	if s := tax.Value(biodv.TaxSource); s != "1027" {
+		t.Errorf("taxon source %q, want %q", s, "1027")
+	}
+}
+
+func TestTaxID(t *testing.T) {
+	done := newTestServer()
+	defer done()
+
+	db, err := OpenTax(LatestRelease)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tax, err := db.TaxID("4QHKG")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tax.Name() != "Rhea americana" {
+		t.Errorf("taxon name %q, want %q", tax.Name(), "Rhea americana")
+	}
+
+	syn, err := db.TaxID("6Q7QN")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if syn.IsCorrect() {
+		t.Errorf("taxon %s should be a synonym", syn.Name())
+	}
+	if syn.Parent() != "4QHKG" {
+		t.Errorf("synonym parent %q, want %q", syn.Parent(), "4QHKG")
+	}
+
+	if _, err := db.TaxID("XXXX"); err == nil {
+		t.Errorf("taxon ID %q: expecting error", "XXXX")
+	}
+}
+
+func TestChildren(t *testing.T) {
+	done := newTestServer()
+	defer done()
+
+	db, _ := OpenTax("")
+	testData := []struct {
+		id    string
+		names []string
+	}{
+		{"", []string{"Animalia", "Plantae"}},
+		{"4QHKG", []string{"Rhea americana albescens", "Rhea americana intermedia"}},
+	}
+	for _, d := range testData {
+		sc := db.Children(d.id)
+		var names []string
+		for sc.Scan() {
+			names = append(names, sc.Taxon().Name())
+		}
+		if err := sc.Err(); err != nil {
+			t.Errorf("children of %q: unexpected error: %v", d.id, err)
+			continue
+		}
+		if len(names) != len(d.names) {
+			t.Errorf("children of %q: got %v, want %v", d.id, names, d.names)
+			continue
+		}
+		for i, n := range names {
+			if n != d.names[i] {
+				t.Errorf("children of %q: got %v, want %v", d.id, names, d.names)
+				break
+			}
+		}
+	}
+}
+
+func TestSynonyms(t *testing.T) {
+	done := newTestServer()
+	defer done()
+
+	db, _ := OpenTax("")
+	sc := db.Synonyms("4QHKG")
+	var ls []biodv.Taxon
+	for sc.Scan() {
+		ls = append(ls, sc.Taxon())
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	names := []string{"Struthio rhea", "Rhea macrorhyncha"}
+	if len(ls) != len(names) {
+		t.Fatalf("found %d synonyms, want %d", len(ls), len(names))
+	}
+	for i, tax := range ls {
+		if tax.Name() != names[i] {
+			t.Errorf("synonym %d: name %q, want %q", i, tax.Name(), names[i])
+		}
+		if tax.IsCorrect() {
+			t.Errorf("synonym %s: should not be correct", tax.Name())
+		}
+		if tax.Parent() != "4QHKG" {
+			t.Errorf("synonym %s: parent %q, want %q", tax.Name(), tax.Parent(), "4QHKG")
+		}
+	}
+}
+
+var rheaSearchBlob = `
+{"offset":0,"limit":100,"total":2,"last":true,"result":[
+	{"id":"4QHKG","usage":{"id":"4QHKG","name":{"id":"a8f2c","scientificName":"Rhea americana","authorship":"(Linnaeus, 1758)","rank":"species","genus":"Rhea","specificEpithet":"americana","code":"zoological"},"status":"accepted","origin":"source","parentId":"6Q7QM","sourceDatasetKey":1027,"label":"Rhea americana (Linnaeus, 1758)"}},
+	{"id":"7BLR2","usage":{"id":"7BLR2","name":{"id":"c71a0","scientificName":"Rhea americana albescens","authorship":"Arribálzaga & Holmberg, 1878","rank":"subspecies","genus":"Rhea","specificEpithet":"americana","infraspecificEpithet":"albescens","code":"zoological"},"status":"accepted","origin":"source","parentId":"4QHKG","sourceDatasetKey":1027,"label":"Rhea americana albescens Arribálzaga & Holmberg, 1878"}}
+]}
+`
+
+var kingdomBlob = `
+{"offset":0,"limit":100,"total":2,"last":true,"result":[
+	{"id":"N","usage":{"id":"N","name":{"id":"1","scientificName":"Animalia","rank":"kingdom"},"status":"accepted","origin":"source","parentId":"5T6MX"}},
+	{"id":"P","usage":{"id":"P","name":{"id":"2","scientificName":"Plantae","rank":"kingdom"},"status":"accepted","origin":"source","parentId":"5T6MX"}}
+]}
+`
+
+var rheaBlob = `
+{"id":"4QHKG","name":{"id":"a8f2c","scientificName":"Rhea americana","authorship":"(Linnaeus, 1758)","rank":"species","genus":"Rhea","specificEpithet":"americana","code":"zoological"},"status":"accepted","origin":"source","parentId":"6Q7QM","sourceDatasetKey":1027,"label":"Rhea americana (Linnaeus, 1758)"}
+`
+
+var rheaSynBlob = `
+{"id":"6Q7QN","name":{"id":"f3b18","scientificName":"Struthio rhea","authorship":"Linnaeus, 1758","rank":"species","genus":"Struthio","specificEpithet":"rhea","code":"zoological"},"status":"synonym","origin":"source","parentId":"4QHKG","accepted":{"id":"4QHKG","name":{"id":"a8f2c","scientificName":"Rhea americana","authorship":"(Linnaeus, 1758)","rank":"species"},"status":"accepted","parentId":"6Q7QM"},"sourceDatasetKey":1027,"label":"Struthio rhea Linnaeus, 1758"}
+`
+
+var rheaChildrenBlob = `
+{"offset":0,"limit":100,"total":2,"last":true,"result":[
+	{"id":"7BLR2","name":{"id":"c71a0","scientificName":"Rhea americana albescens","authorship":"Arribálzaga & Holmberg, 1878","rank":"subspecies"},"status":"accepted","parentId":"4QHKG","sourceDatasetKey":1027},
+	{"id":"7BLR3","name":{"id":"c71a1","scientificName":"Rhea americana intermedia","authorship":"Rothschild & Chubb, 1914","rank":"subspecies"},"status":"accepted","parentId":"4QHKG","sourceDatasetKey":1027}
+]}
+`
+
+var rheaSynonymsBlob = `
+{"homotypic":[
+	{"id":"6Q7QN","name":{"id":"f3b18","scientificName":"Struthio rhea","authorship":"Linnaeus, 1758","rank":"species"},"status":"synonym","parentId":"4QHKG","sourceDatasetKey":1027}
+],"heterotypic":[
+	{"id":"6Q7QP","name":{"id":"f3b19","scientificName":"Rhea macrorhyncha","authorship":"Sclater, 1860","rank":"species"},"status":"synonym","parentId":"4QHKG","sourceDatasetKey":1027}
+],"misapplied":[]}
+`
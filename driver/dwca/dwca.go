@@ -0,0 +1,283 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package dwca implements a taxonomy driver
+// that reads a local Darwin Core Archive checklist
+// (for example,
+// the GBIF backbone taxonomy,
+// or an export from ChecklistBank).
+//
+// The archive must have a taxon core,
+// and it is read completely into memory
+// when the taxonomy is open.
+// The following Darwin Core terms are used:
+//
+//	taxonID (or the core ID)     the ID of the taxon.
+//	scientificName               the name of the taxon
+//	                             (canonicalName is used if present).
+//	scientificNameAuthorship     the author of the taxon.
+//	taxonRank                    the rank of the taxon.
+//	parentNameUsageID            the parent of the taxon.
+//	acceptedNameUsageID          the accepted taxon of a synonym.
+//	taxonomicStatus              the status of the taxon.
+//	namePublishedIn              the reference of the taxon.
+package dwca
+
+import (
+	"strings"
+
+	"github.com/js-arias/biodv"
+	"github.com/js-arias/biodv/encoding/dwca"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	biodv.RegisterTax("dwca", biodv.TaxDriver{OpenTax, nil, aboutTaxDwCA})
+}
+
+// AboutTaxDwCA returns a simple statement of the purpose of the driver.
+func aboutTaxDwCA() string {
+	return "a driver for a local Darwin Core Archive checklist"
+}
+
+// OpenTax returns the taxonomy handler
+// of a Darwin Core Archive checklist,
+// that implements the biodv.Taxonomy interface.
+//
+// The param is the path of the archive,
+// either a zip file,
+// or a directory with the unzipped archive.
+func OpenTax(param string) (biodv.Taxonomy, error) {
+	param = strings.TrimSpace(param)
+	if param == "" {
+		return nil, errors.New("dwca: taxonomy: undefined archive")
+	}
+	a, err := dwca.Open(param)
+	if err != nil {
+		return nil, errors.Wrap(err, "dwca: taxonomy")
+	}
+	defer a.Close()
+
+	if n := a.Core.Name(); n != "" && !strings.EqualFold(n, "Taxon") {
+		return nil, errors.Errorf("dwca: taxonomy: %s: core is %q, want 'Taxon'", param, n)
+	}
+	db := &taxDB{
+		ids:      make(map[string]*taxon),
+		names:    make(map[string][]*taxon),
+		children: make(map[string][]*taxon),
+		synonyms: make(map[string][]*taxon),
+	}
+	if err := db.read(a); err != nil {
+		return nil, errors.Wrapf(err, "dwca: taxonomy: %s", param)
+	}
+	return db, nil
+}
+
+// TaxDB is the handler
+// of a Darwin Core Archive checklist.
+type taxDB struct {
+	ids      map[string]*taxon
+	names    map[string][]*taxon
+	roots    []*taxon
+	children map[string][]*taxon
+	synonyms map[string][]*taxon
+}
+
+// Taxon is a taxon read from the archive.
+// It implements the biodv.Taxon interface.
+type taxon struct {
+	id      string
+	name    string
+	author  string
+	ref     string
+	rank    biodv.Rank
+	parent  string
+	correct bool
+}
+
+func (tx *taxon) Name() string {
+	return tx.name
+}
+
+func (tx *taxon) ID() string {
+	return tx.id
+}
+
+func (tx *taxon) Parent() string {
+	return tx.parent
+}
+
+func (tx *taxon) Rank() biodv.Rank {
+	return tx.rank
+}
+
+func (tx *taxon) IsCorrect() bool {
+	return tx.correct
+}
+
+func (tx *taxon) Keys() []string {
+	return []string{
+		biodv.TaxAuthor,
+		biodv.TaxRef,
+	}
+}
+
+func (tx *taxon) Value(key string) string {
+	switch strings.ToLower(strings.TrimSpace(key)) {
+	case biodv.TaxAuthor:
+		return tx.author
+	case biodv.TaxRef:
+		return tx.ref
+	}
+	return ""
+}
+
+// Read reads the taxons of the archive.
+func (db *taxDB) read(a *dwca.Archive) error {
+	sc := a.Scanner(a.Core)
+	var ls []*taxon
+	for sc.Scan() {
+		tx := newTaxon(sc.Record())
+		if tx == nil {
+			continue
+		}
+		if _, dup := db.ids[tx.id]; dup {
+			continue
+		}
+		db.ids[tx.id] = tx
+		ls = append(ls, tx)
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+
+	for _, tx := range ls {
+		nm := strings.ToLower(tx.name)
+		db.names[nm] = append(db.names[nm], tx)
+
+		if !tx.correct {
+			db.synonyms[tx.parent] = append(db.synonyms[tx.parent], tx)
+			continue
+		}
+		if _, ok := db.ids[tx.parent]; !ok {
+			tx.parent = ""
+		}
+		if tx.parent == "" {
+			db.roots = append(db.roots, tx)
+			continue
+		}
+		db.children[tx.parent] = append(db.children[tx.parent], tx)
+	}
+	return nil
+}
+
+// NewTaxon returns a taxon
+// from a row of the archive.
+// It returns nil if the row is not a valid taxon.
+func newTaxon(row map[string]string) *taxon {
+	id := row["taxonID"]
+	if id == "" {
+		id = row[dwca.IDKey]
+	}
+	sci := row["scientificName"]
+	if id == "" || sci == "" {
+		return nil
+	}
+
+	author := row["scientificNameAuthorship"]
+	name := row["canonicalName"]
+	if name == "" {
+		if author != "" {
+			sci = strings.TrimSpace(strings.TrimSuffix(sci, author))
+		}
+		n := biodv.ParseName(sci)
+		name = n.Canon()
+		if author == "" {
+			author = n.Authorship()
+		}
+	}
+	name = biodv.TaxCanon(name)
+	if name == "" {
+		return nil
+	}
+
+	tx := &taxon{
+		id:      id,
+		name:    name,
+		author:  author,
+		ref:     row["namePublishedIn"],
+		rank:    biodv.GetRank(row["taxonRank"]),
+		parent:  row["parentNameUsageID"],
+		correct: true,
+	}
+	if acc := row["acceptedNameUsageID"]; acc != "" && acc != id {
+		tx.parent = acc
+		tx.correct = false
+	} else if isSynonym(row["taxonomicStatus"]) {
+		tx.correct = false
+	}
+	return tx
+}
+
+// IsSynonym returns true
+// if a taxonomic status
+// is from a synonym.
+func isSynonym(status string) bool {
+	status = strings.ToLower(status)
+	return strings.Contains(status, "synonym") || strings.Contains(status, "misapplied")
+}
+
+func (db *taxDB) Taxon(name string) *biodv.TaxScan {
+	name = strings.ToLower(strings.Join(strings.Fields(name), " "))
+	if name == "" {
+		sc := biodv.NewTaxScan(1)
+		sc.Add(nil, errors.Errorf("dwca: taxonomy: empty taxon name"))
+		return sc
+	}
+	return listScan(db.names[name])
+}
+
+func (db *taxDB) Children(id string) *biodv.TaxScan {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return listScan(db.roots)
+	}
+	return listScan(db.children[id])
+}
+
+func (db *taxDB) Synonyms(id string) *biodv.TaxScan {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		sc := biodv.NewTaxScan(1)
+		sc.Add(nil, errors.Errorf("dwca: taxonomy: invalid ID for synonyms"))
+		return sc
+	}
+	return listScan(db.synonyms[id])
+}
+
+func (db *taxDB) TaxID(id string) (biodv.Taxon, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return nil, errors.Errorf("dwca: taxonomy: empty taxon ID")
+	}
+	tx, ok := db.ids[id]
+	if !ok {
+		return nil, errors.Errorf("dwca: taxonomy: taxon %q not found", id)
+	}
+	return tx, nil
+}
+
+// ListScan returns a scanner
+// with a list of taxons.
+func listScan(ls []*taxon) *biodv.TaxScan {
+	sc := biodv.NewTaxScan(len(ls) + 1)
+	for _, tx := range ls {
+		sc.Add(tx, nil)
+	}
+	sc.Add(nil, nil)
+	return sc
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package dwca
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/js-arias/biodv"
+)
+
+var metaBlob = `<?xml version="1.0" encoding="UTF-8"?>
+<archive xmlns="http://rs.tdwg.org/dwc/text/">
+  <core encoding="UTF-8" fieldsTerminatedBy="\t" linesTerminatedBy="\n" fieldsEnclosedBy="" ignoreHeaderLines="1" rowType="http://rs.tdwg.org/dwc/terms/Taxon">
+    <files>
+      <location>Taxon.tsv</location>
+    </files>
+    <id index="0" />
+    <field index="0" term="http://rs.tdwg.org/dwc/terms/taxonID"/>
+    <field index="1" term="http://rs.tdwg.org/dwc/terms/parentNameUsageID"/>
+    <field index="2" term="http://rs.tdwg.org/dwc/terms/acceptedNameUsageID"/>
+    <field index="3" term="http://rs.tdwg.org/dwc/terms/scientificName"/>
+    <field index="4" term="http://rs.tdwg.org/dwc/terms/scientificNameAuthorship"/>
+    <field index="5" term="http://rs.tdwg.org/dwc/terms/taxonRank"/>
+    <field index="6" term="http://rs.tdwg.org/dwc/terms/taxonomicStatus"/>
+  </core>
+</archive>
+`
+
+var taxonBlob = "taxonID\tparentNameUsageID\tacceptedNameUsageID\tscientificName\tscientificNameAuthorship\ttaxonRank\ttaxonomicStatus\n" +
+	"1\t\t\tAnimalia\t\tkingdom\taccepted\n" +
+	"2\t1\t\tRheidae Bonaparte, 1849\tBonaparte, 1849\tfamily\taccepted\n" +
+	"3\t2\t\tRhea Brisson, 1760\tBrisson, 1760\tgenus\taccepted\n" +
+	"4\t3\t\tRhea americana (Linnaeus, 1758)\t(Linnaeus, 1758)\tspecies\taccepted\n" +
+	"5\t3\t\tRhea pennata d'Orbigny, 1834\td'Orbigny, 1834\tspecies\taccepted\n" +
+	"6\t\t5\tPterocnemia pennata (d'Orbigny, 1834)\t(d'Orbigny, 1834)\tspecies\tsynonym\n" +
+	"7\t\t4\tStruthio rhea Linnaeus, 1758\tLinnaeus, 1758\tspecies\thomotypic synonym\n" +
+	"8\t4\t\tRhea americana albescens Arribálzaga & Holmberg, 1878\tArribálzaga & Holmberg, 1878\tsubspecies\taccepted\n"
+
+func newArchive(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "biodv-dwca")
+	if err != nil {
+		t.Fatalf("unable to create temporal dir: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "meta.xml"), []byte(metaBlob), 0644); err != nil {
+		t.Fatalf("unable to write meta.xml: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "Taxon.tsv"), []byte(taxonBlob), 0644); err != nil {
+		t.Fatalf("unable to write Taxon.tsv: %v", err)
+	}
+	return dir
+}
+
+func TestOpenTax(t *testing.T) {
+	dir := newArchive(t)
+	defer os.RemoveAll(dir)
+
+	db, err := OpenTax(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testData := []struct {
+		id      string
+		name    string
+		author  string
+		rank    biodv.Rank
+		parent  string
+		correct bool
+	}{
+		{"1", "Animalia", "", biodv.Kingdom, "", true},
+		{"2", "Rheidae", "Bonaparte, 1849", biodv.Family, "1", true},
+		{"4", "Rhea americana", "(Linnaeus, 1758)", biodv.Species, "3", true},
+		{"6", "Pterocnemia pennata", "(d'Orbigny, 1834)", biodv.Species, "5", false},
+		{"7", "Struthio rhea", "Linnaeus, 1758", biodv.Species, "4", false},
+		{"8", "Rhea americana albescens", "Arribálzaga & Holmberg, 1878", biodv.Subspecies, "4", true},
+	}
+	for _, d := range testData {
+		tax, err := db.TaxID(d.id)
+		if err != nil {
+			t.Errorf("taxon %s: unexpected error: %v", d.id, err)
+			continue
+		}
+		if tax.Name() != d.name {
+			t.Errorf("taxon %s: name %q, want %q", d.id, tax.Name(), d.name)
+		}
+		if a := tax.Value(biodv.TaxAuthor); a != d.author {
+			t.Errorf("taxon %s: author %q, want %q", d.id, a, d.author)
+		}
+		if tax.Rank() != d.rank {
+			t.Errorf("taxon %s: rank %s, want %s", d.id, tax.Rank(), d.rank)
+		}
+		if tax.Parent() != d.parent {
+			t.Errorf("taxon %s: parent %q, want %q", d.id, tax.Parent(), d.parent)
+		}
+		if tax.IsCorrect() != d.correct {
+			t.Errorf("taxon %s: correct %v, want %v", d.id, tax.IsCorrect(), d.correct)
+		}
+	}
+	if _, err := db.TaxID("100"); err == nil {
+		t.Errorf("taxon %q: expecting error", "100")
+	}
+
+	if _, err := OpenTax(""); err == nil {
+		t.Errorf("open without archive: expecting error")
+	}
+}
+
+func TestScans(t *testing.T) {
+	dir := newArchive(t)
+	defer os.RemoveAll(dir)
+
+	db, err := OpenTax(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testData := []struct {
+		what string
+		sc   *biodv.TaxScan
+		ids  []string
+	}{
+		{"taxon Rhea", db.Taxon("rhea"), []string{"3"}},
+		{"taxon Rhea americana", db.Taxon("Rhea  americana"), []string{"4"}},
+		{"taxon Rhea nana", db.Taxon("Rhea nana"), nil},
+		{"root children", db.Children(""), []string{"1"}},
+		{"children of Rhea", db.Children("3"), []string{"4", "5"}},
+		{"synonyms of Rhea americana", db.Synonyms("4"), []string{"7"}},
+		{"synonyms of Rhea pennata", db.Synonyms("5"), []string{"6"}},
+	}
+	for _, d := range testData {
+		var ids []string
+		for d.sc.Scan() {
+			ids = append(ids, d.sc.Taxon().ID())
+		}
+		if err := d.sc.Err(); err != nil {
+			t.Errorf("%s: unexpected error: %v", d.what, err)
+			continue
+		}
+		if len(ids) != len(d.ids) {
+			t.Errorf("%s: got %v, want %v", d.what, ids, d.ids)
+			continue
+		}
+		for i, id := range ids {
+			if id != d.ids[i] {
+				t.Errorf("%s: got %v, want %v", d.what, ids, d.ids)
+				break
+			}
+		}
+	}
+}
@@ -7,7 +7,6 @@
 package gbif
 
 import (
-	"encoding/json"
 	"strings"
 
 	"github.com/js-arias/biodv"
@@ -37,9 +36,6 @@ func aboutSetGBIF() string {
 // dataset handler,
 // that implements the biodv.SetDB interface.
 func OpenSet(param string) (biodv.SetDB, error) {
-	if reqChan == nil {
-		initReqs()
-	}
 	return setDB{}, nil
 }
 
@@ -140,24 +136,9 @@ func (db setDB) SetID(id string) (biodv.Dataset, error) {
 	if id == "" {
 		return nil, errors.Errorf("gbif: setDB: empty dataset DB")
 	}
-	var err error
-	for r := 0; r < Retry; r++ {
-		req := newRequest("dataset/" + id)
-		select {
-		case err = <-req.err:
-			continue
-		case a := <-req.ans:
-			d := json.NewDecoder(&a)
-			ds := &dataset{}
-			err = d.Decode(ds)
-			if err != nil {
-				continue
-			}
-			return ds, nil
-		}
-	}
-	if err == nil {
-		return nil, errors.Errorf("gbif: setDB: no answer after %d retries", Retry)
+	ds := &dataset{}
+	if err := Client.GetJSON("dataset/"+id, ds); err != nil {
+		return nil, errors.Wrap(err, "gbif: setDB")
 	}
-	return nil, errors.Wrap(err, "gbif: setDB")
+	return ds, nil
 }
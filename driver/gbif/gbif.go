@@ -6,80 +6,101 @@
 
 // Package gbif implements an interface
 // to GBIF webservice.
+//
+// Several requests to GBIF can be in flight
+// at the same time,
+// so the pages of a large list of records
+// are retrieved in parallel.
+// By default,
+// at most two requests are made in parallel,
+// and a new request is started
+// at most every 300 milliseconds
+// (i.e. about three requests per second).
+// GBIF does not publish a fixed rate limit,
+// instead,
+// it answers with a 429 status
+// (too many requests)
+// when a client makes too many requests,
+// and those requests are retried
+// waiting longer each time.
+// The number of parallel requests,
+// and the rate limit,
+// can be changed with the fields of Client
+// (Workers, Burst, and Wait),
+// before any request is made.
 package gbif
 
 import (
-	"bytes"
-	"net/http"
+	"net/url"
+	"strconv"
 	"time"
-)
 
-// Retry is the number of times a request will be retried
-// before aborted.
-var Retry = 5
-
-// Timeout is the timeout of the http request.
-var Timeout = 20 * time.Second
+	"github.com/js-arias/biodv/driver/web"
+)
 
-// Wait is the waiting time for a new request
-// (we don't want to overload the GBIF server!).
-var Wait = time.Millisecond * 300
+// Client is the client of the GBIF webservice.
+var Client = web.New("gbif", "http://api.gbif.org/v1/")
 
-// Buffer is the maximum number of requests in the request queue.
-var Buffer = 100
+func init() {
+	// rate limits
+	// (see the package documentation)
+	Client.Workers = 2
+	Client.Burst = 2
+	Client.Wait = 300 * time.Millisecond
+}
 
-const wsHead = "http://api.gbif.org/v1/"
+// MaxOffset is the maximum number of records
+// (offset + limit)
+// that can be retrieved
+// from a GBIF occurrence search.
+const maxOffset = 100000
 
-// Request contains an gbif request,
-// and a channel with the answers.
-type request struct {
+// Page is the answer
+// of a page of a list request.
+type page struct {
 	req string
-	ans chan bytes.Buffer
-	err chan error
+	b   []byte
+	err error
 }
 
-// NewRequest sends a request to the request channel.
-func newRequest(req string) request {
-	r := request{
-		req: wsHead + req,
-		ans: make(chan bytes.Buffer),
-		err: make(chan error),
+// Pages makes, in parallel,
+// the requests for the pages of a list,
+// from the given offset,
+// up to count elements
+// (the last page is shortened,
+// so it does not go beyond count).
+// The answers are returned in order.
+// Closing done
+// stops the requests.
+func pages(reqstr string, param url.Values, off, limit, count int64, done <-chan struct{}) <-chan chan page {
+	w := Client.Workers
+	if w < 1 {
+		w = 1
 	}
-	reqChan.cReqs <- r
-	return r
-}
-
-// ReqChanType keeps the requests channel.
-type reqChanType struct {
-	cReqs chan request
-}
-
-// ReqChan is the requests channel.
-// It should be initialized before
-// using the database.
-var reqChan *reqChanType
-
-// InitReqs initialize the request channel.
-func initReqs() {
-	http.DefaultClient.Timeout = Timeout
-	reqChan = &reqChanType{cReqs: make(chan request, Buffer)}
-	go reqChan.reqs()
-}
-
-// Reqs make the network request.
-func (rc *reqChanType) reqs() {
-	for r := range rc.cReqs {
-		a, err := http.Get(r.req)
-		if err != nil {
-			r.err <- err
-			continue
+	out := make(chan chan page, w)
+	go func() {
+		defer close(out)
+		for ; off < count; off += limit {
+			// each page uses its own parameters
+			p := make(url.Values, len(param)+2)
+			for k, v := range param {
+				p[k] = v
+			}
+			p.Set("offset", strconv.FormatInt(off, 10))
+			if off+limit > count {
+				p.Set("limit", strconv.FormatInt(count-off, 10))
+			}
+			a := make(chan page, 1)
+			select {
+			case out <- a:
+			case <-done:
+				return
+			}
+			go func(req string) {
+				b, err := Client.GetList(req)
+				a <- page{req, b, err}
+			}(reqstr + p.Encode())
 		}
-		var b bytes.Buffer
-		b.ReadFrom(a.Body)
-		a.Body.Close()
-		r.ans <- b
-
-		// we do not want to overload the gbif server.
-		time.Sleep(Wait)
-	}
+	}()
+	return out
 }
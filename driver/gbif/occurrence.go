@@ -77,9 +77,6 @@ const RecOrganism = "organism:"
 // By default,
 // it will search only preserved specimens.
 func OpenRec(param string) (biodv.RecDB, error) {
-	if reqChan == nil {
-		initReqs()
-	}
 	db := recDB{param: url.Values{}}
 	if i := strings.Index(param, ":"); i > 1 {
 		if strings.HasPrefix(param, RecSetDataset) {
@@ -118,6 +115,7 @@ type recDB struct {
 // OccAnswer is the answer of the occurrence request.
 type occAnswer struct {
 	Offset, Limit int64
+	Count         int64
 	EndOfRecords  bool
 	Results       []*occurrence
 }
@@ -269,44 +267,16 @@ func (occ *occurrence) Value(key string) string {
 }
 
 func (occ *occurrence) catalog() string {
-	if occ.CatalogNumber == "" {
-		return ""
-	}
 	if occ.CatalogNumber == "NO DISPONIBLE" {
 		return ""
 	}
 	if occ.InstitutionCode == "" {
 		return occ.CatalogNumber
 	}
-	cat := occ.CatalogNumber
-	coll := occ.CollectionCode
 	if x, ok := musAcronyms[occ.InstitutionCode]; ok {
 		occ.InstitutionCode = x
 	}
-	inst := occ.InstitutionCode
-
-	if inst == coll {
-		coll = ""
-	}
-	if strings.HasPrefix(cat, occ.CollectionCode) {
-		coll = ""
-	}
-	if strings.HasPrefix(cat, occ.InstitutionCode) {
-		inst = ""
-		coll = ""
-	}
-	if coll != "" && strings.HasPrefix(occ.CollectionCode, occ.InstitutionCode) {
-		inst = ""
-	}
-
-	v := cat
-	if coll != "" {
-		v = coll + ":" + cat
-	}
-	if inst != "" {
-		v = inst + ":" + v
-	}
-	return v
+	return biodv.JoinCatalog(occ.InstitutionCode, occ.CollectionCode, occ.CatalogNumber)
 }
 
 // IsZero returns true if the "zero coordinate" issue
@@ -350,51 +320,71 @@ func (db recDB) TaxRecs(id string) *biodv.RecScan {
 
 // RecordList returns an specific list of records
 // with a given set of parameters.
+//
+// After the first page,
+// the number of records is known,
+// so the other pages are requested in parallel.
+// As GBIF only returns the first records
+// of a search
+// (see maxOffset),
+// if there are more records,
+// the list ends with an error
+// that indicates that the results were truncated.
 func (db recDB) recordList(sc *biodv.RecScan, reqstr string, param url.Values) {
-	var err error
+	req := reqstr + param.Encode()
+	b, err := Client.GetList(req)
+	if err != nil {
+		sc.Add(nil, errors.Wrap(err, "gbif: recDB"))
+		return
+	}
+	resp, err := decodeRecordList(bytes.NewBuffer(b))
+	if err != nil {
+		Client.Remove(req)
+		sc.Add(nil, errors.Wrap(err, "gbif: recDB"))
+		return
+	}
+	for _, occ := range resp.Results {
+		if !sc.Add(occ, nil) {
+			return
+		}
+	}
+	if resp.EndOfRecords || resp.Limit <= 0 {
+		sc.Add(nil, nil)
+		return
+	}
 
-	end := false
-	for off := int64(0); !end; {
-		if off > 0 {
-			param.Set("offset", strconv.FormatInt(off, 10))
+	count := resp.Count
+	if count > maxOffset {
+		count = maxOffset
+	}
+	done := make(chan struct{})
+	defer close(done)
+	for a := range pages(reqstr, param, resp.Limit, resp.Limit, count, done) {
+		p := <-a
+		if p.err != nil {
+			sc.Add(nil, errors.Wrap(p.err, "gbif: recDB"))
+			return
 		}
-		retryErr := true
-		for r := 0; r < Retry; r++ {
-			req := newRequest(reqstr + param.Encode())
-			select {
-			case err = <-req.err:
-				continue
-			case a := <-req.ans:
-				var resp *occAnswer
-				resp, err = decodeRecordList(&a)
-				if err != nil {
-					continue
-				}
-
-				for _, occ := range resp.Results {
-					if !sc.Add(occ, nil) {
-						return
-					}
-				}
-
-				// end retry loop
-				r = Retry
-				retryErr = false
-				if resp.EndOfRecords {
-					end = true
-				}
-				off += resp.Limit
-			}
+		resp, err := decodeRecordList(bytes.NewBuffer(p.b))
+		if err != nil {
+			Client.Remove(p.req)
+			sc.Add(nil, errors.Wrap(err, "gbif: recDB"))
+			return
 		}
-
-		if retryErr {
-			if err == nil {
-				sc.Add(nil, errors.Errorf("gbif: recDB: no answer after %d retries", Retry))
+		for _, occ := range resp.Results {
+			if !sc.Add(occ, nil) {
+				return
 			}
-			sc.Add(nil, errors.Wrap(err, "gbif: recDB"))
+		}
+		if resp.EndOfRecords {
+			sc.Add(nil, nil)
 			return
 		}
 	}
+	if resp.Count > count {
+		sc.Add(nil, errors.Errorf("gbif: recDB: results truncated: only %d of %d records can be retrieved from GBIF", count, resp.Count))
+		return
+	}
 	sc.Add(nil, nil)
 }
 
@@ -410,24 +400,9 @@ func (db recDB) RecID(id string) (biodv.Record, error) {
 	if id == "" {
 		return nil, errors.Errorf("gbif: recDB: empty record ID")
 	}
-	var err error
-	for r := 0; r < Retry; r++ {
-		req := newRequest("occurrence/" + id)
-		select {
-		case err = <-req.err:
-			continue
-		case a := <-req.ans:
-			d := json.NewDecoder(&a)
-			occ := &occurrence{}
-			err = d.Decode(occ)
-			if err != nil {
-				continue
-			}
-			return occ, nil
-		}
-	}
-	if err == nil {
-		return nil, errors.Errorf("gbif: recDB: no answer after %d retries", Retry)
+	occ := &occurrence{}
+	if err := Client.GetJSON("occurrence/"+id, occ); err != nil {
+		return nil, errors.Wrap(err, "gbif: recDB")
 	}
-	return nil, errors.Wrap(err, "gbif: recDB")
+	return occ, nil
 }
@@ -8,9 +8,16 @@ package gbif
 
 import (
 	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/js-arias/biodv"
+	"github.com/js-arias/biodv/driver/web"
 )
 
 func TestDecodeRecordList(t *testing.T) {
@@ -30,6 +37,112 @@ func TestDecodeRecordList(t *testing.T) {
 	}
 }
 
+func TestRecordList(t *testing.T) {
+	const count, limit = 1000, 30
+
+	var mu sync.Mutex
+	inFlight, maxFlight := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxFlight {
+			maxFlight = inFlight
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+
+		off, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		var ls []string
+		for i := off; i < off+limit && i < count; i++ {
+			ls = append(ls, fmt.Sprintf(`{"key":%d,"taxonKey":2481174}`, i+1))
+		}
+		fmt.Fprintf(w, `{"offset":%d,"limit":%d,"endOfRecords":%v,"count":%d,"results":[%s]}`, off, limit, off+limit >= count, count, strings.Join(ls, ","))
+	}))
+	defer srv.Close()
+
+	base, wait, ttl := Client.Base, Client.Wait, web.TTL
+	Client.Base = srv.URL + "/"
+	Client.Wait = 0
+	web.TTL = 0
+	defer func() {
+		Client.Base = base
+		Client.Wait = wait
+		web.TTL = ttl
+	}()
+
+	db, _ := OpenRec(RecUseAll)
+	sc := db.TaxRecs("2481174")
+	i := 0
+	for sc.Scan() {
+		i++
+		if id := sc.Record().ID(); id != strconv.Itoa(i) {
+			t.Fatalf("record %d: ID %s, want %d", i, id, i)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if i != count {
+		t.Errorf("found %d records, want %d", i, count)
+	}
+	if maxFlight > Client.Workers {
+		t.Errorf("%d requests in flight, want at most %d", maxFlight, Client.Workers)
+	}
+}
+
+func TestRecordListTruncated(t *testing.T) {
+	const count = maxOffset + 500
+
+	var mu sync.Mutex
+	last := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		off, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		mu.Lock()
+		if off+limit > last {
+			last = off + limit
+		}
+		mu.Unlock()
+
+		var ls []string
+		for i := off; i < off+limit && i < count; i++ {
+			ls = append(ls, fmt.Sprintf(`{"key":%d,"taxonKey":2481174}`, i+1))
+		}
+		fmt.Fprintf(w, `{"offset":%d,"limit":%d,"endOfRecords":%v,"count":%d,"results":[%s]}`, off, limit, off+limit >= count, count, strings.Join(ls, ","))
+	}))
+	defer srv.Close()
+
+	base, wait, ttl := Client.Base, Client.Wait, web.TTL
+	Client.Base = srv.URL + "/"
+	Client.Wait = 0
+	web.TTL = 0
+	defer func() {
+		Client.Base = base
+		Client.Wait = wait
+		web.TTL = ttl
+	}()
+
+	db, _ := OpenRec(RecUseAll)
+	sc := db.TaxRecs("2481174")
+	i := 0
+	for sc.Scan() {
+		i++
+	}
+	if i != maxOffset {
+		t.Errorf("found %d records, want %d", i, maxOffset)
+	}
+	if last > maxOffset {
+		t.Errorf("last record requested %d, want at most %d", last, maxOffset)
+	}
+	if err := sc.Err(); err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Errorf("error %v, want a truncated results error", err)
+	}
+}
+
 var larusBlob = `
 {"offset":0,"limit":300,"endOfRecords":true,"count":8474604,"results":[
 	{"key":1494057472,"datasetKey":"83e20573-f7dd-4852-9159-21566e1e691e","publishingOrgKey":"1cd669d0-80ea-11de-a9d0-f1765f95f18b","networkKeys":[],"installationKey":"9f25fd85-85dc-4dcd-a1b4-b31165442e2b","publishingCountry":"BE","protocol":"DWC_ARCHIVE","lastCrawled":"2017-10-08T08:55:41.659+0000","lastParsed":"2017-04-27T20:20:28.400+0000","crawlId":49,"extensions":{},"basisOfRecord":"MACHINE_OBSERVATION","sex":"FEMALE","lifeStage":"ADULT","taxonKey":2481139,"kingdomKey":1,"phylumKey":44,"classKey":212,"orderKey":7192402,"familyKey":9316,"genusKey":2481126,"speciesKey":2481139,"scientificName":"Larus argentatus Pontoppidan, 1763","kingdom":"Animalia","phylum":"Chordata","order":"Charadriiformes","family":"Laridae","genus":"Larus","species":"Larus argentatus","genericName":"Larus","specificEpithet":"argentatus","taxonRank":"SPECIES","decimalLongitude":1.596802,"decimalLatitude":50.223982,"coordinateUncertaintyInMeters":7.0,"elevation":0.0,"year":2016,"month":1,"day":1,"eventDate":"2016-01-01T11:50:15.000+0000","issues":["COORDINATE_ROUNDED","COUNTRY_DERIVED_FROM_COORDINATES"],"lastInterpreted":"2018-07-08T00:45:25.746+0000","license":"http://creativecommons.org/publicdomain/zero/1.0/legalcode","identifiers":[],"facts":[],"relations":[],"geodeticDatum":"WGS84","class":"Aves","countryCode":"FR","country":"France","rightsHolder":"INBO","minimumDistanceAboveSurfaceInMeters":"2","identifier":"6036:20160101115015","informationWithheld":"see metadata","dynamicProperties":"{\"device_info_serial\":6036, \"catch_location\":\"Vismijn, Oostende\", \"tracking_started_at\":\"2014-06-13\"}","samplingEffort":"{\"seconds_since_last_occurrence\":176}","georeferenceVerificationStatus":"unverified","datasetName":"Bird tracking - GPS tracking of Lesser Black-backed Gulls and Herring Gulls breeding at the southern North Sea coast","language":"en","gbifID":"1494057472","occurrenceID":"6036:20160101115015","type":"Event","vernacularName":"Herring Gull","organismID":"H903607","institutionCode":"INBO","organismName":"Stefanie","ownerInstitutionCode":"INBO/VLIZ/UGent/UA","samplingProtocol":"https://doi.org/10.1007/s10336-012-0908-1","datasetID":"https://doi.org/10.15468/02omly","accessRights":"http://www.inbo.be/en/norms-for-data-use","georeferenceSources":"GPS"},
@@ -12,6 +12,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/js-arias/biodv"
 
@@ -45,9 +46,6 @@ const TaxNoNub0 = "no-nub-0"
 // If the param is equal to TaxNoNub0
 // it will skip taxons with a nubKey = 0.
 func OpenTax(param string) (biodv.Taxonomy, error) {
-	if reqChan == nil {
-		initReqs()
-	}
 	db := taxDB{useNub0: true}
 	if param == TaxNoNub0 {
 		db.useNub0 = false
@@ -128,7 +126,38 @@ func (sp *species) Parent() string {
 }
 
 func (sp *species) Rank() biodv.Rank {
-	return biodv.GetRank(sp.RankStr)
+	return gbifRanks[sp.RankStr]
+}
+
+// GbifRanks maps the GBIF rank values
+// to biodv ranks.
+// Any other GBIF rank
+// (e.g. 'SECTION', or 'INFRAFAMILY')
+// is taken as unranked.
+var gbifRanks = map[string]biodv.Rank{
+	"KINGDOM":     biodv.Kingdom,
+	"SUBKINGDOM":  biodv.Subkingdom,
+	"PHYLUM":      biodv.Phylum,
+	"SUBPHYLUM":   biodv.Subphylum,
+	"SUPERCLASS":  biodv.Superclass,
+	"CLASS":       biodv.Class,
+	"SUBCLASS":    biodv.Subclass,
+	"INFRACLASS":  biodv.Infraclass,
+	"SUPERORDER":  biodv.Superorder,
+	"ORDER":       biodv.Order,
+	"SUBORDER":    biodv.Suborder,
+	"INFRAORDER":  biodv.Infraorder,
+	"SUPERFAMILY": biodv.Superfamily,
+	"FAMILY":      biodv.Family,
+	"SUBFAMILY":   biodv.Subfamily,
+	"TRIBE":       biodv.Tribe,
+	"SUBTRIBE":    biodv.Subtribe,
+	"GENUS":       biodv.Genus,
+	"SUBGENUS":    biodv.Subgenus,
+	"SPECIES":     biodv.Species,
+	"SUBSPECIES":  biodv.Subspecies,
+	"VARIETY":     biodv.Variety,
+	"FORM":        biodv.Form,
 }
 
 func (sp *species) IsCorrect() bool {
@@ -212,82 +241,77 @@ func (db taxDB) Synonyms(id string) *biodv.TaxScan {
 // TaxonList returns an specific list of taxons
 // with a given set of parameters.
 func (db taxDB) taxonList(sc *biodv.TaxScan, reqstr string, param url.Values) {
-	var err error
-	// nubs store all the nubs found
+	// nubs store all the nubs found,
+	// and missing the nubs not found,
+	// in the order they are found.
 	nubs := make(map[int64]bool)
+	var missing []int64
 
 	end := false
 	for off := int64(0); !end; {
 		if off > 0 {
 			param.Set("offset", strconv.FormatInt(off, 10))
 		}
-		retryErr := true
-		for r := 0; r < Retry; r++ {
-			req := newRequest(reqstr + param.Encode())
-			select {
-			case err = <-req.err:
-				continue
-			case a := <-req.ans:
-				var resp *spAnswer
-				resp, err = decodeTaxonList(&a)
-				if err != nil {
-					continue
-				}
-
-				for _, sp := range resp.Results {
-					// skip taxons with no match
-					// to the GBIF backbone
-					if sp.noMatch() {
-						continue
-					}
-					nub := sp.NubKey
-					if nub == 0 && db.useNub0 {
-						nub = sp.Key
-					}
-					if sp.Key != nub {
-						if !nubs[nub] {
-							nubs[nub] = false
-						}
-						continue
-					}
-					nubs[nub] = true
-					if !sc.Add(sp, nil) {
-						return
-					}
-				}
-
-				// end retry loop
-				r = Retry
-				retryErr = false
-				if resp.EndOfRecords {
-					end = true
-				}
-				off += resp.Limit
-			}
+		req := reqstr + param.Encode()
+		b, err := Client.GetList(req)
+		if err != nil {
+			sc.Add(nil, errors.Wrap(err, "gbif: taxonomy"))
+			return
 		}
-
-		if retryErr {
-			if err == nil {
-				sc.Add(nil, errors.Errorf("gbif: taxonomy: no answer after %d retries", Retry))
-			}
+		resp, err := decodeTaxonList(bytes.NewBuffer(b))
+		if err != nil {
+			Client.Remove(req)
 			sc.Add(nil, errors.Wrap(err, "gbif: taxonomy"))
 			return
 		}
 
-		// Add taxons if the nun taxon was found,
-		// but never included
-		// (for example,
-		// when searching an orthographic variant)
-		for id, ok := range nubs {
-			if ok {
+		for _, sp := range resp.Results {
+			// skip taxons with no match
+			// to the GBIF backbone
+			if sp.noMatch() {
 				continue
 			}
-			sp, err := db.TaxID(strconv.FormatInt(id, 10))
-			if err != nil {
-				sc.Add(nil, err)
+			nub := sp.NubKey
+			if nub == 0 && db.useNub0 {
+				nub = sp.Key
+			}
+			if sp.Key != nub {
+				if _, ok := nubs[nub]; !ok {
+					nubs[nub] = false
+					missing = append(missing, nub)
+				}
+				continue
+			}
+			nubs[nub] = true
+			if !sc.Add(sp, nil) {
 				return
 			}
-			sc.Add(sp, nil)
+		}
+		if resp.EndOfRecords {
+			end = true
+		}
+		off += resp.Limit
+	}
+
+	// Add taxons if the nub taxon was found,
+	// but never included
+	// (for example,
+	// when searching an orthographic variant)
+	var ids []string
+	for _, id := range missing {
+		if nubs[id] {
+			continue
+		}
+		ids = append(ids, strconv.FormatInt(id, 10))
+	}
+	ls, err := db.taxIDs(ids)
+	if err != nil {
+		sc.Add(nil, err)
+		return
+	}
+	for _, sp := range ls {
+		if !sc.Add(sp, nil) {
+			return
 		}
 	}
 	sc.Add(nil, nil)
@@ -311,40 +335,49 @@ func (db taxDB) rootTaxons(sc *biodv.TaxScan) {
 		"7", // Protozoa
 		"8", // Viruses
 	}
-	for _, id := range kingdoms {
-		tax, err := db.TaxID(id)
-		if err != nil {
-			sc.Add(nil, err)
-			break
-		}
+	ls, err := db.taxIDs(kingdoms)
+	if err != nil {
+		sc.Add(nil, err)
+		return
+	}
+	for _, tax := range ls {
 		sc.Add(tax, nil)
 	}
 	sc.Add(nil, nil)
 }
 
+// TaxIDs returns the taxons
+// of a list of IDs,
+// making the requests in parallel.
+func (db taxDB) taxIDs(ids []string) ([]biodv.Taxon, error) {
+	ls := make([]biodv.Taxon, len(ids))
+	errs := make([]error, len(ids))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			ls[i], errs[i] = db.TaxID(id)
+		}(i, id)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ls, nil
+}
+
 func (db taxDB) TaxID(id string) (biodv.Taxon, error) {
 	id = strings.TrimSpace(id)
 	if id == "" {
 		return nil, errors.Errorf("gbif: taxonomy: empty taxon ID")
 	}
-	var err error
-	for r := 0; r < Retry; r++ {
-		req := newRequest("species/" + id)
-		select {
-		case err = <-req.err:
-			continue
-		case a := <-req.ans:
-			d := json.NewDecoder(&a)
-			sp := &species{}
-			err = d.Decode(sp)
-			if err != nil {
-				continue
-			}
-			return sp, nil
-		}
-	}
-	if err == nil {
-		return nil, errors.Errorf("gbif: taxonomy: no answer after %d retries", Retry)
+	sp := &species{}
+	if err := Client.GetJSON("species/"+id, sp); err != nil {
+		return nil, errors.Wrap(err, "gbif: taxonomy")
 	}
-	return nil, errors.Wrap(err, "gbif: taxonomy")
+	return sp, nil
 }
@@ -11,86 +11,18 @@ package geolocate
 import (
 	"bytes"
 	"encoding/json"
-	"net/http"
 	"net/url"
 	"strings"
-	"time"
 
 	"github.com/js-arias/biodv"
+	"github.com/js-arias/biodv/driver/web"
 	"github.com/js-arias/biodv/geography"
 
 	"github.com/pkg/errors"
 )
 
-// Retry is the number of times a request will be retried
-// before aborted.
-var Retry = 5
-
-// Timeout is the timeout of the http request.
-var Timeout = 20 * time.Second
-
-// Wait is the waiting time for a new request
-// (we don't want to overload the GEOLocate server!).
-var Wait = time.Millisecond * 300
-
-// Buffer is the maximum number of requests in the request queue.
-var Buffer = 100
-
-const wsHead = "http://www.museum.tulane.edu/webservices/geolocatesvcv2/glcwrap.aspx?"
-
-// Request contains a GEOLocate request,
-// and a channel with the answers.
-type request struct {
-	req string
-	ans chan bytes.Buffer
-	err chan error
-}
-
-// NewRequest sends a request to the request channel.
-func newRequest(req string) request {
-	r := request{
-		req: wsHead + req,
-		ans: make(chan bytes.Buffer),
-		err: make(chan error),
-	}
-	reqChan.cReqs <- r
-	return r
-}
-
-// ReqChanType keeps the requests channel.
-type reqChanType struct {
-	cReqs chan request
-}
-
-// ReqChan is the requests channel.
-// It should be initialized before
-// using the database.
-var reqChan *reqChanType
-
-// InitReqs initialize the request channel.
-func initReqs() {
-	http.DefaultClient.Timeout = Timeout
-	reqChan = &reqChanType{cReqs: make(chan request, Buffer)}
-	go reqChan.reqs()
-}
-
-// Reqs make the network request.
-func (rc *reqChanType) reqs() {
-	for r := range rc.cReqs {
-		a, err := http.Get(r.req)
-		if err != nil {
-			r.err <- err
-			continue
-		}
-		var b bytes.Buffer
-		b.ReadFrom(a.Body)
-		a.Body.Close()
-		r.ans <- b
-
-		// we do not want to overload the gbif server.
-		time.Sleep(Wait)
-	}
-}
+// Client is the Client of the GEOLocate webservice.
+var Client = web.New("geolocate", "http://www.museum.tulane.edu/webservices/geolocatesvcv2/glcwrap.aspx?")
 
 func init() {
 	biodv.RegisterGz("geolocate", biodv.GzDriver{Open, aboutGEOLocate})
@@ -104,9 +36,6 @@ func aboutGEOLocate() string {
 // Open returns the GEOLocate service handle
 // that implements the biodv.Gazetteer interface.
 func Open(param string) (biodv.Gazetteer, error) {
-	if reqChan == nil {
-		initReqs()
-	}
 	return gzService{param}, nil
 }
 
@@ -190,43 +119,36 @@ type property struct {
 
 // PointList returns an specific list of points.
 func (gz gzService) pointList(sc *biodv.GeoScan, param url.Values) {
-	var err error
-	for r := 0; r < Retry; r++ {
-		req := newRequest(param.Encode())
-		select {
-		case err = <-req.err:
-			continue
-		case a := <-req.ans:
-			var resp *locAnswer
-			resp, err = decodePointList(&a)
-			if err != nil {
-				continue
-			}
-
-			for _, f := range resp.Features {
-				if gz.param != "" && f.Properties.Precision != gz.param {
-					continue
-				}
-				p := geography.Position{
-					Lat:    f.Geometry.Coordinates[1],
-					Lon:    f.Geometry.Coordinates[0],
-					Source: "web:geolocate",
-				}
-
-				if v, ok := f.Properties.UncertaintyRadiusMeters.(float64); ok {
-					p.Uncertainty = uint(v)
-				}
-				if !sc.Add(p, nil) {
-					return
-				}
-			}
-			r = Retry
-		}
+	req := param.Encode()
+	b, err := Client.Get(req)
+	if err != nil {
+		sc.Add(geography.NewPosition(), errors.Wrap(err, "geolocate"))
+		return
 	}
+	resp, err := decodePointList(bytes.NewBuffer(b))
 	if err != nil {
-		sc.Add(geography.NewPosition(), errors.Wrapf(err, "geolocate"))
+		Client.Remove(req)
+		sc.Add(geography.NewPosition(), errors.Wrap(err, "geolocate"))
 		return
 	}
+
+	for _, f := range resp.Features {
+		if gz.param != "" && f.Properties.Precision != gz.param {
+			continue
+		}
+		p := geography.Position{
+			Lat:    f.Geometry.Coordinates[1],
+			Lon:    f.Geometry.Coordinates[0],
+			Source: "web:geolocate",
+		}
+
+		if v, ok := f.Properties.UncertaintyRadiusMeters.(float64); ok {
+			p.Uncertainty = uint(v)
+		}
+		if !sc.Add(p, nil) {
+			return
+		}
+	}
 	sc.Add(geography.NewPosition(), nil)
 }
 
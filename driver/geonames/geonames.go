@@ -0,0 +1,328 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package geonames implements a gazetteer
+// that uses a local copy
+// of the GeoNames database
+// <http://download.geonames.org/export/dump/>.
+//
+// The driver parameter is the path
+// of the GeoNames data.
+// It can be a directory
+// that contains a complete dump
+// (allCountries.txt)
+// or one or more country extracts
+// (e.g. AR.txt),
+// or the path of a single dump file.
+// Dump files can be zip compressed.
+// The administrative code files
+// (admin1CodesASCII.txt and admin2Codes.txt)
+// are searched in the same directory.
+// If no parameter is given,
+// the directory 'geonames'
+// in the current project
+// will be used.
+package geonames
+
+import (
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/js-arias/biodv"
+	"github.com/js-arias/biodv/geography"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultDir is the default directory
+// of the GeoNames data.
+const DefaultDir = "geonames"
+
+// MaxDist is the maximum distance,
+// in meters,
+// between a point and a place
+// to be used in a reverse search.
+var MaxDist uint = 50000
+
+// Uncertainty is the uncertainty,
+// in meters,
+// assigned to a place,
+// given its GeoNames feature class.
+var Uncertainty = map[string]uint{
+	"A": 50000, // country, state, region
+	"H": 2000,  // stream, lake
+	"L": 5000,  // parks, area
+	"P": 2000,  // city, village
+	"R": 1000,  // road, railroad
+	"S": 500,   // spot, building, farm
+	"T": 2000,  // mountain, hill, rock
+	"U": 5000,  // undersea
+	"V": 2000,  // forest, heath
+}
+
+func init() {
+	biodv.RegisterGz("geonames", biodv.GzDriver{Open, aboutGeoNames})
+}
+
+// AboutGeoNames returns a simple statement of the purpose of the driver.
+func aboutGeoNames() string {
+	return "a driver for a local copy of GeoNames gazetteer"
+}
+
+// A Place is a GeoNames record.
+type place struct {
+	id      string
+	names   []string // normalized names
+	lat     float64
+	lon     float64
+	class   string
+	elev    uint
+	country string
+	admin1  string
+	admin2  string
+}
+
+// A Cell is a cell of one degree
+// used to index the places.
+type cell struct {
+	lat, lon int
+}
+
+// Gazetteer is a biodv.Gazetteer.
+type gazetteer struct {
+	byCountry map[string][]*place
+	grid      map[cell][]*place
+
+	// names of the administrative divisions
+	admin1 map[string]string
+	admin2 map[string]string
+}
+
+// Open returns a gazetteer
+// that implements the biodv.Gazetteer interface,
+// with the places stored in the GeoNames data
+// at the indicated path.
+func Open(param string) (biodv.Gazetteer, error) {
+	if param == "" {
+		param = DefaultDir
+	}
+	dumps, dir, err := dumpFiles(param)
+	if err != nil {
+		return nil, errors.Wrap(err, "geonames: open")
+	}
+
+	gz := &gazetteer{
+		byCountry: make(map[string][]*place),
+		grid:      make(map[cell][]*place),
+		admin1:    make(map[string]string),
+		admin2:    make(map[string]string),
+	}
+	for _, d := range dumps {
+		if err := readFile(d, gz.readPlaces); err != nil {
+			return nil, errors.Wrap(err, "geonames: open")
+		}
+	}
+	for _, a := range []struct {
+		file string
+		m    map[string]string
+	}{
+		{"admin1CodesASCII.txt", gz.admin1},
+		{"admin2Codes.txt", gz.admin2},
+	} {
+		name := filepath.Join(dir, a.file)
+		if _, err := os.Stat(name); err != nil {
+			continue
+		}
+		m := a.m
+		if err := readFile(name, func(r io.Reader) error { return readAdmin(r, m) }); err != nil {
+			return nil, errors.Wrap(err, "geonames: open")
+		}
+	}
+	return gz, nil
+}
+
+// DumpFiles returns the dump files
+// and the directory of a GeoNames path.
+func dumpFiles(path string) ([]string, string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, "", err
+	}
+	if !info.IsDir() {
+		return []string{path}, filepath.Dir(path), nil
+	}
+
+	for _, nm := range []string{"allCountries.txt", "allCountries.zip"} {
+		name := filepath.Join(path, nm)
+		if _, err := os.Stat(name); err == nil {
+			return []string{name}, path, nil
+		}
+	}
+
+	d, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	names, err := d.Readdirnames(0)
+	d.Close()
+	if err != nil {
+		return nil, "", err
+	}
+	sort.Strings(names)
+	var dumps []string
+	for _, nm := range names {
+		ext := filepath.Ext(nm)
+		if ext != ".txt" && ext != ".zip" {
+			continue
+		}
+		if cc := strings.TrimSuffix(nm, ext); len(cc) != 2 || !geography.IsValidCode(cc) {
+			continue
+		}
+		dumps = append(dumps, filepath.Join(path, nm))
+	}
+	if len(dumps) == 0 {
+		return nil, "", errors.Errorf("no GeoNames dump files in %q", path)
+	}
+	return dumps, path, nil
+}
+
+// Add adds a place to the gazetteer.
+func (gz *gazetteer) add(p *place) {
+	gz.byCountry[p.country] = append(gz.byCountry[p.country], p)
+
+	// administrative and undersea features
+	// are not used in reverse searches.
+	if p.class == "A" || p.class == "U" {
+		return
+	}
+	c := cellOf(p.lat, p.lon)
+	gz.grid[c] = append(gz.grid[c], p)
+}
+
+func cellOf(lat, lon float64) cell {
+	return cell{int(math.Floor(lat)), int(math.Floor(lon))}
+}
+
+// Locate returns the places
+// that match a locality name
+// inside an administrative division.
+//
+// If there are places with the same name
+// (ignoring case, diacritics, and punctuation)
+// only those places are returned,
+// otherwise,
+// the places with the most similar names
+// are returned.
+func (gz *gazetteer) Locate(adm geography.Admin, locality string) *biodv.GeoScan {
+	adm.Country = strings.ToUpper(adm.Country)
+	if !geography.IsValidCode(adm.Country) {
+		sc := biodv.NewGeoScan(1)
+		sc.Add(geography.NewPosition(), errors.Errorf("geonames: A valid country must be given to Locate"))
+		return sc
+	}
+	loc := geography.Normalize(locality)
+	if loc == "" {
+		sc := biodv.NewGeoScan(1)
+		sc.Add(geography.NewPosition(), errors.Errorf("geonames: Empty locality"))
+		return sc
+	}
+	state := geography.Normalize(adm.State)
+	county := geography.Normalize(adm.County)
+
+	tol := tolerance(loc)
+	best := tol + 1
+	var ls []*place
+	for _, p := range gz.byCountry[adm.Country] {
+		d := minDist(loc, p.names, best)
+		if d > best || d > tol {
+			continue
+		}
+		if state != "" && !sameName(state, geography.Normalize(gz.admin1[p.country+"."+p.admin1])) {
+			continue
+		}
+		if county != "" && !sameName(county, geography.Normalize(gz.admin2[p.country+"."+p.admin1+"."+p.admin2])) {
+			continue
+		}
+		if d < best {
+			best = d
+			ls = ls[:0]
+		}
+		ls = append(ls, p)
+	}
+
+	sc := biodv.NewGeoScan(len(ls) + 1)
+	for _, p := range ls {
+		sc.Add(p.position(), nil)
+	}
+	sc.Add(geography.NewPosition(), nil)
+	return sc
+}
+
+// Position returns the position of a place.
+func (p *place) position() geography.Position {
+	return geography.Position{
+		Lat:         p.lat,
+		Lon:         p.lon,
+		Elevation:   p.elev,
+		Source:      "geonames:" + p.id,
+		Uncertainty: Uncertainty[p.class],
+	}
+}
+
+// Reverse returns the administrative data
+// of the nearest place to a given point.
+// If there is no place
+// at a distance smaller than MaxDist,
+// it returns an empty Admin.
+func (gz *gazetteer) Reverse(pt geography.Position) (geography.Admin, error) {
+	if !pt.IsValid() {
+		return geography.Admin{}, errors.New("geonames: reverse: invalid position")
+	}
+
+	// a degree is about 111 km
+	dLat := int(math.Ceil(float64(MaxDist) / 111195))
+	dLon := 180
+	if cs := math.Cos(pt.Lat * math.Pi / 180); cs > 0.01 {
+		dLon = int(math.Ceil(float64(MaxDist) / (111195 * cs)))
+		if dLon > 180 {
+			dLon = 180
+		}
+	}
+
+	c := cellOf(pt.Lat, pt.Lon)
+	var near *place
+	min := MaxDist + 1
+	for lat := c.lat - dLat; lat <= c.lat+dLat; lat++ {
+		for i := -dLon; i <= dLon; i++ {
+			lon := c.lon + i
+			if lon < -180 {
+				lon += 360
+			}
+			if lon >= 180 {
+				lon -= 360
+			}
+			for _, p := range gz.grid[cell{lat, lon}] {
+				d := pt.Distance(geography.Position{Lat: p.lat, Lon: p.lon})
+				if d < min {
+					min = d
+					near = p
+				}
+			}
+		}
+	}
+	if near == nil {
+		return geography.Admin{}, nil
+	}
+	return geography.Admin{
+		Country: near.country,
+		State:   gz.admin1[near.country+"."+near.admin1],
+		County:  gz.admin2[near.country+"."+near.admin1+"."+near.admin2],
+	}, nil
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package geonames
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/js-arias/biodv/geography"
+)
+
+var arBlob = strings.Join([]string{
+	"3841956\tLas Pavas\tLas Pavas\t\t-22.46667\t-64.58333\tP\tPPL\tAR\t\t17\t6662\t\t\t0\t\t412\tAmerica/Argentina/Salta\t2016-01-30",
+	"3841957\tLas Pavas\tLas Pavas\t\t-27.25375\t-65.87399\tP\tPPL\tAR\t\t24\t1060\t\t\t0\t\t1410\tAmerica/Argentina/Tucuman\t2016-01-30",
+	"3838233\tSalta\tSalta\tCiudad de Salta,Сальта\t-24.7859\t-65.41166\tP\tPPLA\tAR\t\t17\t6628\t\t\t512686\t\t1165\tAmerica/Argentina/Salta\t2016-01-30",
+	"3836873\tSan Miguel de Tucumán\tSan Miguel de Tucuman\tTucuman,Tucumán\t-26.82414\t-65.2226\tP\tPPLA\tAR\t\t24\t1029\t\t\t781023\t\t469\tAmerica/Argentina/Tucuman\t2016-01-30",
+	"3838231\tProvincia de Salta\tProvincia de Salta\tSalta\t-25\t-64.5\tA\tADM1\tAR\t\t17\t\t\t\t1333365\t\t365\tAmerica/Argentina/Salta\t2016-01-30",
+	"",
+}, "\n")
+
+var admin1Blob = "AR.17\tSalta\tSalta\t3838231\n" +
+	"AR.24\tTucumán\tTucuman\t3833578\n"
+
+var admin2Blob = "AR.17.6662\tDepartamento de Santa Victoria\tDepartamento de Santa Victoria\t3836199\n" +
+	"AR.17.6628\tDepartamento Capital\tDepartamento Capital\t3862254\n" +
+	"AR.24.1060\tDepartamento de Tafí del Valle\tDepartamento de Tafi del Valle\t3836009\n" +
+	"AR.24.1029\tDepartamento Capital\tDepartamento Capital\t3836564\n"
+
+func openTest(t *testing.T) (*gazetteer, func()) {
+	dir, err := ioutil.TempDir("", "geonames")
+	if err != nil {
+		t.Fatalf("unable to create temporal directory: %v", err)
+	}
+	for _, f := range []struct {
+		name, data string
+	}{
+		{"AR.txt", arBlob},
+		{"admin1CodesASCII.txt", admin1Blob},
+		{"admin2Codes.txt", admin2Blob},
+		{"readme.txt", "not a dump file"},
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, f.name), []byte(f.data), 0644); err != nil {
+			os.RemoveAll(dir)
+			t.Fatalf("unable to write %s: %v", f.name, err)
+		}
+	}
+	gz, err := Open(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return gz.(*gazetteer), func() { os.RemoveAll(dir) }
+}
+
+func locate(t *testing.T, gz *gazetteer, adm geography.Admin, loc string) []geography.Position {
+	var ls []geography.Position
+	sc := gz.Locate(adm, loc)
+	for sc.Scan() {
+		ls = append(ls, sc.Position())
+	}
+	if err := sc.Err(); err != nil {
+		t.Errorf("locate %q: unexpected error: %v", loc, err)
+	}
+	return ls
+}
+
+func TestLocate(t *testing.T) {
+	gz, clean := openTest(t)
+	defer clean()
+
+	tests := []struct {
+		adm  geography.Admin
+		loc  string
+		want []string
+	}{
+		{geography.Admin{Country: "ar"}, "Las Pavas", []string{"geonames:3841956", "geonames:3841957"}},
+		{geography.Admin{Country: "AR", State: "Tucuman"}, "las pavas", []string{"geonames:3841957"}},
+		{geography.Admin{Country: "AR", State: "Prov. Salta", County: "Santa Victoria"}, "Las Pavas", []string{"geonames:3841956"}},
+		{geography.Admin{Country: "AR"}, "Las  Pabas", []string{"geonames:3841956", "geonames:3841957"}},
+		{geography.Admin{Country: "AR"}, "S. Miguel de Tucuman", []string{"geonames:3836873"}},
+		{geography.Admin{Country: "AR"}, "Ciudad de Salta", []string{"geonames:3838233"}},
+		{geography.Admin{Country: "AR"}, "Salta", []string{"geonames:3838233", "geonames:3838231"}},
+		{geography.Admin{Country: "AR", State: "Jujuy"}, "Las Pavas", nil},
+		{geography.Admin{Country: "AR"}, "Las Palmas", nil},
+		{geography.Admin{Country: "UY"}, "Las Pavas", nil},
+	}
+	for _, test := range tests {
+		ls := locate(t, gz, test.adm, test.loc)
+		if len(ls) != len(test.want) {
+			t.Errorf("locate %q: found %d places, want %d", test.loc, len(ls), len(test.want))
+			continue
+		}
+		for i, p := range ls {
+			if p.Source != test.want[i] {
+				t.Errorf("locate %q: place %q, want %q", test.loc, p.Source, test.want[i])
+			}
+		}
+	}
+
+	sc := gz.Locate(geography.Admin{}, "Las Pavas")
+	for sc.Scan() {
+	}
+	if sc.Err() == nil {
+		t.Errorf("locate without country: expecting error")
+	}
+
+	ls := locate(t, gz, geography.Admin{Country: "AR", State: "Salta"}, "Las Pavas")
+	if len(ls) == 1 && (ls[0].Uncertainty != Uncertainty["P"] || ls[0].Elevation != 412) {
+		t.Errorf("place uncertainty %d, elevation %d, want %d, %d", ls[0].Uncertainty, ls[0].Elevation, Uncertainty["P"], 412)
+	}
+}
+
+func TestReverse(t *testing.T) {
+	gz, clean := openTest(t)
+	defer clean()
+
+	tests := []struct {
+		p    geography.Position
+		want geography.Admin
+	}{
+		{geography.Position{Lat: -22.5, Lon: -64.6}, geography.Admin{"AR", "Salta", "Departamento de Santa Victoria"}},
+		{geography.Position{Lat: -26.9, Lon: -65.3}, geography.Admin{"AR", "Tucumán", "Departamento Capital"}},
+		{geography.Position{Lat: -24.8, Lon: -65.3}, geography.Admin{"AR", "Salta", "Departamento Capital"}},
+		{geography.Position{Lat: -34.6, Lon: -58.4}, geography.Admin{}},
+	}
+	for _, test := range tests {
+		adm, err := gz.Reverse(test.p)
+		if err != nil {
+			t.Errorf("reverse %v: unexpected error: %v", test.p, err)
+			continue
+		}
+		if adm != test.want {
+			t.Errorf("reverse %v: %v, want %v", test.p, adm, test.want)
+		}
+	}
+	if _, err := gz.Reverse(geography.NewPosition()); err == nil {
+		t.Errorf("reverse invalid position: expecting error")
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package geonames
+
+import (
+	"strings"
+	"unicode"
+)
+
+// IsASCII returns true
+// if a name only contains ASCII characters.
+func isASCII(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if name[i] > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
+
+// Tolerance returns the maximum number of edits
+// accepted when comparing a name.
+// It is about one edit each six letters.
+func tolerance(name string) int {
+	return len([]rune(name)) / 6
+}
+
+// SameName returns true if two
+// normalized names of an administrative division
+// can be taken as the same name,
+// i.e. one name contains the other,
+// or the differences are within the tolerance.
+func sameName(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	if strings.Contains(" "+a+" ", " "+b+" ") || strings.Contains(" "+b+" ", " "+a+" ") {
+		return true
+	}
+	return levenshtein([]rune(a), []rune(b), tolerance(a)) <= tolerance(a)
+}
+
+// MinDist returns the minimum edit distance
+// between a name
+// and a list of names.
+// Distances greater than max
+// are not calculated exactly.
+func minDist(name string, names []string, max int) int {
+	nm := []rune(name)
+	min := max + 1
+	for _, n := range names {
+		if n == name {
+			return 0
+		}
+		if d := levenshtein(nm, []rune(n), min-1); d < min {
+			min = d
+		}
+	}
+	return min
+}
+
+// Levenshtein returns the edit distance
+// between two strings.
+// If the distance is greater than max,
+// it returns a value greater than max.
+func levenshtein(a, b []rune, max int) int {
+	if d := len(a) - len(b); d > max || -d > max {
+		return max + 1
+	}
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			v := prev[j-1] + cost
+			if d := prev[j] + 1; d < v {
+				v = d
+			}
+			if d := curr[j-1] + 1; d < v {
+				v = d
+			}
+			curr[j] = v
+			if v < rowMin {
+				rowMin = v
+			}
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
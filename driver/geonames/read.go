@@ -0,0 +1,176 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package geonames
+
+import (
+	"archive/zip"
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/biodv/geography"
+
+	"github.com/pkg/errors"
+)
+
+// ReadFile opens a file
+// (or the text file of a zip file)
+// and process it with a read function.
+func readFile(name string, read func(io.Reader) error) error {
+	if filepath.Ext(name) != ".zip" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := read(f); err != nil {
+			return errors.Wrapf(err, "%s", name)
+		}
+		return nil
+	}
+
+	zr, err := zip.OpenReader(name)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	base := strings.TrimSuffix(filepath.Base(name), ".zip") + ".txt"
+	for _, zf := range zr.File {
+		if zf.Name != base {
+			continue
+		}
+		f, err := zf.Open()
+		if err != nil {
+			return errors.Wrapf(err, "%s", name)
+		}
+		defer f.Close()
+		if err := read(f); err != nil {
+			return errors.Wrapf(err, "%s: %s", name, base)
+		}
+		return nil
+	}
+	return errors.Errorf("%s: file %q not found", name, base)
+}
+
+// Columns of a GeoNames dump file.
+const (
+	colID = iota
+	colName
+	colASCII
+	colAlternate
+	colLat
+	colLon
+	colClass
+	colCode
+	colCountry
+	colCC2
+	colAdmin1
+	colAdmin2
+	colAdmin3
+	colAdmin4
+	colPopulation
+	colElevation
+	colDEM
+	colTimeZone
+	colModified
+	numCols
+)
+
+// ReadPlaces reads the places
+// of a GeoNames dump file.
+func (gz *gazetteer) readPlaces(r io.Reader) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for ln := 1; s.Scan(); ln++ {
+		row := strings.Split(s.Text(), "\t")
+		if len(row) < numCols {
+			if strings.TrimSpace(s.Text()) == "" {
+				continue
+			}
+			return errors.Errorf("line %d: found %d columns, want %d", ln, len(row), numCols)
+		}
+		cc := strings.ToUpper(row[colCountry])
+		if !geography.IsValidCode(cc) {
+			continue
+		}
+		lat, err := strconv.ParseFloat(row[colLat], 64)
+		if err != nil {
+			return errors.Wrapf(err, "line %d", ln)
+		}
+		lon, err := strconv.ParseFloat(row[colLon], 64)
+		if err != nil {
+			return errors.Wrapf(err, "line %d", ln)
+		}
+		if !geography.IsValidCoord(lat, lon) {
+			continue
+		}
+		p := &place{
+			id:      row[colID],
+			lat:     lat,
+			lon:     lon,
+			class:   row[colClass],
+			country: cc,
+			admin1:  row[colAdmin1],
+			admin2:  row[colAdmin2],
+		}
+		for _, col := range []int{colElevation, colDEM} {
+			if v, err := strconv.Atoi(row[col]); err == nil && v > 0 {
+				p.elev = uint(v)
+				break
+			}
+		}
+		p.names = placeNames(row[colName], row[colASCII], row[colAlternate])
+		gz.add(p)
+	}
+	return s.Err()
+}
+
+// PlaceNames returns the normalized names
+// of a place.
+// Alternate names that are not
+// in the latin alphabet are ignored.
+func placeNames(name, ascii, alternate string) []string {
+	var names []string
+	add := func(nm string) {
+		nm = geography.Normalize(nm)
+		if nm == "" || !isASCII(nm) {
+			return
+		}
+		for _, n := range names {
+			if n == nm {
+				return
+			}
+		}
+		names = append(names, nm)
+	}
+	add(name)
+	add(ascii)
+	for _, nm := range strings.Split(alternate, ",") {
+		add(nm)
+	}
+	return names
+}
+
+// ReadAdmin reads a GeoNames
+// administrative codes file.
+func readAdmin(r io.Reader, m map[string]string) error {
+	s := bufio.NewScanner(r)
+	for ln := 1; s.Scan(); ln++ {
+		row := strings.Split(s.Text(), "\t")
+		if len(row) < 2 {
+			if strings.TrimSpace(s.Text()) == "" {
+				continue
+			}
+			return errors.Errorf("line %d: found %d columns, want at least 2", ln, len(row))
+		}
+		m[row[0]] = row[1]
+	}
+	return s.Err()
+}
@@ -0,0 +1,30 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package inat implements an interface
+// to iNaturalist webservice.
+//
+// Records are searched
+// using iNaturalist taxon IDs,
+// so a taxon must have an iNaturalist external ID
+// (e.g. set with 'tax.set -k extern -v inat:<id> <name>')
+// before its records can be retrieved.
+package inat
+
+import (
+	"time"
+
+	"github.com/js-arias/biodv/driver/web"
+)
+
+// Client is the Client of the iNaturalist webservice.
+var Client = web.New("inat", "https://api.inaturalist.org/v1/")
+
+func init() {
+	// iNaturalist asks for no more than
+	// one request per second.
+	Client.Wait = time.Second
+}
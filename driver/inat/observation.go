@@ -0,0 +1,254 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package inat
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/js-arias/biodv"
+	"github.com/js-arias/biodv/geography"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	biodv.RegisterRec("inat", biodv.RecDriver{OpenRec, RecURL, aboutRecINat})
+}
+
+// RecURL returns the url of an iNaturalist observation.
+func RecURL(id string) string {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return ""
+	}
+	return "https://www.inaturalist.org/observations/" + id
+}
+
+// AboutRecINat returns a simple statement of the purpose of the driver.
+func aboutRecINat() string {
+	return "a driver for the iNaturalist observations DB"
+}
+
+// RecUseAll is a parameter used to open a recDB
+// that returns all the observations for a given taxon,
+// including the observations
+// that are not research grade.
+const RecUseAll = "use-all"
+
+// OpenRec returns the iNaturalist
+// records handler,
+// that implements the biodv.RecDB interface.
+//
+// By default,
+// it will search only research grade observations.
+func OpenRec(param string) (biodv.RecDB, error) {
+	db := recDB{param: url.Values{}}
+	if param != RecUseAll {
+		db.param.Add("quality_grade", "research")
+	}
+	return db, nil
+}
+
+// RecDB is the handler of iNaturalist observations DB.
+type recDB struct {
+	param url.Values
+}
+
+// ObsAnswer is the answer of the observations request.
+type obsAnswer struct {
+	TotalResults int64 `json:"total_results"`
+	PerPage      int64 `json:"per_page"`
+	Results      []*observation
+}
+
+// Observation stores the iNaturalist observation information.
+// It implements the biodv.Record interface.
+type observation struct {
+	Key     int64 `json:"id"`
+	TaxInfo *struct {
+		ID int64
+	} `json:"taxon"`
+	URI         string
+	Description string
+
+	// Collection event
+	ObservedOn     string `json:"observed_on"`
+	TimeObservedAt string `json:"time_observed_at"`
+	PlaceGuess     string `json:"place_guess"`
+	User           struct {
+		Login string
+		Name  string
+	}
+
+	// Georeference
+	GeoJSON *struct {
+		Coordinates []float64
+	} `json:"geojson"`
+	PositionalAccuracy       float64 `json:"positional_accuracy"`
+	PublicPositionalAccuracy float64 `json:"public_positional_accuracy"`
+}
+
+func (obs *observation) Taxon() string {
+	if obs.TaxInfo == nil {
+		return ""
+	}
+	return strconv.FormatInt(obs.TaxInfo.ID, 10)
+}
+
+func (obs *observation) ID() string {
+	return strconv.FormatInt(obs.Key, 10)
+}
+
+func (obs *observation) Basis() biodv.BasisOfRecord {
+	return biodv.Observation
+}
+
+func (obs *observation) CollEvent() biodv.CollectionEvent {
+	t, _ := time.Parse(time.RFC3339, obs.TimeObservedAt)
+	if t.IsZero() {
+		t, _ = time.Parse("2006-01-02", obs.ObservedOn)
+	}
+	place := strings.Join(strings.Fields(obs.PlaceGuess), " ")
+
+	cl := biodv.CollectionEvent{
+		Date:      t,
+		Admin:     geography.Admin{Country: placeCountry(place)},
+		Locality:  place,
+		Collector: strings.Join(strings.Fields(obs.User.Name), " "),
+	}
+	if cl.Collector == "" {
+		cl.Collector = obs.User.Login
+	}
+	return cl
+}
+
+// PlaceCountry returns the country code
+// of a place description,
+// assuming that the country is the last element
+// of the description
+// (e.g. 'Tafí del Valle, Tucumán, Argentina').
+func placeCountry(place string) string {
+	i := strings.LastIndex(place, ",")
+	if i < 0 {
+		return ""
+	}
+	c := strings.TrimSpace(place[i+1:])
+	if len(c) == 2 && geography.IsValidCode(c) {
+		return strings.ToUpper(c)
+	}
+	return geography.CountryCode(c)
+}
+
+func (obs *observation) GeoRef() geography.Position {
+	p := geography.NewPosition()
+	if obs.GeoJSON == nil || len(obs.GeoJSON.Coordinates) < 2 {
+		return p
+	}
+	lon, lat := obs.GeoJSON.Coordinates[0], obs.GeoJSON.Coordinates[1]
+	if !geography.IsValidCoord(lat, lon) {
+		return p
+	}
+	p.Lat = lat
+	p.Lon = lon
+
+	// in obscured observations
+	// the public accuracy
+	// includes the obscured area.
+	acc := obs.PositionalAccuracy
+	if obs.PublicPositionalAccuracy > acc {
+		acc = obs.PublicPositionalAccuracy
+	}
+	if acc > 0 {
+		p.Uncertainty = uint(acc)
+	}
+	return p
+}
+
+func (obs *observation) Keys() []string {
+	return []string{
+		biodv.RecRef,
+		biodv.RecComment,
+	}
+}
+
+func (obs *observation) Value(key string) string {
+	switch strings.ToLower(strings.TrimSpace(key)) {
+	case biodv.RecRef:
+		return obs.URI
+	case biodv.RecComment:
+		return strings.TrimSpace(obs.Description)
+	}
+	return ""
+}
+
+func (db recDB) TaxRecs(id string) *biodv.RecScan {
+	sc := biodv.NewRecScan(200)
+	id = strings.TrimSpace(id)
+	if id == "" || id == "0" {
+		sc.Add(nil, errors.Errorf("inat: recDB: invalid taxon ID"))
+		return sc
+	}
+	param := url.Values{}
+	for key, val := range db.param {
+		for _, s := range val {
+			param.Add(key, s)
+		}
+	}
+	param.Add("taxon_id", id)
+	param.Add("per_page", "200")
+	param.Add("order_by", "id")
+	param.Add("order", "asc")
+	go db.recordList(sc, param)
+	return sc
+}
+
+// RecordList returns the list of observations
+// with a given set of parameters.
+//
+// As iNaturalist limits the number of pages
+// that can be requested,
+// the observations are ordered by its ID,
+// and each request asks for the observations
+// after the last received ID.
+func (db recDB) recordList(sc *biodv.RecScan, param url.Values) {
+	for {
+		resp := &obsAnswer{}
+		if err := Client.GetListJSON("observations?"+param.Encode(), resp); err != nil {
+			sc.Add(nil, errors.Wrap(err, "inat: recDB"))
+			return
+		}
+		for _, obs := range resp.Results {
+			if !sc.Add(obs, nil) {
+				return
+			}
+		}
+		if len(resp.Results) == 0 || int64(len(resp.Results)) < resp.PerPage {
+			break
+		}
+		last := resp.Results[len(resp.Results)-1]
+		param.Set("id_above", strconv.FormatInt(last.Key, 10))
+	}
+	sc.Add(nil, nil)
+}
+
+func (db recDB) RecID(id string) (biodv.Record, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return nil, errors.Errorf("inat: recDB: empty record ID")
+	}
+	resp := &obsAnswer{}
+	if err := Client.GetJSON("observations/"+url.PathEscape(id), resp); err != nil {
+		return nil, errors.Wrapf(err, "inat: recDB: record %q", id)
+	}
+	if len(resp.Results) == 0 {
+		return nil, errors.Errorf("inat: recDB: record %q: not found", id)
+	}
+	return resp.Results[0], nil
+}
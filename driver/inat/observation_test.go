@@ -0,0 +1,155 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package inat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/js-arias/biodv"
+	"github.com/js-arias/biodv/driver/web"
+)
+
+// Fixtures are answers recorded from iNaturalist
+// (reduced to the fields used by the driver),
+// indexed by the request.
+var fixtures = map[string]string{
+	"/observations?order=asc&order_by=id&per_page=200&quality_grade=research&taxon_id=20504":                   rheaPage1Blob,
+	"/observations?id_above=18520336&order=asc&order_by=id&per_page=200&quality_grade=research&taxon_id=20504": rheaPage2Blob,
+	"/observations/15331745": rheaObsBlob,
+}
+
+// NewTestServer starts a test server,
+// and sets the client to use it.
+// The returned function closes the server,
+// and restores the client.
+func newTestServer() func() {
+	base, wait, ttl := Client.Base, Client.Wait, web.TTL
+	Client.Wait = 0
+	web.TTL = 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		blob, ok := fixtures[r.URL.RequestURI()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(blob))
+	}))
+	Client.Base = srv.URL + "/"
+	return func() {
+		srv.Close()
+		Client.Base, Client.Wait, web.TTL = base, wait, ttl
+	}
+}
+
+func TestTaxRecs(t *testing.T) {
+	done := newTestServer()
+	defer done()
+
+	db, err := OpenRec("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sc := db.TaxRecs("20504")
+	var ls []biodv.Record
+	for sc.Scan() {
+		ls = append(ls, sc.Record())
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testData := []struct {
+		id        string
+		taxon     string
+		date      time.Time
+		country   string
+		locality  string
+		collector string
+		lat, lon  float64
+		uncert    uint
+	}{
+		{"15331745", "20504", time.Date(2018, 8, 12, 10, 21, 0, 0, time.FixedZone("", -3*3600)), "AR", "Reserva Ecológica Costanera Sur, Buenos Aires, Argentina", "Juana Pérez", -34.6125, -58.3536, 12},
+		{"18520336", "20504", time.Date(2018, 11, 3, 0, 0, 0, 0, time.UTC), "UY", "Rocha, UY", "birder42", -34.21, -53.88, 25000},
+		{"20114872", "144545", time.Date(2019, 1, 20, 17, 5, 0, 0, time.FixedZone("", -3*3600)), "", "Entre Ríos", "Pedro Gómez", -31.7, -60.5, 0},
+	}
+	if len(ls) != len(testData) {
+		t.Fatalf("found %d records, want %d", len(ls), len(testData))
+	}
+	for i, d := range testData {
+		r := ls[i]
+		if r.ID() != d.id {
+			t.Errorf("record %d: ID %q, want %q", i, r.ID(), d.id)
+		}
+		if r.Taxon() != d.taxon {
+			t.Errorf("record %s: taxon %q, want %q", d.id, r.Taxon(), d.taxon)
+		}
+		if r.Basis() != biodv.Observation {
+			t.Errorf("record %s: basis %s, want %s", d.id, r.Basis(), biodv.Observation)
+		}
+		ev := r.CollEvent()
+		if !ev.Date.Equal(d.date) {
+			t.Errorf("record %s: date %v, want %v", d.id, ev.Date, d.date)
+		}
+		if ev.CountryCode() != d.country {
+			t.Errorf("record %s: country %q, want %q", d.id, ev.CountryCode(), d.country)
+		}
+		if ev.Locality != d.locality {
+			t.Errorf("record %s: locality %q, want %q", d.id, ev.Locality, d.locality)
+		}
+		if ev.Collector != d.collector {
+			t.Errorf("record %s: collector %q, want %q", d.id, ev.Collector, d.collector)
+		}
+		geo := r.GeoRef()
+		if geo.Lat != d.lat || geo.Lon != d.lon {
+			t.Errorf("record %s: coordinates %.4f %.4f, want %.4f %.4f", d.id, geo.Lat, geo.Lon, d.lat, d.lon)
+		}
+		if geo.Uncertainty != d.uncert {
+			t.Errorf("record %s: uncertainty %d, want %d", d.id, geo.Uncertainty, d.uncert)
+		}
+	}
+}
+
+func TestRecID(t *testing.T) {
+	done := newTestServer()
+	defer done()
+
+	db, _ := OpenRec("")
+	r, err := db.RecID("15331745")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.Taxon() != "20504" {
+		t.Errorf("record %s: taxon %q, want %q", r.ID(), r.Taxon(), "20504")
+	}
+	if ref := r.Value(biodv.RecRef); ref != "https://www.inaturalist.org/observations/15331745" {
+		t.Errorf("record %s: reference %q", r.ID(), ref)
+	}
+	if c := r.Value(biodv.RecComment); c != "Adult with chicks." {
+		t.Errorf("record %s: comment %q, want %q", r.ID(), c, "Adult with chicks.")
+	}
+
+	if _, err := db.RecID("1"); err == nil {
+		t.Errorf("record %q: expecting error", "1")
+	}
+}
+
+var rheaObsJSON = `{"id":15331745,"uuid":"6b0d5b02-4d3f-4b7e-9a4c-0f6f2f1b1e01","quality_grade":"research","observed_on":"2018-08-12","time_observed_at":"2018-08-12T10:21:00-03:00","place_guess":"Reserva Ecológica Costanera Sur, Buenos Aires, Argentina","location":"-34.6125,-58.3536","geojson":{"coordinates":[-58.3536,-34.6125],"type":"Point"},"positional_accuracy":12,"public_positional_accuracy":12,"obscured":false,"geoprivacy":null,"taxon":{"id":20504,"name":"Rhea americana","rank":"species"},"user":{"id":1234,"login":"jperez","name":"Juana  Pérez"},"uri":"https://www.inaturalist.org/observations/15331745","description":" Adult with chicks. "}`
+
+var rheaPage1Blob = `{"total_results":3,"page":1,"per_page":2,"results":[
+	` + rheaObsJSON + `,
+	{"id":18520336,"quality_grade":"research","observed_on":"2018-11-03","time_observed_at":null,"place_guess":"Rocha, UY","geojson":{"coordinates":[-53.88,-34.21],"type":"Point"},"positional_accuracy":30,"public_positional_accuracy":25000,"obscured":true,"geoprivacy":"obscured","taxon":{"id":20504,"name":"Rhea americana","rank":"species"},"user":{"id":4321,"login":"birder42","name":""},"uri":"https://www.inaturalist.org/observations/18520336","description":null}
+]}`
+
+var rheaPage2Blob = `{"total_results":3,"page":1,"per_page":2,"results":[
+	{"id":20114872,"quality_grade":"research","observed_on":"2019-01-20","time_observed_at":"2019-01-20T17:05:00-03:00","place_guess":"Entre Ríos","geojson":{"coordinates":[-60.5,-31.7],"type":"Point"},"positional_accuracy":null,"public_positional_accuracy":null,"obscured":false,"geoprivacy":null,"taxon":{"id":144545,"name":"Rhea americana intermedia","rank":"subspecies"},"user":{"id":5555,"login":"pgomez","name":"Pedro Gómez"},"uri":"https://www.inaturalist.org/observations/20114872","description":""}
+]}`
+
+var rheaObsBlob = `{"total_results":1,"page":1,"per_page":1,"results":[` + rheaObsJSON + `]}`
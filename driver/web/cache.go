@@ -0,0 +1,155 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CacheDir is the directory
+// used to store the cached responses.
+// If empty,
+// a biodv directory
+// in the user cache directory is used.
+var CacheDir string
+
+// CacheRoot returns the directory
+// used to store the cached responses.
+func cacheRoot() string {
+	if CacheDir != "" {
+		return CacheDir
+	}
+	d, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(d, "biodv")
+}
+
+// Path returns the file
+// used to store the answer of a request.
+func (c *Client) path(u string) string {
+	dir := cacheRoot()
+	if dir == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(u))
+	return filepath.Join(dir, c.Name, hex.EncodeToString(sum[:]))
+}
+
+// Cached returns the cached answer
+// of a request.
+func (c *Client) cached(u string) ([]byte, bool) {
+	if TTL <= 0 && !Offline {
+		return nil, false
+	}
+	p := c.path(u)
+	if p == "" {
+		return nil, false
+	}
+	fi, err := os.Stat(p)
+	if err != nil {
+		return nil, false
+	}
+	if !Offline && time.Since(fi.ModTime()) > TTL {
+		return nil, false
+	}
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+// Store stores the answer of a request
+// in the cache.
+// As the cache is only an optimization,
+// errors are ignored.
+func (c *Client) store(u string, b []byte) {
+	if TTL <= 0 {
+		return
+	}
+	p := c.path(u)
+	if p == "" {
+		return
+	}
+	dir := filepath.Dir(p)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	f, err := ioutil.TempFile(dir, "tmp-")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(b)
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return
+	}
+	if err := os.Rename(f.Name(), p); err != nil {
+		os.Remove(f.Name())
+	}
+}
+
+// Remove removes the answer of a request
+// from the cache.
+// It should be used
+// when an answer returned by Get
+// is invalid.
+func (c *Client) Remove(req string) {
+	if p := c.path(c.Base + req); p != "" {
+		os.Remove(p)
+	}
+}
+
+// Prune removes from the cache
+// the files stored before the given age
+// (including temporary files
+// left by interrupted requests).
+// If age is zero,
+// all the answers are removed.
+// It returns the number of removed files.
+func Prune(age time.Duration) (int, error) {
+	dir := cacheRoot()
+	if dir == "" {
+		return 0, nil
+	}
+	n := 0
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if fi.IsDir() {
+			return nil
+		}
+		if age > 0 && time.Since(fi.ModTime()) <= age {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		n++
+		return nil
+	})
+	if err != nil {
+		return n, errors.Wrap(err, "web: prune")
+	}
+	return n, nil
+}
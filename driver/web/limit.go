@@ -0,0 +1,57 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package web
+
+import (
+	"sync"
+	"time"
+)
+
+// Bucket is a token bucket,
+// used to limit the rate of the requests.
+type bucket struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// Take takes a token from the bucket,
+// in which a token is added every given interval,
+// up to burst tokens.
+// It returns the waiting time
+// until the token is available.
+func (b *bucket) take(every time.Duration, burst int) time.Duration {
+	if every <= 0 {
+		return 0
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if b.last.IsZero() {
+		b.tokens = float64(burst)
+	} else {
+		b.tokens += float64(now.Sub(b.last)) / float64(every)
+		if b.tokens > float64(burst) {
+			b.tokens = float64(burst)
+		}
+	}
+	b.last = now
+
+	// the token is reserved,
+	// so the bucket can have a debt
+	// that is paid by waiting.
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens * float64(every))
+}
@@ -0,0 +1,277 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package web implements the HTTP transport
+// shared by the drivers of web services.
+//
+// A Client makes GET requests
+// to a web service,
+// waiting between requests,
+// so the server is not overloaded,
+// and retrying the requests
+// that fail with a network error,
+// or a 429 (too many requests)
+// or 5xx (server error) status.
+//
+// The answers are stored in an on-disk cache,
+// so repeated requests
+// are answered without using the network.
+// The answers of lists and searches
+// are only read from the cache
+// in offline mode.
+// If Offline is set,
+// the requests are answered only from the cache.
+// Expired answers can be removed
+// from the cache with Prune.
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Offline if true,
+// the clients answer only with cached responses,
+// without making any network request.
+var Offline bool
+
+// TTL is the time to live of a cached response.
+// If TTL is zero,
+// the cache is not used
+// (except in offline mode).
+var TTL = 24 * time.Hour
+
+// ErrNotFound is the error returned
+// when the requested element
+// is not in the webservice.
+var ErrNotFound = errors.New("not found")
+
+// ErrNotCached is the error returned
+// in offline mode,
+// when the request is not in the cache.
+var ErrNotCached = errors.New("not in cache")
+
+// StatusError is the error returned
+// when the webservice answers
+// with an unexpected status.
+type StatusError struct {
+	URL    string
+	Code   int
+	Status string
+}
+
+func (err *StatusError) Error() string {
+	return fmt.Sprintf("%s: %s", err.URL, err.Status)
+}
+
+// Temporary returns true
+// if the request can be retried.
+func (err *StatusError) Temporary() bool {
+	return err.Code == http.StatusTooManyRequests || err.Code >= 500
+}
+
+// A Client is a client
+// for a web service.
+// The client fields should be set
+// before making any request.
+type Client struct {
+	// Name is the name of the service,
+	// used to store the cached responses.
+	Name string
+
+	// Base is the base URL of the webservice,
+	// prepended to each request.
+	Base string
+
+	// Retry is the number of times a request will be retried
+	// before aborted.
+	Retry int
+
+	// Timeout is the timeout of the http request.
+	Timeout time.Duration
+
+	// Wait is the waiting time between requests,
+	// i.e. the rate limit of the client.
+	Wait time.Duration
+
+	// Burst is the maximum number of requests
+	// that can be made without waiting,
+	// if the client was idle.
+	Burst int
+
+	// Workers is the maximum number of requests
+	// in flight at the same time.
+	Workers int
+
+	// Backoff is the waiting time
+	// before the first retry of a failed request.
+	// It is doubled on each new retry.
+	Backoff time.Duration
+
+	once    sync.Once
+	client  *http.Client
+	workers chan struct{}
+	bucket  bucket
+}
+
+// New returns a new client
+// for a webservice,
+// with default values.
+func New(name, base string) *Client {
+	return &Client{
+		Name:    name,
+		Base:    base,
+		Retry:   5,
+		Timeout: 20 * time.Second,
+		Wait:    300 * time.Millisecond,
+		Burst:   1,
+		Workers: 1,
+		Backoff: time.Second,
+	}
+}
+
+// Get makes a request
+// and returns the body of the answer.
+// The request is appended
+// to the base URL of the client.
+//
+// The answer is stored in the cache,
+// so if the answer can not be decoded,
+// it must be removed from the cache
+// with Remove.
+func (c *Client) Get(req string) ([]byte, error) {
+	return c.get(req, false)
+}
+
+// GetList is like Get,
+// but for the requests of a list,
+// or a search,
+// for example,
+// a page of a paginated list.
+// As the elements of a list can change,
+// and the pages of a list
+// must be consistent,
+// the cached answer is only used
+// in offline mode.
+func (c *Client) GetList(req string) ([]byte, error) {
+	return c.get(req, true)
+}
+
+func (c *Client) get(req string, list bool) ([]byte, error) {
+	u := c.Base + req
+	if !list || Offline {
+		if b, ok := c.cached(u); ok {
+			return b, nil
+		}
+	}
+	if Offline {
+		return nil, errors.Wrap(ErrNotCached, u)
+	}
+	c.once.Do(c.init)
+
+	var err error
+	wait := c.Backoff
+	for r := 0; r < c.Retry; r++ {
+		if r > 0 {
+			time.Sleep(wait)
+			wait *= 2
+		}
+		var b []byte
+		var after time.Duration
+		b, after, err = c.fetch(u)
+		if err == nil {
+			c.store(u, b)
+			return b, nil
+		}
+		if se, ok := err.(*StatusError); ok && !se.Temporary() {
+			return nil, err
+		}
+		if err == ErrNotFound {
+			return nil, err
+		}
+		if after > wait {
+			wait = after
+		}
+	}
+	if err == nil {
+		return nil, errors.Errorf("%s: no answer after %d retries", u, c.Retry)
+	}
+	return nil, err
+}
+
+// GetJSON makes a request,
+// and decodes the JSON answer into v.
+func (c *Client) GetJSON(req string, v interface{}) error {
+	return c.getJSON(req, false, v)
+}
+
+// GetListJSON makes a request of a list
+// (see GetList),
+// and decodes the JSON answer into v.
+func (c *Client) GetListJSON(req string, v interface{}) error {
+	return c.getJSON(req, true, v)
+}
+
+func (c *Client) getJSON(req string, list bool, v interface{}) error {
+	b, err := c.get(req, list)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		// do not keep a bad answer
+		c.Remove(req)
+		return errors.Wrap(err, c.Base+req)
+	}
+	return nil
+}
+
+// Init initializes the client.
+func (c *Client) init() {
+	c.client = &http.Client{Timeout: c.Timeout}
+	w := c.Workers
+	if w < 1 {
+		w = 1
+	}
+	c.workers = make(chan struct{}, w)
+}
+
+// Fetch makes the network request.
+// If the server asks for a waiting time
+// before a retry,
+// it is also returned.
+func (c *Client) fetch(u string) ([]byte, time.Duration, error) {
+	c.workers <- struct{}{}
+	defer func() { <-c.workers }()
+	time.Sleep(c.bucket.take(c.Wait, c.Burst))
+
+	a, err := c.client.Get(u)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer a.Body.Close()
+	b, err := ioutil.ReadAll(a.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	switch {
+	case a.StatusCode == http.StatusNotFound:
+		return nil, 0, ErrNotFound
+	case a.StatusCode != http.StatusOK:
+		var after time.Duration
+		if s, err := strconv.Atoi(a.Header.Get("Retry-After")); err == nil {
+			after = time.Duration(s) * time.Second
+		}
+		return nil, after, &StatusError{u, a.StatusCode, a.Status}
+	}
+	return b, 0, nil
+}
@@ -0,0 +1,248 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package web
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// NewTestServer returns a server
+// that fails the first request to /busy,
+// and counts the requests.
+func newTestServer(count map[string]int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count[r.URL.Path]++
+		switch r.URL.Path {
+		case "/busy":
+			if count[r.URL.Path] == 1 {
+				http.Error(w, "busy", http.StatusServiceUnavailable)
+				return
+			}
+		case "/bad":
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		case "/html":
+			w.Write([]byte("<html>busy</html>"))
+			return
+		case "/json", "/ok":
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"path":"` + r.URL.Path + `"}`))
+	}))
+}
+
+func newTestClient(t *testing.T, srv *httptest.Server) (*Client, func()) {
+	dir, err := ioutil.TempDir("", "biodv-web")
+	if err != nil {
+		t.Fatalf("unable to create temporal dir: %v", err)
+	}
+	cache, ttl, offline := CacheDir, TTL, Offline
+	CacheDir = dir
+	TTL = time.Hour
+	Offline = false
+
+	c := New("test", srv.URL+"/")
+	c.Wait = 0
+	c.Backoff = time.Millisecond
+	return c, func() {
+		os.RemoveAll(dir)
+		CacheDir, TTL, Offline = cache, ttl, offline
+	}
+}
+
+func TestGet(t *testing.T) {
+	count := make(map[string]int)
+	srv := newTestServer(count)
+	defer srv.Close()
+	c, clean := newTestClient(t, srv)
+	defer clean()
+
+	b, err := c.Get("busy")
+	if err != nil {
+		t.Fatalf("busy: unexpected error: %v", err)
+	}
+	if string(b) != `{"path":"/busy"}` {
+		t.Errorf("busy: answer %q", b)
+	}
+	if count["/busy"] != 2 {
+		t.Errorf("busy: %d requests, want %d", count["/busy"], 2)
+	}
+
+	if _, err := c.Get("missing"); err != ErrNotFound {
+		t.Errorf("missing: error %v, want %v", err, ErrNotFound)
+	}
+	if count["/missing"] != 1 {
+		t.Errorf("missing: %d requests, want %d", count["/missing"], 1)
+	}
+
+	if _, err := c.Get("bad"); err == nil {
+		t.Errorf("bad: expecting error")
+	}
+	if count["/bad"] != 1 {
+		t.Errorf("bad: %d requests, want %d", count["/bad"], 1)
+	}
+
+	var v struct {
+		Path string
+	}
+	if err := c.GetJSON("json", &v); err != nil {
+		t.Fatalf("json: unexpected error: %v", err)
+	}
+	if v.Path != "/json" {
+		t.Errorf("json: path %q, want %q", v.Path, "/json")
+	}
+
+	// bad answers are not kept
+	if err := c.GetJSON("html", &v); err == nil {
+		t.Errorf("html: expecting error")
+	}
+	if _, ok := c.cached(c.Base + "html"); ok {
+		t.Errorf("html: bad answer in cache")
+	}
+}
+
+func TestCache(t *testing.T) {
+	count := make(map[string]int)
+	srv := newTestServer(count)
+	defer srv.Close()
+	c, clean := newTestClient(t, srv)
+	defer clean()
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.Get("ok"); err != nil {
+			t.Fatalf("ok: unexpected error: %v", err)
+		}
+	}
+	if count["/ok"] != 1 {
+		t.Errorf("ok: %d requests, want %d", count["/ok"], 1)
+	}
+
+	// expired cache
+	TTL = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	if _, err := c.Get("ok"); err != nil {
+		t.Fatalf("ok: unexpected error: %v", err)
+	}
+	if count["/ok"] != 2 {
+		t.Errorf("ok: %d requests, want %d", count["/ok"], 2)
+	}
+
+	// in offline mode
+	// expired answers are used.
+	Offline = true
+	b, err := c.Get("ok")
+	if err != nil {
+		t.Fatalf("offline: unexpected error: %v", err)
+	}
+	if string(b) != `{"path":"/ok"}` {
+		t.Errorf("offline: answer %q", b)
+	}
+	if _, err := c.Get("json"); err == nil {
+		t.Errorf("offline: expecting error")
+	}
+	if count["/ok"] != 2 || count["/json"] != 0 {
+		t.Errorf("offline: unexpected requests: %v", count)
+	}
+}
+
+func TestGetList(t *testing.T) {
+	count := make(map[string]int)
+	srv := newTestServer(count)
+	defer srv.Close()
+	c, clean := newTestClient(t, srv)
+	defer clean()
+
+	// lists are always requested
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetList("ok"); err != nil {
+			t.Fatalf("ok: unexpected error: %v", err)
+		}
+	}
+	if count["/ok"] != 3 {
+		t.Errorf("ok: %d requests, want %d", count["/ok"], 3)
+	}
+
+	// but answered from the cache
+	// in offline mode.
+	Offline = true
+	b, err := c.GetList("ok")
+	if err != nil {
+		t.Fatalf("offline: unexpected error: %v", err)
+	}
+	if string(b) != `{"path":"/ok"}` {
+		t.Errorf("offline: answer %q", b)
+	}
+	if count["/ok"] != 3 {
+		t.Errorf("offline: %d requests, want %d", count["/ok"], 3)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	count := make(map[string]int)
+	srv := newTestServer(count)
+	defer srv.Close()
+	c, clean := newTestClient(t, srv)
+	defer clean()
+
+	if _, err := c.Get("ok"); err != nil {
+		t.Fatalf("ok: unexpected error: %v", err)
+	}
+	if _, err := c.Get("json"); err != nil {
+		t.Fatalf("json: unexpected error: %v", err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(c.path(c.Base+"ok"), old, old); err != nil {
+		t.Fatalf("unable to change file time: %v", err)
+	}
+
+	n, err := Prune(time.Hour)
+	if err != nil {
+		t.Fatalf("prune: unexpected error: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("prune: %d files removed, want %d", n, 1)
+	}
+	if _, ok := c.cached(c.Base + "ok"); ok {
+		t.Errorf("prune: expired answer in cache")
+	}
+	if _, ok := c.cached(c.Base + "json"); !ok {
+		t.Errorf("prune: answer not in cache")
+	}
+
+	if n, err := Prune(0); err != nil || n != 1 {
+		t.Errorf("prune all: %d files removed, error %v, want %d", n, err, 1)
+	}
+}
+
+func TestBucket(t *testing.T) {
+	var b bucket
+	every := time.Hour
+
+	// the first burst requests do not wait
+	for i := 0; i < 3; i++ {
+		if w := b.take(every, 3); w != 0 {
+			t.Errorf("request %d: wait %v, want 0", i, w)
+		}
+	}
+	for i := 1; i <= 2; i++ {
+		w := b.take(every, 3)
+		if w < time.Duration(i)*every-time.Minute || w > time.Duration(i)*every {
+			t.Errorf("request %d: wait %v, want %v", i+2, w, time.Duration(i)*every)
+		}
+	}
+
+	if w := b.take(0, 3); w != 0 {
+		t.Errorf("no limit: wait %v, want 0", w)
+	}
+}
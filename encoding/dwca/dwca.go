@@ -0,0 +1,319 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package dwca reads and writes
+// Darwin Core Archives.
+//
+// A Darwin Core Archive
+// (<https://dwc.tdwg.org/text/>)
+// is a set of delimited text files,
+// usually compressed into a single zip file,
+// with a core data file,
+// and optionally,
+// one or more extension files
+// that are linked to the core by an ID.
+// The structure of the archive
+// is described in a meta.xml file,
+// and the metadata of the dataset
+// is stored in an EML file.
+//
+// If the archive does not contain a meta.xml file,
+// the first text file of the archive
+// will be used as the core,
+// and the first line of the file
+// will be read as the name of the columns.
+package dwca
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// IDKey is the key used to store
+// the ID of a row
+// (i.e. the core ID).
+const IDKey = "id"
+
+// An Archive is a Darwin Core Archive
+// open for reading.
+type Archive struct {
+	// Core is the core data file of the archive.
+	Core *Table
+
+	// Extensions are the extension files
+	// of the archive.
+	Extensions []*Table
+
+	// Metadata is the name of the
+	// metadata file.
+	// It can be empty.
+	Metadata string
+
+	dir string
+	zr  *zip.ReadCloser
+}
+
+// A Table is the description of a data file
+// in the archive.
+type Table struct {
+	RowType   string // the row type, e.g. 'http://rs.tdwg.org/dwc/terms/Occurrence'
+	Files     []string
+	Delim     string  // fields separator
+	Enclosure string  // fields enclosure, it can be empty
+	Header    int     // number of lines to ignore
+	ID        int     // index of the ID column, -1 if not defined
+	Fields    []Field // fields of the table
+}
+
+// A Field is a column of a data file.
+type Field struct {
+	Index   int    // index of the column, -1 if is a constant
+	Term    string // the full term
+	Default string // default value of the field
+}
+
+// Name returns the short name of the term
+// of a field.
+func (f Field) Name() string {
+	return TermName(f.Term)
+}
+
+// Name returns the short name
+// of the row type of a table,
+// e.g. 'Occurrence'.
+func (t *Table) Name() string {
+	return TermName(t.RowType)
+}
+
+// TermName returns the short name of a term,
+// i.e. the last element of a term URI.
+func TermName(term string) string {
+	term = strings.TrimSpace(term)
+	if i := strings.LastIndexAny(term, "/#"); i >= 0 {
+		return term[i+1:]
+	}
+	return term
+}
+
+// Open opens a Darwin Core Archive
+// from a zip file,
+// or from a directory
+// that contains the unzipped archive.
+func Open(name string) (*Archive, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "dwca: open")
+	}
+	a := &Archive{}
+	if fi.IsDir() {
+		a.dir = name
+	} else {
+		zr, err := zip.OpenReader(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "dwca: open %s", name)
+		}
+		a.zr = zr
+	}
+
+	r, err := a.open("meta.xml")
+	if err != nil {
+		if err := a.noMeta(); err != nil {
+			a.Close()
+			return nil, errors.Wrapf(err, "dwca: open %s", name)
+		}
+		return a, nil
+	}
+	defer r.Close()
+	if err := a.readMeta(r); err != nil {
+		a.Close()
+		return nil, errors.Wrapf(err, "dwca: open %s", name)
+	}
+	return a, nil
+}
+
+// Close closes the archive.
+func (a *Archive) Close() error {
+	if a.zr == nil {
+		return nil
+	}
+	err := a.zr.Close()
+	a.zr = nil
+	return err
+}
+
+// Extension returns the extension
+// with a given row type.
+// The row type can be given as a short name
+// (e.g. 'Taxon').
+// It returns nil if the extension is not found.
+func (a *Archive) Extension(rowType string) *Table {
+	for _, e := range a.Extensions {
+		if e.RowType == rowType || strings.EqualFold(e.Name(), rowType) {
+			return e
+		}
+	}
+	return nil
+}
+
+// Open opens a file from the archive.
+func (a *Archive) open(name string) (io.ReadCloser, error) {
+	if a.zr == nil {
+		return os.Open(filepath.Join(a.dir, filepath.FromSlash(name)))
+	}
+	for _, f := range a.zr.File {
+		if f.Name == name || strings.TrimPrefix(f.Name, "./") == name {
+			return f.Open()
+		}
+	}
+	// some archives are zipped with an enclosing directory
+	for _, f := range a.zr.File {
+		if path.Base(f.Name) == name {
+			return f.Open()
+		}
+	}
+	return nil, errors.Errorf("file %q not found", name)
+}
+
+// Files returns the name of the files
+// in the archive.
+func (a *Archive) files() []string {
+	var ls []string
+	if a.zr != nil {
+		for _, f := range a.zr.File {
+			if strings.HasSuffix(f.Name, "/") {
+				continue
+			}
+			ls = append(ls, f.Name)
+		}
+		return ls
+	}
+	fs, _ := ioutil.ReadDir(a.dir)
+	for _, f := range fs {
+		if f.IsDir() {
+			continue
+		}
+		ls = append(ls, f.Name())
+	}
+	return ls
+}
+
+// NoMeta sets the core of an archive
+// without a meta file.
+func (a *Archive) noMeta() error {
+	for _, f := range a.files() {
+		ext := strings.ToLower(path.Ext(f))
+		delim := "\t"
+		switch ext {
+		case ".txt", ".tsv", ".tab":
+		case ".csv":
+			delim = ","
+		default:
+			continue
+		}
+		a.Core = &Table{
+			Files:     []string{f},
+			Delim:     delim,
+			Enclosure: `"`,
+			Header:    1,
+			ID:        -1,
+		}
+		return nil
+	}
+	return errors.New("core data file not found")
+}
+
+// Meta is the xml structure
+// of a meta.xml file.
+type meta struct {
+	Metadata   string      `xml:"metadata,attr"`
+	Core       *metaTable  `xml:"core"`
+	Extensions []metaTable `xml:"extension"`
+}
+
+type metaTable struct {
+	RowType   string   `xml:"rowType,attr"`
+	Delim     string   `xml:"fieldsTerminatedBy,attr"`
+	Enclosure string   `xml:"fieldsEnclosedBy,attr"`
+	Header    int      `xml:"ignoreHeaderLines,attr"`
+	Files     []string `xml:"files>location"`
+	ID        *struct {
+		Index int `xml:"index,attr"`
+	} `xml:"id"`
+	CoreID *struct {
+		Index int `xml:"index,attr"`
+	} `xml:"coreid"`
+	Fields []struct {
+		Index   *int   `xml:"index,attr"`
+		Term    string `xml:"term,attr"`
+		Default string `xml:"default,attr"`
+	} `xml:"field"`
+}
+
+// ReadMeta reads the content of a meta.xml file.
+func (a *Archive) readMeta(r io.Reader) error {
+	m := &meta{}
+	if err := xml.NewDecoder(r).Decode(m); err != nil {
+		return errors.Wrap(err, "while reading meta.xml")
+	}
+	if m.Core == nil {
+		return errors.New("meta.xml without core")
+	}
+	a.Metadata = m.Metadata
+	a.Core = m.Core.table()
+	for _, e := range m.Extensions {
+		a.Extensions = append(a.Extensions, e.table())
+	}
+	return nil
+}
+
+func (mt metaTable) table() *Table {
+	t := &Table{
+		RowType:   strings.TrimSpace(mt.RowType),
+		Delim:     unescape(mt.Delim),
+		Enclosure: unescape(mt.Enclosure),
+		Header:    mt.Header,
+		ID:        -1,
+	}
+	if t.Delim == "" {
+		t.Delim = ","
+	}
+	for _, f := range mt.Files {
+		t.Files = append(t.Files, strings.TrimSpace(f))
+	}
+	if mt.ID != nil {
+		t.ID = mt.ID.Index
+	}
+	if mt.CoreID != nil {
+		t.ID = mt.CoreID.Index
+	}
+	for _, f := range mt.Fields {
+		fd := Field{
+			Index:   -1,
+			Term:    strings.TrimSpace(f.Term),
+			Default: f.Default,
+		}
+		if f.Index != nil {
+			fd.Index = *f.Index
+		}
+		t.Fields = append(t.Fields, fd)
+	}
+	return t
+}
+
+// Unescape replaces the escape sequences
+// used in meta.xml attributes.
+func unescape(s string) string {
+	r := strings.NewReplacer(`\t`, "\t", `\n`, "\n", `\r`, "\r", `\"`, `"`, `\'`, "'")
+	return r.Replace(s)
+}
@@ -0,0 +1,257 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package dwca
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var metaBlob = `<?xml version="1.0" encoding="UTF-8"?>
+<archive xmlns="http://rs.tdwg.org/dwc/text/" metadata="eml.xml">
+  <core encoding="UTF-8" fieldsTerminatedBy="\t" linesTerminatedBy="\n" fieldsEnclosedBy="" ignoreHeaderLines="1" rowType="http://rs.tdwg.org/dwc/terms/Occurrence">
+    <files>
+      <location>occurrence.txt</location>
+    </files>
+    <id index="0" />
+    <field index="1" term="http://rs.tdwg.org/dwc/terms/occurrenceID"/>
+    <field index="2" term="http://rs.tdwg.org/dwc/terms/scientificName"/>
+    <field index="3" term="http://rs.tdwg.org/dwc/terms/decimalLatitude"/>
+    <field index="4" term="http://rs.tdwg.org/dwc/terms/decimalLongitude"/>
+    <field term="http://rs.tdwg.org/dwc/terms/basisOfRecord" default="PreservedSpecimen"/>
+  </core>
+</archive>
+`
+
+var occBlob = "id\toccurrenceID\tscientificName\tdecimalLatitude\tdecimalLongitude\n" +
+	"1\tMLP:1\tRhea americana\t-34.9\t-57.95\n" +
+	"2\tMLP:2\tRhea americana (Linnaeus, 1758)\t\t\n" +
+	"\n" +
+	"3\tMLP:3\t\"Pterocnemia\" pennata\t-41.1\t-71.3\n"
+
+var emlBlob = `<?xml version="1.0" encoding="utf-8"?>
+<eml:eml xmlns:eml="eml://ecoinformatics.org/eml-2.1.1">
+  <dataset>
+    <alternateIdentifier>mlp-birds</alternateIdentifier>
+    <title xml:lang="en">MLP   Bird collection</title>
+    <creator><organizationName>Museo de La Plata</organizationName></creator>
+    <abstract><para>Bird specimens</para><para>of the MLP.</para></abstract>
+    <intellectualRights>
+      <para>This work is licensed under a <ulink url="http://creativecommons.org/licenses/by/4.0/legalcode"><citetitle>CC-BY 4.0</citetitle></ulink>.</para>
+    </intellectualRights>
+  </dataset>
+</eml:eml>
+`
+
+func TestArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dwca")
+	if err != nil {
+		t.Fatalf("unable to create temporal directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "test.zip")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatalf("unable to create file: %v", err)
+	}
+	zw := zip.NewWriter(f)
+	for _, fl := range []struct {
+		name, data string
+	}{
+		{"meta.xml", metaBlob},
+		{"occurrence.txt", occBlob},
+		{"eml.xml", emlBlob},
+	} {
+		w, err := zw.Create(fl.name)
+		if err != nil {
+			t.Fatalf("unable to create %s: %v", fl.name, err)
+		}
+		w.Write([]byte(fl.data))
+	}
+	zw.Close()
+	f.Close()
+
+	a, err := Open(name)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer a.Close()
+
+	if a.Core.Name() != "Occurrence" {
+		t.Errorf("core %q, want %q", a.Core.Name(), "Occurrence")
+	}
+	sc := a.Scanner(a.Core)
+	var rows []map[string]string
+	for sc.Scan() {
+		rows = append(rows, sc.Record())
+	}
+	if err := sc.Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("read %d rows, want %d", len(rows), 3)
+	}
+	if rows[0][IDKey] != "1" {
+		t.Errorf("row ID %q, want %q", rows[0][IDKey], "1")
+	}
+	if rows[1]["scientificName"] != "Rhea americana (Linnaeus, 1758)" {
+		t.Errorf("row name %q, want %q", rows[1]["scientificName"], "Rhea americana (Linnaeus, 1758)")
+	}
+	if _, ok := rows[1]["decimalLatitude"]; ok {
+		t.Errorf("empty value stored")
+	}
+	if rows[2]["scientificName"] != `"Pterocnemia" pennata` {
+		t.Errorf("row name %q, want %q", rows[2]["scientificName"], `"Pterocnemia" pennata`)
+	}
+	if rows[2]["basisOfRecord"] != "PreservedSpecimen" {
+		t.Errorf("default value %q, want %q", rows[2]["basisOfRecord"], "PreservedSpecimen")
+	}
+
+	set, err := a.Dataset()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if set.Title != "MLP Bird collection" {
+		t.Errorf("title %q, want %q", set.Title, "MLP Bird collection")
+	}
+	if set.ID != "mlp-birds" {
+		t.Errorf("id %q, want %q", set.ID, "mlp-birds")
+	}
+	if set.Publisher != "Museo de La Plata" {
+		t.Errorf("publisher %q, want %q", set.Publisher, "Museo de La Plata")
+	}
+	if set.Abstract != "Bird specimens\nof the MLP." {
+		t.Errorf("abstract %q, want %q", set.Abstract, "Bird specimens\nof the MLP.")
+	}
+	lic := "CC-BY 4.0 <http://creativecommons.org/licenses/by/4.0/legalcode>"
+	if set.License != lic {
+		t.Errorf("license %q, want %q", set.License, lic)
+	}
+}
+
+func TestNoMeta(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dwca")
+	if err != nil {
+		t.Fatalf("unable to create temporal directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	blob := "gbifID,scientificName,countryCode\n" +
+		"919431660,\"Puma concolor (Linnaeus, 1771)\",EC\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "occurrence.csv"), []byte(blob), 0644); err != nil {
+		t.Fatalf("unable to write file: %v", err)
+	}
+
+	a, err := Open(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer a.Close()
+	sc := a.Scanner(a.Core)
+	var rows []map[string]string
+	for sc.Scan() {
+		rows = append(rows, sc.Record())
+	}
+	if err := sc.Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("read %d rows, want %d", len(rows), 1)
+	}
+	if rows[0]["scientificName"] != "Puma concolor (Linnaeus, 1771)" {
+		t.Errorf("row name %q, want %q", rows[0]["scientificName"], "Puma concolor (Linnaeus, 1771)")
+	}
+	if rows[0]["countryCode"] != "EC" {
+		t.Errorf("row country %q, want %q", rows[0]["countryCode"], "EC")
+	}
+}
+
+func TestWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dwca")
+	if err != nil {
+		t.Fatalf("unable to create temporal directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "test.zip")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatalf("unable to create file: %v", err)
+	}
+	w := NewWriter(f)
+	occ, err := w.Create("occurrence.txt", "http://rs.tdwg.org/dwc/terms/Occurrence", []string{
+		"http://rs.tdwg.org/dwc/terms/occurrenceID",
+		"http://rs.tdwg.org/dwc/terms/scientificName",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := occ.Write(map[string]string{IDKey: "1", "occurrenceID": "1", "scientificName": "Rhea americana"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	tax, err := w.Create("taxon.txt", "http://rs.tdwg.org/dwc/terms/Taxon", []string{
+		"http://rs.tdwg.org/dwc/terms/taxonRank",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := tax.Write(map[string]string{IDKey: "1", "taxonRank": "species"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	set := &Dataset{Title: "Ratites", Abstract: "Ratite birds", Publisher: "Museo de La Plata"}
+	src := []*Dataset{{Title: "MLP Bird collection", License: "CC-BY 4.0"}}
+	if err := w.WriteEML(set, src); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	f.Close()
+
+	a, err := Open(name)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer a.Close()
+	sc := a.Scanner(a.Core)
+	var rows []map[string]string
+	for sc.Scan() {
+		rows = append(rows, sc.Record())
+	}
+	if err := sc.Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(rows) != 1 || rows[0]["scientificName"] != "Rhea americana" || rows[0][IDKey] != "1" {
+		t.Errorf("rows %v, want one row of %q", rows, "Rhea americana")
+	}
+	ext := a.Extension("Taxon")
+	if ext == nil {
+		t.Fatalf("taxon extension not found")
+	}
+	sc = a.Scanner(ext)
+	rows = nil
+	for sc.Scan() {
+		rows = append(rows, sc.Record())
+	}
+	if len(rows) != 1 || rows[0]["taxonRank"] != "species" || rows[0][IDKey] != "1" {
+		t.Errorf("rows %v, want one row with rank %q", rows, "species")
+	}
+	md, err := a.Dataset()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if md.Title != set.Title {
+		t.Errorf("title %q, want %q", md.Title, set.Title)
+	}
+	if md.Publisher != set.Publisher {
+		t.Errorf("publisher %q, want %q", md.Publisher, set.Publisher)
+	}
+}
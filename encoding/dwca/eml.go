@@ -0,0 +1,124 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package dwca
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Dataset is the metadata of a dataset
+// as described in an EML file.
+type Dataset struct {
+	ID        string // an alternate identifier of the dataset
+	Title     string
+	Abstract  string
+	License   string
+	URL       string
+	Publisher string
+}
+
+// Eml is the xml structure
+// of an EML file.
+type eml struct {
+	Dataset struct {
+		ID        []string `xml:"alternateIdentifier"`
+		Title     []string `xml:"title"`
+		Publisher string   `xml:"publisher>organizationName"`
+		Creator   string   `xml:"creator>organizationName"`
+		Abstract  struct {
+			Para []string `xml:"para"`
+		} `xml:"abstract"`
+		Rights struct {
+			Para []struct {
+				Text string `xml:",chardata"`
+				Link struct {
+					URL   string `xml:"url,attr"`
+					Title string `xml:"citetitle"`
+				} `xml:"ulink"`
+			} `xml:"para"`
+		} `xml:"intellectualRights"`
+		Online []struct {
+			Function string `xml:"function,attr"`
+			URL      string `xml:",chardata"`
+		} `xml:"distribution>online>url"`
+	} `xml:"dataset"`
+}
+
+// ReadEML reads the dataset metadata
+// from an EML file.
+func ReadEML(r io.Reader) (*Dataset, error) {
+	e := &eml{}
+	if err := xml.NewDecoder(r).Decode(e); err != nil {
+		return nil, errors.Wrap(err, "dwca: eml")
+	}
+	set := &Dataset{
+		Publisher: clean(e.Dataset.Publisher),
+	}
+	if len(e.Dataset.ID) > 0 {
+		set.ID = clean(e.Dataset.ID[0])
+	}
+	if len(e.Dataset.Title) > 0 {
+		set.Title = clean(e.Dataset.Title[0])
+	}
+	if set.Publisher == "" {
+		set.Publisher = clean(e.Dataset.Creator)
+	}
+	var abs []string
+	for _, p := range e.Dataset.Abstract.Para {
+		if p = clean(p); p != "" {
+			abs = append(abs, p)
+		}
+	}
+	set.Abstract = strings.Join(abs, "\n")
+	for _, p := range e.Dataset.Rights.Para {
+		if l := clean(p.Link.Title); l != "" {
+			set.License = l
+			if p.Link.URL != "" {
+				set.License += " <" + strings.TrimSpace(p.Link.URL) + ">"
+			}
+			break
+		}
+		if l := clean(p.Text); l != "" {
+			set.License = l
+			break
+		}
+	}
+	for _, u := range e.Dataset.Online {
+		if u.Function == "" || u.Function == "information" {
+			set.URL = strings.TrimSpace(u.URL)
+			break
+		}
+	}
+	return set, nil
+}
+
+// Clean removes extra spaces from a string.
+func clean(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Dataset returns the dataset metadata
+// stored in the archive.
+// If the archive does not have
+// a metadata file,
+// it returns nil.
+func (a *Archive) Dataset() (*Dataset, error) {
+	name := a.Metadata
+	if name == "" {
+		name = "eml.xml"
+	}
+	r, err := a.open(name)
+	if err != nil {
+		return nil, nil
+	}
+	defer r.Close()
+	return ReadEML(r)
+}
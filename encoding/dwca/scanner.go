@@ -0,0 +1,220 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package dwca
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// A Scanner reads rows
+// from a data file of an archive.
+type Scanner struct {
+	a     *Archive
+	t     *Table
+	files []string
+	f     io.ReadCloser
+	next  func() ([]string, error)
+	head  []Field
+	line  int
+	row   map[string]string
+	err   error
+}
+
+// Scanner returns a scanner
+// that reads the rows of a table
+// of the archive.
+func (a *Archive) Scanner(t *Table) *Scanner {
+	if t == nil {
+		return &Scanner{err: errors.New("dwca: scanner: undefined table")}
+	}
+	return &Scanner{
+		a:     a,
+		t:     t,
+		files: t.Files,
+	}
+}
+
+// Close closes the scanner,
+// preventing further enumeration.
+//
+// If Scan returns false,
+// the scanner is closed automatically
+// and it will suffice to check the result of Err.
+func (sc *Scanner) Close() {
+	if sc.err == io.EOF {
+		return
+	}
+	if sc.f != nil {
+		sc.f.Close()
+		sc.f = nil
+	}
+	sc.err = io.EOF
+}
+
+// Err returns the error,
+// if any,
+// that was encountered during iteration.
+func (sc *Scanner) Err() error {
+	if sc.err == io.EOF {
+		return nil
+	}
+	return sc.err
+}
+
+// Record returns the last read row,
+// as a map of the short name
+// of each term and its value.
+// The ID of the row
+// is stored with the IDKey.
+//
+// Every call to Record must be preceded
+// by a call to Scan.
+func (sc *Scanner) Record() map[string]string {
+	if sc.err != nil {
+		panic("dwca: scanner: accessing a closed scanner")
+	}
+	if sc.row == nil {
+		panic("dwca: scanner: calling Record without a Scan call")
+	}
+	row := sc.row
+	sc.row = nil
+	return row
+}
+
+// Scan advances the scanner to the next row.
+// It returns false when there is no more rows,
+// or an error happens when preparing it.
+// Err should be consulted to distinguish
+// between the two cases.
+//
+// Every call to Record,
+// even the first one,
+// must be preceded by a call to Scan.
+func (sc *Scanner) Scan() bool {
+	if sc.err != nil {
+		return false
+	}
+	for {
+		if sc.f == nil {
+			if len(sc.files) == 0 {
+				sc.Close()
+				return false
+			}
+			if err := sc.openFile(sc.files[0]); err != nil {
+				sc.Close()
+				sc.err = errors.Wrap(err, "dwca: scanner")
+				return false
+			}
+			sc.files = sc.files[1:]
+		}
+		v, err := sc.next()
+		if err == io.EOF {
+			sc.f.Close()
+			sc.f = nil
+			continue
+		}
+		sc.line++
+		if err != nil {
+			sc.Close()
+			sc.err = errors.Wrapf(err, "dwca: scanner: on line %d", sc.line)
+			return false
+		}
+		if sc.line <= sc.t.Header {
+			if sc.line == 1 && len(sc.head) == 0 && len(sc.t.Fields) == 0 {
+				sc.setHeader(v)
+			}
+			continue
+		}
+		if len(v) == 1 && strings.TrimSpace(v[0]) == "" {
+			continue
+		}
+		sc.row = sc.makeRow(v)
+		return true
+	}
+}
+
+// OpenFile opens a data file.
+func (sc *Scanner) openFile(name string) error {
+	f, err := sc.a.open(name)
+	if err != nil {
+		return err
+	}
+	sc.f = f
+	sc.line = 0
+	if sc.t.Enclosure != "" {
+		r := csv.NewReader(f)
+		r.Comma = []rune(sc.t.Delim)[0]
+		r.FieldsPerRecord = -1
+		r.LazyQuotes = true
+		sc.next = r.Read
+		return nil
+	}
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	delim := sc.t.Delim
+	sc.next = func() ([]string, error) {
+		if !s.Scan() {
+			if err := s.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		ln := strings.TrimSuffix(s.Text(), "\r")
+		return strings.Split(ln, delim), nil
+	}
+	return nil
+}
+
+// SetHeader sets the fields
+// using the header line
+// of a table without field definitions.
+func (sc *Scanner) setHeader(v []string) {
+	for i, h := range v {
+		h = strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))
+		if h == "" {
+			continue
+		}
+		if strings.EqualFold(h, IDKey) && sc.t.ID < 0 {
+			sc.t.ID = i
+			continue
+		}
+		sc.head = append(sc.head, Field{Index: i, Term: h})
+	}
+}
+
+// MakeRow returns a map
+// with the values of a row.
+func (sc *Scanner) makeRow(v []string) map[string]string {
+	row := make(map[string]string)
+	fields := sc.t.Fields
+	if len(fields) == 0 {
+		fields = sc.head
+	}
+	for _, f := range fields {
+		val := f.Default
+		if f.Index >= 0 && f.Index < len(v) {
+			if s := strings.TrimSpace(v[f.Index]); s != "" {
+				val = s
+			}
+		}
+		if val == "" {
+			continue
+		}
+		row[f.Name()] = val
+	}
+	if sc.t.ID >= 0 && sc.t.ID < len(v) {
+		if id := strings.TrimSpace(v[sc.t.ID]); id != "" {
+			row[IDKey] = id
+		}
+	}
+	return row
+}
@@ -0,0 +1,326 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package dwca
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// A Writer writes a Darwin Core Archive
+// as a zip file.
+//
+// The first table created in the archive
+// is the core,
+// and the others are extensions.
+// Files are tab delimited,
+// without field enclosure,
+// and with a header line.
+type Writer struct {
+	zw     *zip.Writer
+	tables []*TableWriter
+	eml    bool
+	closed bool
+}
+
+// A TableWriter writes rows
+// into a data file of an archive.
+type TableWriter struct {
+	t    *Table
+	w    *bufio.Writer
+	file string
+}
+
+// NewWriter returns a new Writer
+// that writes an archive to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{zw: zip.NewWriter(w)}
+}
+
+// Create creates a new data file in the archive
+// with a given file name,
+// row type,
+// and a list of fields
+// (as full terms).
+// Any previous data file is closed.
+func (w *Writer) Create(file, rowType string, terms []string) (*TableWriter, error) {
+	if w.closed {
+		return nil, errors.New("dwca: writer: create: closed archive")
+	}
+	if err := w.flush(); err != nil {
+		return nil, errors.Wrap(err, "dwca: writer: create")
+	}
+	fw, err := w.zw.Create(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "dwca: writer: create")
+	}
+	t := &Table{
+		RowType:   rowType,
+		Files:     []string{file},
+		Delim:     "\t",
+		Enclosure: "",
+		Header:    1,
+		ID:        0,
+	}
+	head := []string{IDKey}
+	for i, tm := range terms {
+		t.Fields = append(t.Fields, Field{Index: i + 1, Term: tm})
+		head = append(head, TermName(tm))
+	}
+	tw := &TableWriter{t: t, w: bufio.NewWriter(fw), file: file}
+	w.tables = append(w.tables, tw)
+	if err := tw.writeLine(head); err != nil {
+		return nil, errors.Wrap(err, "dwca: writer: create")
+	}
+	return tw, nil
+}
+
+// Write writes a row.
+// The values are indexed by the short name
+// of the field terms,
+// and the ID of the row
+// (or the core ID in extensions)
+// is stored with the IDKey.
+func (tw *TableWriter) Write(row map[string]string) error {
+	v := []string{row[IDKey]}
+	for _, f := range tw.t.Fields {
+		v = append(v, row[f.Name()])
+	}
+	if err := tw.writeLine(v); err != nil {
+		return errors.Wrapf(err, "dwca: writer: %s", tw.file)
+	}
+	return nil
+}
+
+func (tw *TableWriter) writeLine(v []string) error {
+	r := strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+	for i, s := range v {
+		v[i] = r.Replace(s)
+	}
+	_, err := tw.w.WriteString(strings.Join(v, "\t") + "\n")
+	return err
+}
+
+// Flush writes any buffered data
+// of the last data file.
+func (w *Writer) flush() error {
+	if len(w.tables) == 0 {
+		return nil
+	}
+	return w.tables[len(w.tables)-1].w.Flush()
+}
+
+// WriteEML writes the metadata of the archive
+// in an EML file.
+// The sources are the datasets
+// from which the data was extracted.
+func (w *Writer) WriteEML(set *Dataset, sources []*Dataset) error {
+	if w.closed {
+		return errors.New("dwca: writer: eml: closed archive")
+	}
+	if err := w.flush(); err != nil {
+		return errors.Wrap(err, "dwca: writer: eml")
+	}
+	fw, err := w.zw.Create("eml.xml")
+	if err != nil {
+		return errors.Wrap(err, "dwca: writer: eml")
+	}
+
+	e := &emlOut{
+		NS:       "eml://ecoinformatics.org/eml-2.1.1",
+		Package:  set.ID,
+		System:   "biodv",
+		Lang:     "en",
+		Dataset:  emlDataset(set),
+		SchemaNS: "http://www.w3.org/2001/XMLSchema-instance",
+		Schema:   "eml://ecoinformatics.org/eml-2.1.1 http://rs.gbif.org/schema/eml-gbif-profile/1.1/eml.xsd",
+	}
+	if len(sources) > 0 {
+		m := &emlMethods{}
+		m.Step.Desc = "Data compiled from the following sources."
+		for _, s := range sources {
+			m.Step.Sources = append(m.Step.Sources, emlDataset(s))
+		}
+		e.Dataset.Methods = m
+	}
+
+	if _, err := io.WriteString(fw, xml.Header); err != nil {
+		return errors.Wrap(err, "dwca: writer: eml")
+	}
+	enc := xml.NewEncoder(fw)
+	enc.Indent("", "  ")
+	if err := enc.Encode(e); err != nil {
+		return errors.Wrap(err, "dwca: writer: eml")
+	}
+	w.eml = true
+	return nil
+}
+
+// Close writes the meta.xml file
+// and closes the archive.
+// It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if err := w.flush(); err != nil {
+		return errors.Wrap(err, "dwca: writer: close")
+	}
+	if len(w.tables) > 0 {
+		if err := w.writeMeta(); err != nil {
+			return errors.Wrap(err, "dwca: writer: close")
+		}
+	}
+	if err := w.zw.Close(); err != nil {
+		return errors.Wrap(err, "dwca: writer: close")
+	}
+	return nil
+}
+
+// WriteMeta writes the meta.xml file.
+func (w *Writer) writeMeta() error {
+	fw, err := w.zw.Create("meta.xml")
+	if err != nil {
+		return err
+	}
+	m := &metaOut{NS: "http://rs.tdwg.org/dwc/text/"}
+	if w.eml {
+		m.Metadata = "eml.xml"
+	}
+	for i, tw := range w.tables {
+		mt := metaTableOut{
+			RowType:   tw.t.RowType,
+			Encoding:  "UTF-8",
+			Delim:     `\t`,
+			Lines:     `\n`,
+			Enclosure: "",
+			Header:    tw.t.Header,
+			Files:     tw.t.Files,
+		}
+		for _, f := range tw.t.Fields {
+			mt.Fields = append(mt.Fields, metaFieldOut{Index: f.Index, Term: f.Term})
+		}
+		if i == 0 {
+			mt.ID = &metaIndex{Index: 0}
+			m.Core = mt
+			continue
+		}
+		mt.CoreID = &metaIndex{Index: 0}
+		m.Extensions = append(m.Extensions, mt)
+	}
+
+	if _, err := io.WriteString(fw, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(fw)
+	enc.Indent("", "  ")
+	return enc.Encode(m)
+}
+
+type metaOut struct {
+	XMLName    xml.Name       `xml:"archive"`
+	NS         string         `xml:"xmlns,attr"`
+	Metadata   string         `xml:"metadata,attr,omitempty"`
+	Core       metaTableOut   `xml:"core"`
+	Extensions []metaTableOut `xml:"extension"`
+}
+
+type metaTableOut struct {
+	Encoding  string         `xml:"encoding,attr"`
+	Delim     string         `xml:"fieldsTerminatedBy,attr"`
+	Lines     string         `xml:"linesTerminatedBy,attr"`
+	Enclosure string         `xml:"fieldsEnclosedBy,attr"`
+	Header    int            `xml:"ignoreHeaderLines,attr"`
+	RowType   string         `xml:"rowType,attr"`
+	Files     []string       `xml:"files>location"`
+	ID        *metaIndex     `xml:"id"`
+	CoreID    *metaIndex     `xml:"coreid"`
+	Fields    []metaFieldOut `xml:"field"`
+}
+
+type metaIndex struct {
+	Index int `xml:"index,attr"`
+}
+
+type metaFieldOut struct {
+	Index int    `xml:"index,attr"`
+	Term  string `xml:"term,attr"`
+}
+
+type emlOut struct {
+	XMLName  xml.Name       `xml:"eml:eml"`
+	NS       string         `xml:"xmlns:eml,attr"`
+	SchemaNS string         `xml:"xmlns:xsi,attr"`
+	Schema   string         `xml:"xsi:schemaLocation,attr"`
+	Package  string         `xml:"packageId,attr,omitempty"`
+	System   string         `xml:"system,attr"`
+	Lang     string         `xml:"xml:lang,attr"`
+	Dataset  *emlDatasetOut `xml:"dataset"`
+}
+
+type emlDatasetOut struct {
+	ID        string      `xml:"alternateIdentifier,omitempty"`
+	Title     string      `xml:"title"`
+	Creator   emlParty    `xml:"creator"`
+	Publisher *emlParty   `xml:"publisher,omitempty"`
+	Abstract  *emlPara    `xml:"abstract,omitempty"`
+	Rights    *emlPara    `xml:"intellectualRights,omitempty"`
+	Dist      *emlDist    `xml:"distribution,omitempty"`
+	Contact   emlParty    `xml:"contact"`
+	Methods   *emlMethods `xml:"methods,omitempty"`
+}
+
+type emlDist struct {
+	URL string `xml:"online>url"`
+}
+
+type emlParty struct {
+	Org string `xml:"organizationName"`
+}
+
+type emlPara struct {
+	Para []string `xml:"para"`
+}
+
+type emlMethods struct {
+	Step struct {
+		Desc    string           `xml:"description>para"`
+		Sources []*emlDatasetOut `xml:"dataSource"`
+	} `xml:"methodStep"`
+}
+
+func emlDataset(set *Dataset) *emlDatasetOut {
+	party := set.Publisher
+	if party == "" {
+		party = set.Title
+	}
+	d := &emlDatasetOut{
+		ID:      set.ID,
+		Title:   set.Title,
+		Creator: emlParty{party},
+		Contact: emlParty{party},
+	}
+	if set.URL != "" {
+		d.Dist = &emlDist{set.URL}
+	}
+	if set.Publisher != "" {
+		d.Publisher = &emlParty{set.Publisher}
+	}
+	if set.Abstract != "" {
+		d.Abstract = &emlPara{strings.Split(set.Abstract, "\n")}
+	}
+	if set.License != "" {
+		d.Rights = &emlPara{[]string{set.License}}
+	}
+	return d
+}
@@ -0,0 +1,500 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package geography
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// BordersFile is the default location
+// of the country borders file
+// in a biodv project.
+// If the file is not available,
+// the default world borders are used.
+var BordersFile = filepath.Join("geography", "borders.json")
+
+// BorderTolerance is the distance,
+// in meters,
+// from a country border
+// in which a point is still considered
+// as part of the country.
+// As borders are usually simplified,
+// it should be increased
+// when using very coarse borders.
+var BorderTolerance uint = 10000
+
+// A Point is a pair of coordinates
+// in a border.
+type point struct {
+	lat, lon float64
+}
+
+// A Polygon is a set of rings,
+// the first one is the outer ring,
+// the others are holes.
+type polygon [][]point
+
+// A Boundary is the set of polygons
+// of a country.
+type boundary struct {
+	code   string
+	polys  []polygon
+	center Position
+
+	// bounding box
+	minLat, maxLat float64
+	minLon, maxLon float64
+}
+
+var (
+	bordersMu  sync.RWMutex
+	borders    map[string]*boundary
+	borderList []*boundary // sorted by code
+
+	defaultOnce sync.Once
+)
+
+// DefaultBorders loads the default world borders,
+// if no other borders were loaded.
+func defaultBorders() {
+	defaultOnce.Do(func() {
+		bordersMu.RLock()
+		ok := len(borders) > 0
+		bordersMu.RUnlock()
+		if ok {
+			return
+		}
+		if err := LoadBorders(strings.NewReader(worldBorders)); err != nil {
+			panic(err)
+		}
+	})
+}
+
+// LoadBorders reads the country borders
+// from a GeoJSON feature collection,
+// in which each feature is a polygon
+// (or a multipolygon)
+// with the ISO 3166-1 alpha-2 code of the country
+// stored in the property 'ISO_A2_EH',
+// 'ISO_A2',
+// or 'iso_a2'
+// (as in Natural Earth datasets
+// <http://www.naturalearthdata.com/>).
+// Features without a valid code are ignored.
+//
+// Any previously loaded borders are replaced.
+// If no borders are loaded,
+// a simplified version of the Natural Earth
+// 1:10m admin 0 countries
+// is used when the borders are first queried.
+func LoadBorders(r io.Reader) error {
+	var fc struct {
+		Features []struct {
+			Properties map[string]interface{}
+			Geometry   struct {
+				Type        string
+				Coordinates json.RawMessage
+			}
+		}
+	}
+	if err := json.NewDecoder(r).Decode(&fc); err != nil {
+		return errors.Wrap(err, "geography: borders")
+	}
+
+	bds := make(map[string]*boundary)
+	for i, f := range fc.Features {
+		code := borderCode(f.Properties)
+		if code == "" {
+			continue
+		}
+		var polys [][][][]float64
+		switch f.Geometry.Type {
+		case "Polygon":
+			var p [][][]float64
+			if err := json.Unmarshal(f.Geometry.Coordinates, &p); err != nil {
+				return errors.Wrapf(err, "geography: borders: feature %d", i)
+			}
+			polys = append(polys, p)
+		case "MultiPolygon":
+			if err := json.Unmarshal(f.Geometry.Coordinates, &polys); err != nil {
+				return errors.Wrapf(err, "geography: borders: feature %d", i)
+			}
+		default:
+			continue
+		}
+
+		b, ok := bds[code]
+		if !ok {
+			b = &boundary{
+				code:   code,
+				minLat: MaxLat,
+				maxLat: MinLat,
+				minLon: MaxLon,
+				maxLon: MinLon,
+			}
+			bds[code] = b
+		}
+		for _, p := range polys {
+			b.add(p)
+		}
+	}
+	if len(bds) == 0 {
+		return errors.New("geography: borders: no country borders found")
+	}
+
+	ls := make([]*boundary, 0, len(bds))
+	for _, b := range bds {
+		b.center = b.centroid()
+		ls = append(ls, b)
+	}
+	sort.Slice(ls, func(i, j int) bool { return ls[i].code < ls[j].code })
+
+	bordersMu.Lock()
+	borders = bds
+	borderList = ls
+	bordersMu.Unlock()
+	return nil
+}
+
+// LoadBordersFile reads the country borders
+// from a GeoJSON file.
+func LoadBordersFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return errors.Wrap(err, "geography: borders")
+	}
+	defer f.Close()
+	return LoadBorders(f)
+}
+
+// HasBorders returns true
+// if the country borders are loaded.
+func HasBorders() bool {
+	defaultBorders()
+	bordersMu.RLock()
+	defer bordersMu.RUnlock()
+	return len(borders) > 0
+}
+
+// BorderCode returns the country code
+// from the properties of a border feature.
+func borderCode(prop map[string]interface{}) string {
+	for _, k := range []string{"ISO_A2_EH", "ISO_A2", "iso_a2"} {
+		v, ok := prop[k].(string)
+		if !ok {
+			continue
+		}
+		v = strings.ToUpper(v)
+		if IsValidCode(v) {
+			return v
+		}
+	}
+	return ""
+}
+
+// Add adds a polygon to a boundary.
+func (b *boundary) add(coords [][][]float64) {
+	var p polygon
+	for _, rc := range coords {
+		var ring []point
+		for _, c := range rc {
+			if len(c) < 2 {
+				continue
+			}
+			pt := point{lat: c[1], lon: c[0]}
+			ring = append(ring, pt)
+			b.minLat = math.Min(b.minLat, pt.lat)
+			b.maxLat = math.Max(b.maxLat, pt.lat)
+			b.minLon = math.Min(b.minLon, pt.lon)
+			b.maxLon = math.Max(b.maxLon, pt.lon)
+		}
+		if len(ring) < 3 {
+			continue
+		}
+		p = append(p, ring)
+	}
+	if len(p) == 0 {
+		return
+	}
+	b.polys = append(b.polys, p)
+}
+
+// InBox returns true if a point is inside
+// the bounding box of the boundary,
+// expanded by a distance in degrees.
+func (b *boundary) inBox(p Position, dLat, dLon float64) bool {
+	if p.Lat < b.minLat-dLat || p.Lat > b.maxLat+dLat {
+		return false
+	}
+	if p.Lon < b.minLon-dLon || p.Lon > b.maxLon+dLon {
+		return false
+	}
+	return true
+}
+
+// Contains returns true
+// if a point is inside the boundary.
+func (b *boundary) contains(p Position) bool {
+	if !b.inBox(p, 0, 0) {
+		return false
+	}
+	for _, poly := range b.polys {
+		if !inRing(p, poly[0]) {
+			continue
+		}
+		hole := false
+		for _, h := range poly[1:] {
+			if inRing(p, h) {
+				hole = true
+				break
+			}
+		}
+		if !hole {
+			return true
+		}
+	}
+	return false
+}
+
+// Near returns true
+// if a point is at a distance
+// (in meters)
+// from the boundary
+// smaller than the indicated distance.
+func (b *boundary) near(p Position, dist uint) bool {
+	if b.contains(p) {
+		return true
+	}
+	dLat := float64(dist) / degLen
+	dLon := 360.0
+	if cs := math.Cos(toRad(p.Lat)); cs > 0.01 {
+		dLon = dLat / cs
+	}
+	if !b.inBox(p, dLat, dLon) {
+		return false
+	}
+	d := float64(dist)
+	for _, poly := range b.polys {
+		for _, ring := range poly {
+			if ringDist(p, ring) <= d {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// InRing returns true
+// if a point is inside a ring,
+// using the ray casting algorithm.
+func inRing(p Position, ring []point) bool {
+	in := false
+	j := len(ring) - 1
+	for i := range ring {
+		a, b := ring[i], ring[j]
+		if (a.lat > p.Lat) != (b.lat > p.Lat) {
+			lon := a.lon + (p.Lat-a.lat)*(b.lon-a.lon)/(b.lat-a.lat)
+			if p.Lon < lon {
+				in = !in
+			}
+		}
+		j = i
+	}
+	return in
+}
+
+// DegLen is the length of a degree
+// (in meters)
+// of a great circle.
+const degLen = EarthRadius * math.Pi / 180
+
+// RingDist returns the minimum distance,
+// in meters,
+// from a point to the border of a ring,
+// using an equirectangular projection
+// centered at the point.
+func ringDist(p Position, ring []point) float64 {
+	cs := math.Cos(toRad(p.Lat))
+	min := math.MaxFloat64
+	proj := func(pt point) (float64, float64) {
+		dLon := pt.lon - p.Lon
+		if dLon > 180 {
+			dLon -= 360
+		}
+		if dLon < -180 {
+			dLon += 360
+		}
+		return dLon * cs * degLen, (pt.lat - p.Lat) * degLen
+	}
+	x1, y1 := proj(ring[len(ring)-1])
+	for _, pt := range ring {
+		x2, y2 := proj(pt)
+		if d := segDist(x1, y1, x2, y2); d < min {
+			min = d
+		}
+		x1, y1 = x2, y2
+	}
+	return min
+}
+
+// SegDist returns the distance
+// from the origin
+// to a segment.
+func segDist(x1, y1, x2, y2 float64) float64 {
+	dx, dy := x2-x1, y2-y1
+	l := dx*dx + dy*dy
+	t := 0.0
+	if l > 0 {
+		t = -(x1*dx + y1*dy) / l
+		if t < 0 {
+			t = 0
+		}
+		if t > 1 {
+			t = 1
+		}
+	}
+	x, y := x1+t*dx, y1+t*dy
+	return math.Sqrt(x*x + y*y)
+}
+
+// CountryAt returns the ISO 3166-1 alpha-2 code
+// of the country that contains a position.
+// If the position is outside any country
+// (e.g. in the sea),
+// it returns an empty string.
+func CountryAt(p Position) string {
+	if !p.IsValid() {
+		return ""
+	}
+	defaultBorders()
+	bordersMu.RLock()
+	defer bordersMu.RUnlock()
+	for _, b := range borderList {
+		if b.contains(p) {
+			return b.code
+		}
+	}
+	return ""
+}
+
+// InSea returns true
+// if a position is outside any country,
+// at a distance from any border
+// greater than the uncertainty of the position
+// plus the BorderTolerance.
+func InSea(p Position) bool {
+	if !p.IsValid() {
+		return false
+	}
+	defaultBorders()
+	bordersMu.RLock()
+	defer bordersMu.RUnlock()
+	if len(borders) == 0 {
+		return false
+	}
+	dist := p.Uncertainty + BorderTolerance
+	for _, b := range borderList {
+		if b.near(p, dist) {
+			return false
+		}
+	}
+	return true
+}
+
+// Contains returns true
+// if a position is inside the country
+// of the administrative division,
+// or at a distance from its border
+// smaller than the uncertainty of the position
+// plus the BorderTolerance.
+// States and counties are not checked.
+//
+// If the country is not valid
+// it returns false,
+// and if the country is not in the borders data,
+// it returns true.
+func (a Admin) Contains(p Position) bool {
+	if !p.IsValid() {
+		return false
+	}
+	defaultBorders()
+	bordersMu.RLock()
+	defer bordersMu.RUnlock()
+	if !IsValidCode(a.Country) {
+		return false
+	}
+	b, ok := borders[strings.ToUpper(a.Country)]
+	if !ok {
+		return true
+	}
+	return b.near(p, p.Uncertainty+BorderTolerance)
+}
+
+// Centroid returns the centroid
+// of the main polygon
+// (i.e. the polygon with the largest area)
+// of a country,
+// for a given ISO 3166-1 alpha-2 country code.
+// If the country is not in the borders data,
+// it returns an invalid position.
+func Centroid(code string) Position {
+	defaultBorders()
+	bordersMu.RLock()
+	defer bordersMu.RUnlock()
+	b, ok := borders[strings.ToUpper(code)]
+	if !ok {
+		return NewPosition()
+	}
+	return b.center
+}
+
+// Centroid returns the centroid
+// of the largest polygon of a boundary.
+func (b *boundary) centroid() Position {
+	var max, lat, lon float64
+	for _, poly := range b.polys {
+		a, y, x := ringCentroid(poly[0])
+		if math.Abs(a) <= max {
+			continue
+		}
+		max = math.Abs(a)
+		lat, lon = y, x
+	}
+	if max == 0 {
+		return NewPosition()
+	}
+	return Position{Lat: lat, Lon: lon}
+}
+
+// RingCentroid returns the signed area
+// and the centroid of a ring,
+// using planar coordinates.
+func ringCentroid(ring []point) (area, lat, lon float64) {
+	j := len(ring) - 1
+	for i := range ring {
+		a, b := ring[j], ring[i]
+		f := a.lon*b.lat - b.lon*a.lat
+		area += f
+		lon += (a.lon + b.lon) * f
+		lat += (a.lat + b.lat) * f
+		j = i
+	}
+	if area == 0 {
+		return 0, 0, 0
+	}
+	area /= 2
+	return area, lat / (6 * area), lon / (6 * area)
+}
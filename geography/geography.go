@@ -7,7 +7,10 @@
 // Package geography implements simple geographic utilities.
 package geography
 
-import "strings"
+import (
+	"strings"
+	"sync"
+)
 
 // An Admin is a geographic administrative hierarchy.
 type Admin struct {
@@ -29,6 +32,30 @@ func IsValidCode(code string) bool {
 	return ok
 }
 
+// CountryCode returns the ISO 3166-1 alpha-2 code
+// of a country name.
+// The name is normalized before the comparison,
+// so it is case insensitive,
+// and diacritics are ignored.
+// It returns an empty string
+// if the name is not a country.
+func CountryCode(name string) string {
+	countryNamesOnce.Do(func() {
+		countryNames = make(map[string]string, len(country))
+		for code, nm := range country {
+			countryNames[Normalize(nm)] = code
+		}
+	})
+	return countryNames[Normalize(name)]
+}
+
+// CountryNames is a map of the normalized
+// country names to its codes.
+var (
+	countryNamesOnce sync.Once
+	countryNames     map[string]string
+)
+
 // Country is a valid ISO 3166-1 alpha-2 county code,
 // as in
 // <http://en.wikipedia.org/wiki/ISO_3166-1_alpha-2>.
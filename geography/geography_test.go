@@ -6,7 +6,10 @@
 
 package geography
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestCountry(t *testing.T) {
 	testData := []struct {
@@ -27,6 +30,26 @@ func TestCountry(t *testing.T) {
 	}
 }
 
+func TestCountryCode(t *testing.T) {
+	testData := []struct {
+		name string
+		want string
+	}{
+		{"Greece", "GR"},
+		{"united states", "US"},
+		{"  Perú ", "PE"},
+		{"Congo, the Democratic Republic of the", "CD"},
+		{"Mordor", ""},
+		{"", ""},
+	}
+
+	for _, d := range testData {
+		if got := CountryCode(d.name); got != d.want {
+			t.Errorf("name %q = %q, want %q", d.name, got, d.want)
+		}
+	}
+}
+
 func TestVadidCoord(t *testing.T) {
 	testData := []struct {
 		lat  float64
@@ -107,3 +130,134 @@ func TestDistance(t *testing.T) {
 		}
 	}
 }
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"San Miguel de Tucumán":   "san miguel de tucuman",
+		"  Río  Pilcomayo, (río)": "rio pilcomayo rio",
+		"Ñacuñán":                 "nacunan",
+		"Sankt-Gallen":            "sankt gallen",
+	}
+	for in, want := range tests {
+		if got := Normalize(in); got != want {
+			t.Errorf("normalize %q: %q, want %q", in, got, want)
+		}
+	}
+}
+
+var bordersBlob = `{
+"type": "FeatureCollection",
+"features": [
+{"type": "Feature",
+"properties": {"ISO_A2": "AR", "NAME": "Argentina"},
+"geometry": {"type": "Polygon", "coordinates": [
+	[[-73, -55], [-58, -55], [-58, -22], [-73, -22], [-73, -55]],
+	[[-66, -42], [-64, -42], [-64, -40], [-66, -40], [-66, -42]]
+]}},
+{"type": "Feature",
+"properties": {"ISO_A2": "-99", "ISO_A2_EH": "UY", "NAME": "Uruguay"},
+"geometry": {"type": "MultiPolygon", "coordinates": [
+	[[[-58, -35], [-53, -35], [-53, -30], [-58, -30], [-58, -35]]]
+]}},
+{"type": "Feature",
+"properties": {"ISO_A2": "-99", "NAME": "Somewhere"},
+"geometry": {"type": "Polygon", "coordinates": [
+	[[0, 0], [1, 0], [1, 1], [0, 1], [0, 0]]
+]}}
+]
+}`
+
+func TestBorders(t *testing.T) {
+	if err := LoadBorders(strings.NewReader(bordersBlob)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !HasBorders() {
+		t.Fatalf("borders not loaded")
+	}
+
+	testData := []struct {
+		p       Position
+		country string
+		sea     bool
+	}{
+		{Position{Lat: -34.6, Lon: -60}, "AR", false},
+		{Position{Lat: -32, Lon: -56}, "UY", false},
+		{Position{Lat: -41, Lon: -65}, "", true},
+		{Position{Lat: -40.05, Lon: -65}, "", false},
+		{Position{Lat: -40, Lon: -40}, "", true},
+		{Position{Lat: 0.5, Lon: 0.5}, "", true},
+		{Position{Lat: -35.05, Lon: -55}, "", false},
+	}
+	for _, d := range testData {
+		if c := CountryAt(d.p); c != d.country {
+			t.Errorf("country at %.2f, %.2f = %q, want %q", d.p.Lat, d.p.Lon, c, d.country)
+		}
+		if s := InSea(d.p); s != d.sea {
+			t.Errorf("in sea %.2f, %.2f = %v, want %v", d.p.Lat, d.p.Lon, s, d.sea)
+		}
+	}
+
+	adm := Admin{Country: "ar"}
+	if !adm.Contains(Position{Lat: -30, Lon: -60}) {
+		t.Errorf("position inside %q, not contained", adm.Country)
+	}
+	if !adm.Contains(Position{Lat: -30, Lon: -57.95}) {
+		t.Errorf("position near %q border, not contained", adm.Country)
+	}
+	if adm.Contains(Position{Lat: -30, Lon: -56}) {
+		t.Errorf("position outside %q, contained", adm.Country)
+	}
+	if !adm.Contains(Position{Lat: -30, Lon: -57.5, Uncertainty: 100000}) {
+		t.Errorf("position inside uncertainty of %q border, not contained", adm.Country)
+	}
+	if !(Admin{Country: "CL"}).Contains(Position{Lat: -30, Lon: -56}) {
+		t.Errorf("country without borders, not contained")
+	}
+	if (Admin{Country: "XX"}).Contains(Position{Lat: -30, Lon: -60}) {
+		t.Errorf("invalid country, contained")
+	}
+
+	c := Centroid("uy")
+	if !c.Equal(Position{Lat: -32.5, Lon: -55.5}) {
+		t.Errorf("centroid %.2f, %.2f, want %.2f, %.2f", c.Lat, c.Lon, -32.5, -55.5)
+	}
+	if Centroid("CL").IsValid() {
+		t.Errorf("centroid of a country without borders")
+	}
+}
+
+func TestWorldBorders(t *testing.T) {
+	if err := LoadBorders(strings.NewReader(worldBorders)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for code := range country {
+		if Centroid(code).IsValid() {
+			continue
+		}
+		t.Errorf("country %q without borders", code)
+	}
+
+	testData := []struct {
+		p       Position
+		country string
+	}{
+		{Position{Lat: -26.82, Lon: -65.22}, "AR"},
+		{Position{Lat: -34.9, Lon: -56.16}, "UY"},
+		{Position{Lat: 39.57, Lon: 2.65}, "ES"},
+		{Position{Lat: -0.74, Lon: -90.31}, "EC"},
+		{Position{Lat: -6.16, Lon: 39.19}, "TZ"},
+		{Position{Lat: 4.93, Lon: -52.33}, "GF"},
+		{Position{Lat: 42.66, Lon: 21.17}, "RS"},
+	}
+	for _, d := range testData {
+		if !(Admin{Country: d.country}).Contains(d.p) {
+			t.Errorf("position %.2f, %.2f not in %q", d.p.Lat, d.p.Lon, d.country)
+		}
+		if InSea(d.p) {
+			t.Errorf("position %.2f, %.2f in the sea", d.p.Lat, d.p.Lon)
+		}
+	}
+	if !InSea(Position{Lat: -40.123, Lon: -40.512}) {
+		t.Errorf("position in the Atlantic ocean, not in the sea")
+	}
+}
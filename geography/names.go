@@ -0,0 +1,80 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package geography
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Diacritics is a table of latin letters
+// with diacritics
+// and its ASCII equivalents.
+var diacritics = map[rune]string{}
+
+func init() {
+	for _, v := range []struct {
+		runes, ascii string
+	}{
+		{"àáâãäåāăą", "a"},
+		{"æ", "ae"},
+		{"çćĉċč", "c"},
+		{"ďđð", "d"},
+		{"èéêëēĕėęě", "e"},
+		{"ĝğġģ", "g"},
+		{"ĥħ", "h"},
+		{"ìíîïĩīĭįı", "i"},
+		{"ĵ", "j"},
+		{"ķ", "k"},
+		{"ĺļľŀł", "l"},
+		{"ñńņňŉ", "n"},
+		{"òóôõöøōŏő", "o"},
+		{"œ", "oe"},
+		{"ŕŗř", "r"},
+		{"śŝşšș", "s"},
+		{"ß", "ss"},
+		{"ţťŧț", "t"},
+		{"þ", "th"},
+		{"ùúûüũūŭůűų", "u"},
+		{"ŵ", "w"},
+		{"ýÿŷ", "y"},
+		{"źżž", "z"},
+	} {
+		for _, r := range v.runes {
+			diacritics[r] = v.ascii
+		}
+	}
+}
+
+// Normalize returns a place name
+// in lower case,
+// without diacritics,
+// and with any punctuation
+// replaced by a space.
+// It is useful to compare names
+// written with different conventions.
+func Normalize(name string) string {
+	var b strings.Builder
+	space := true
+	for _, r := range strings.ToLower(name) {
+		if s, ok := diacritics[r]; ok {
+			b.WriteString(s)
+			space = false
+			continue
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			space = false
+			continue
+		}
+		if !space {
+			b.WriteRune(' ')
+			space = true
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
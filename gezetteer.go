@@ -165,9 +165,9 @@ func (gsc *GeoScan) Add(p geography.Position, err error) bool {
 		return false
 	}
 	if err != nil {
+		gsc.err = err
 		gsc.c <- geography.NewPosition()
 		close(gsc.c)
-		gsc.err = err
 		return true
 	}
 	gsc.c <- p
@@ -0,0 +1,464 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package journal implements atomic,
+// multi-file,
+// commits for the biodv databases.
+//
+// A transaction (Tx)
+// writes each file into a temporary file,
+// that is synced to the disk.
+// When the transaction is committed,
+// a journal with the list of operations
+// is written,
+// and then the temporary files
+// are renamed to their final names,
+// and the removed files are deleted.
+// After all operations are done,
+// the journal is removed.
+//
+// A transaction can also store
+// the changes made on the values
+// of the databases,
+// that are appended to a change log
+// (the file changes.stz)
+// when the transaction is committed.
+//
+// If the commit is interrupted,
+// the function Shared,
+// called by each database when it is opened,
+// completes the operations of the journal,
+// or, if the journal was not written,
+// removes the temporary files,
+// so the database is always
+// at the state of a complete commit.
+//
+// Databases are locked with advisory locks
+// (see Lock)
+// while they are read,
+// and while a transaction is open.
+package journal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// File name of the journal,
+// and extension of temporary files.
+const (
+	journalFile = "biodv.journal"
+	tmpExt      = ".tmp"
+)
+
+// Operations stored in the journal.
+const (
+	opWrite  = "write"
+	opRemove = "remove"
+	opAppend = "append"
+)
+
+type op struct {
+	kind string
+	file string
+
+	// size of the file before appending
+	size int64
+}
+
+// Tx is a transaction
+// of a set of file operations
+// over a database directory.
+type Tx struct {
+	path    string
+	lock    *Lock
+	ops     []op
+	changes []Change
+	undo    []string
+	commit  []func()
+	done    bool
+}
+
+// Begin starts a new transaction
+// over the database in the given path.
+// The database will be locked
+// until the transaction is committed
+// or rolled back.
+func Begin(path string) (*Tx, error) {
+	l, err := Exclusive(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := restore(path); err != nil {
+		l.Release()
+		return nil, err
+	}
+	return &Tx{path: path, lock: l}, nil
+}
+
+// File is a file written
+// as part of a transaction.
+type File struct {
+	f *os.File
+}
+
+// Write writes to the temporary file.
+func (f *File) Write(p []byte) (int, error) {
+	return f.f.Write(p)
+}
+
+// Close syncs and closes the file.
+func (f *File) Close() error {
+	if err := f.f.Sync(); err != nil {
+		f.f.Close()
+		return err
+	}
+	return f.f.Close()
+}
+
+// Create creates a file
+// that will replace the file
+// with the given name
+// (relative to the database path)
+// when the transaction is committed.
+// If the directory of the file does not exist,
+// it will be created.
+func (tx *Tx) Create(name string) (*File, error) {
+	if tx.done {
+		return nil, errors.New("journal: create: transaction already done")
+	}
+	name = filepath.Clean(name)
+	file := filepath.Join(tx.path, name)
+	if dir := filepath.Dir(file); dir != "." {
+		if err := os.MkdirAll(dir, os.ModeDir|os.ModePerm); err != nil {
+			return nil, errors.Wrapf(err, "journal: create %s", name)
+		}
+	}
+	f, err := os.Create(file + tmpExt)
+	if err != nil {
+		return nil, errors.Wrapf(err, "journal: create %s", name)
+	}
+	tx.set(op{opWrite, name, 0})
+	return &File{f}, nil
+}
+
+// Remove marks a file
+// (relative to the database path)
+// to be removed
+// when the transaction is committed.
+func (tx *Tx) Remove(name string) {
+	if tx.done {
+		return
+	}
+	name = filepath.Clean(name)
+	if tx.set(op{opRemove, name, 0}) {
+		os.Remove(filepath.Join(tx.path, name+tmpExt))
+	}
+}
+
+// Set sets the operation of a file,
+// and returns true
+// if the file was already in the transaction.
+func (tx *Tx) set(o op) bool {
+	for i, p := range tx.ops {
+		if p.file == o.file {
+			tx.ops[i] = o
+			return true
+		}
+	}
+	tx.ops = append(tx.ops, o)
+	return false
+}
+
+// OnCommit registers a function
+// that will be called
+// after the transaction is committed successfully.
+// Databases use it to update their state
+// (for example,
+// their version)
+// only when their files are stored,
+// so if the transaction fails,
+// or is rolled back,
+// the database remains unchanged.
+func (tx *Tx) OnCommit(f func()) {
+	if tx.done {
+		return
+	}
+	tx.commit = append(tx.commit, f)
+}
+
+// Commit applies the operations
+// of the transaction.
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return errors.New("journal: commit: transaction already done")
+	}
+	tx.done = true
+	defer tx.lock.Release()
+	if len(tx.changes) > 0 {
+		if err := tx.writeLog(); err != nil {
+			tx.rollback()
+			return errors.Wrap(err, "journal: commit")
+		}
+	}
+	if len(tx.ops) == 0 {
+		tx.committed()
+		return nil
+	}
+	if err := writeJournal(tx.path, tx.ops); err != nil {
+		tx.rollback()
+		return errors.Wrap(err, "journal: commit")
+	}
+	if err := apply(tx.path, tx.ops); err != nil {
+		return errors.Wrap(err, "journal: commit")
+	}
+	if err := os.Remove(filepath.Join(tx.path, journalFile)); err != nil {
+		return errors.Wrap(err, "journal: commit")
+	}
+	syncDir(tx.path)
+	tx.committed()
+	return nil
+}
+
+// Committed calls the functions
+// registered with OnCommit.
+func (tx *Tx) committed() {
+	for _, f := range tx.commit {
+		f()
+	}
+	tx.commit = nil
+}
+
+// Rollback discards the operations
+// of the transaction.
+// It does nothing if the transaction
+// was already committed.
+func (tx *Tx) Rollback() {
+	if tx.done {
+		return
+	}
+	tx.done = true
+	tx.commit = nil
+	tx.rollback()
+	tx.lock.Release()
+}
+
+func (tx *Tx) rollback() {
+	for _, o := range tx.ops {
+		if o.kind == opWrite || o.kind == opAppend {
+			os.Remove(filepath.Join(tx.path, o.file+tmpExt))
+		}
+	}
+}
+
+// WriteJournal writes the journal file.
+// The journal is written in a temporary file,
+// that is renamed once it is complete,
+// so a journal file is always complete.
+func writeJournal(path string, ops []op) (err error) {
+	file := filepath.Join(path, journalFile)
+	f, err := os.Create(file + tmpExt)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, o := range ops {
+		if o.kind == opAppend {
+			fmt.Fprintf(w, "%s\t%s\t%d\n", o.kind, filepath.ToSlash(o.file), o.size)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\n", o.kind, filepath.ToSlash(o.file))
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	jf := &File{f}
+	if err := jf.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(file+tmpExt, file); err != nil {
+		return err
+	}
+	syncDir(path)
+	return nil
+}
+
+// ReadJournal reads the operations
+// stored in a journal file.
+func readJournal(file string) ([]op, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ops []op
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		ln := strings.Split(s.Text(), "\t")
+		if len(ln) < 2 {
+			continue
+		}
+		o := op{kind: ln[0], file: filepath.FromSlash(ln[1])}
+		switch o.kind {
+		case opWrite, opRemove:
+		case opAppend:
+			if len(ln) != 3 {
+				return nil, errors.Errorf("invalid operation %q", s.Text())
+			}
+			sz, err := strconv.ParseInt(ln[2], 10, 64)
+			if err != nil {
+				return nil, errors.Errorf("invalid operation %q", s.Text())
+			}
+			o.size = sz
+		default:
+			return nil, errors.Errorf("invalid operation %q", ln[0])
+		}
+		ops = append(ops, o)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
+
+// Apply applies a list of operations.
+// As it can be called more than once
+// over the same list,
+// missing temporary files,
+// or already removed files,
+// are ignored.
+func apply(path string, ops []op) error {
+	dirs := make(map[string]bool)
+	for _, o := range ops {
+		file := filepath.Join(path, o.file)
+		switch o.kind {
+		case opWrite:
+			if _, err := os.Stat(file + tmpExt); os.IsNotExist(err) {
+				continue
+			}
+			if err := os.Rename(file+tmpExt, file); err != nil {
+				return err
+			}
+		case opRemove:
+			if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		case opAppend:
+			if _, err := os.Stat(file + tmpExt); os.IsNotExist(err) {
+				continue
+			}
+			if err := appendFile(file, o.size); err != nil {
+				return err
+			}
+		}
+		dirs[filepath.Dir(file)] = true
+	}
+	for d := range dirs {
+		syncDir(d)
+	}
+	return nil
+}
+
+// AppendFile appends the content
+// of the temporary file of a file,
+// from the given size.
+// As the file is truncated to that size,
+// it can be called more than once.
+func appendFile(file string, size int64) error {
+	tmp, err := os.Open(file + tmpExt)
+	if err != nil {
+		return err
+	}
+	defer tmp.Close()
+
+	f, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Seek(size, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := io.Copy(f, tmp); err != nil {
+		f.Close()
+		return err
+	}
+	af := &File{f}
+	if err := af.Close(); err != nil {
+		return err
+	}
+	return os.Remove(file + tmpExt)
+}
+
+// SyncDir syncs a directory,
+// so the renames on it
+// are stored in the disk.
+// As not all systems support
+// syncing a directory,
+// errors are ignored.
+func syncDir(dir string) {
+	if dir == "" {
+		dir = "."
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
+
+// Restore completes an interrupted commit
+// of the database in the given path.
+// If there is a journal,
+// its operations are applied,
+// otherwise,
+// the temporary files
+// in the indicated sub-directories
+// are removed.
+// The database must be locked.
+func restore(path string, dirs ...string) error {
+	file := filepath.Join(path, journalFile)
+	if _, err := os.Stat(file); err == nil {
+		ops, err := readJournal(file)
+		if err != nil {
+			return errors.Wrap(err, "journal: recover")
+		}
+		if err := apply(path, ops); err != nil {
+			return errors.Wrap(err, "journal: recover")
+		}
+		if err := os.Remove(file); err != nil {
+			return errors.Wrap(err, "journal: recover")
+		}
+		syncDir(path)
+	}
+	os.Remove(file + tmpExt)
+	os.Remove(filepath.Join(path, logFile+tmpExt))
+
+	for _, d := range dirs {
+		ls, err := filepath.Glob(filepath.Join(path, d, "*"+tmpExt))
+		if err != nil {
+			continue
+		}
+		for _, f := range ls {
+			os.Remove(f)
+		}
+	}
+	return nil
+}
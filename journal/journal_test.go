@@ -0,0 +1,156 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package journal
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) {
+	if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatalf("unable to write %s: %v", name, err)
+	}
+}
+
+func checkFile(t *testing.T, name, want string) {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Errorf("unable to read %s: %v", name, err)
+		return
+	}
+	if string(b) != want {
+		t.Errorf("file %s: content %q, want %q", name, string(b), want)
+	}
+}
+
+func checkNoFile(t *testing.T, name string) {
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("file %s: should not exist", name)
+	}
+}
+
+func TestCommit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatalf("unable to create temporal directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	old := filepath.Join(dir, "records", "old.stz")
+	os.Mkdir(filepath.Join(dir, "records"), os.ModeDir|os.ModePerm)
+	writeFile(t, old, "old")
+
+	tx, err := Begin(dir)
+	if err != nil {
+		t.Fatalf("begin: unexpected error: %v", err)
+	}
+	for _, n := range []string{filepath.Join("taxonomy", "taxonomy.stz"), filepath.Join("records", "new.stz")} {
+		f, err := tx.Create(n)
+		if err != nil {
+			t.Fatalf("unable to create %s: %v", n, err)
+		}
+		f.Write([]byte(n))
+		if err := f.Close(); err != nil {
+			t.Fatalf("unable to close %s: %v", n, err)
+		}
+		checkNoFile(t, filepath.Join(dir, n))
+	}
+	tx.Remove(filepath.Join("records", "old.stz"))
+	checkFile(t, old, "old")
+	committed := 0
+	tx.OnCommit(func() { committed++ })
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("commit: unexpected error: %v", err)
+	}
+	if committed != 1 {
+		t.Errorf("commit: OnCommit called %d times, want %d", committed, 1)
+	}
+	checkFile(t, filepath.Join(dir, "taxonomy", "taxonomy.stz"), filepath.Join("taxonomy", "taxonomy.stz"))
+	checkFile(t, filepath.Join(dir, "records", "new.stz"), filepath.Join("records", "new.stz"))
+	checkNoFile(t, old)
+	checkNoFile(t, filepath.Join(dir, journalFile))
+	checkNoFile(t, filepath.Join(dir, "records", "new.stz"+tmpExt))
+
+	if err := tx.Commit(); err == nil {
+		t.Errorf("commit: expecting error on a done transaction")
+	}
+}
+
+func TestRollback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatalf("unable to create temporal directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "data.stz")
+	writeFile(t, file, "old")
+
+	tx, err := Begin(dir)
+	if err != nil {
+		t.Fatalf("begin: unexpected error: %v", err)
+	}
+	f, err := tx.Create("data.stz")
+	if err != nil {
+		t.Fatalf("unable to create data.stz: %v", err)
+	}
+	f.Write([]byte("new"))
+	f.Close()
+	committed := false
+	tx.OnCommit(func() { committed = true })
+	tx.Rollback()
+
+	if committed {
+		t.Errorf("rollback: OnCommit function called")
+	}
+	checkFile(t, file, "old")
+	checkNoFile(t, file+tmpExt)
+}
+
+func TestRecover(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatalf("unable to create temporal directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	os.Mkdir(filepath.Join(dir, "records"), os.ModeDir|os.ModePerm)
+
+	// an interrupted commit,
+	// after the journal was written
+	a := filepath.Join(dir, "records", "a.stz")
+	b := filepath.Join(dir, "records", "b.stz")
+	c := filepath.Join(dir, "records", "c.stz")
+	writeFile(t, a, "a")
+	writeFile(t, b, "b")
+	writeFile(t, b+tmpExt, "new b")
+	writeFile(t, c, "new c") // already renamed
+	writeFile(t, filepath.Join(dir, journalFile), "write\trecords/b.stz\nwrite\trecords/c.stz\nremove\trecords/a.stz\n")
+
+	if err := restore(dir, "records"); err != nil {
+		t.Fatalf("restore: unexpected error: %v", err)
+	}
+	checkNoFile(t, a)
+	checkFile(t, b, "new b")
+	checkFile(t, c, "new c")
+	checkNoFile(t, b+tmpExt)
+	checkNoFile(t, filepath.Join(dir, journalFile))
+
+	// an interrupted commit,
+	// before the journal was written
+	writeFile(t, c+tmpExt, "newer c")
+	writeFile(t, filepath.Join(dir, journalFile+tmpExt), "write\trecords/c")
+	if err := restore(dir, "records"); err != nil {
+		t.Fatalf("restore: unexpected error: %v", err)
+	}
+	checkFile(t, c, "new c")
+	checkNoFile(t, c+tmpExt)
+	checkNoFile(t, filepath.Join(dir, journalFile+tmpExt))
+}
@@ -0,0 +1,275 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package journal
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// File name of the exclusive lock.
+// Shared locks use the same name
+// with a suffix that identifies the lock owner.
+const lockFile = "biodv.lock"
+
+// Timeout is the time to wait
+// for a locked database.
+// By default it is short,
+// so a process is not stopped
+// by a lock held for a brief time,
+// for example,
+// by a reader.
+// If it is zero,
+// an error will be returned immediately
+// when the database is locked.
+var Timeout = 5 * time.Second
+
+// Interval between attemps
+// to acquire a lock.
+var retry = 100 * time.Millisecond
+
+// Exclusive locks held by the current process.
+var (
+	mutex sync.Mutex
+	held  = make(map[string]bool)
+)
+
+// Serial is a counter
+// used to build unique names
+// within the current process.
+var serial uint64
+
+// Unique returns a string
+// that is unique
+// for the current process
+// in the current host.
+func unique() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d-%d", host, os.Getpid(), atomic.AddUint64(&serial, 1))
+}
+
+// A Lock is an advisory lock
+// on a database directory.
+//
+// Locks are implemented with lock files
+// in the database directory,
+// so they can be used
+// with shared or network file systems.
+// Readers create a shared lock file
+// (biodv.lock.<owner>)
+// and writers create an exclusive lock file
+// (biodv.lock).
+// A process that finds a lock file
+// of the other kind
+// removes its own lock file
+// and waits,
+// so an exclusive lock
+// is never held together with another lock.
+type Lock struct {
+	path string
+	file string
+}
+
+// Shared acquires a shared (read) lock
+// on the database in the given path.
+//
+// If an interrupted commit is found
+// (a journal, or temporary files
+// in the indicated sub-directories),
+// it will be recovered before acquiring the lock.
+func Shared(path string, dirs ...string) (*Lock, error) {
+	mutex.Lock()
+	own := held[filepath.Clean(path)]
+	mutex.Unlock()
+	if own {
+		// the process is already committing
+		return &Lock{}, nil
+	}
+
+	for {
+		l, err := shared(path)
+		if err != nil {
+			return nil, err
+		}
+		if !needRecover(path, dirs) {
+			return l, nil
+		}
+		l.Release()
+
+		x, err := exclusive(path)
+		if err != nil {
+			return nil, err
+		}
+		err = restore(path, dirs...)
+		x.Release()
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func shared(path string) (*Lock, error) {
+	name := lockFile + "." + unique()
+	l := &Lock{path: path, file: filepath.Join(path, name)}
+	start := time.Now()
+	for {
+		if err := ioutil.WriteFile(l.file, lockInfo(), 0666); err != nil {
+			return nil, errors.Wrap(err, "journal: lock")
+		}
+		xf := filepath.Join(path, lockFile)
+		if _, err := os.Stat(xf); os.IsNotExist(err) {
+			return l, nil
+		}
+		os.Remove(l.file)
+		if err := wait(start, xf); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Exclusive acquires an exclusive (write) lock
+// on the database in the given path.
+func Exclusive(path string) (*Lock, error) {
+	l, err := exclusive(path)
+	if err != nil {
+		return nil, err
+	}
+	mutex.Lock()
+	held[filepath.Clean(path)] = true
+	mutex.Unlock()
+	return l, nil
+}
+
+func exclusive(path string) (*Lock, error) {
+	l := &Lock{path: path, file: filepath.Join(path, lockFile)}
+	start := time.Now()
+	for {
+		f, err := os.OpenFile(l.file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+		if err != nil {
+			if !os.IsExist(err) {
+				return nil, errors.Wrap(err, "journal: lock")
+			}
+			if err := wait(start, l.file); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		f.Write(lockInfo())
+		f.Close()
+
+		ls, _ := filepath.Glob(filepath.Join(path, lockFile+".*"))
+		if len(ls) == 0 {
+			return l, nil
+		}
+		os.Remove(l.file)
+		if err := wait(start, ls[0]); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Release releases the lock.
+func (l *Lock) Release() {
+	if l == nil || l.file == "" {
+		return
+	}
+	os.Remove(l.file)
+	if filepath.Base(l.file) == lockFile {
+		mutex.Lock()
+		delete(held, filepath.Clean(l.path))
+		mutex.Unlock()
+	}
+	l.file = ""
+}
+
+// LockInfo returns the information
+// stored in a lock file.
+func lockInfo() []byte {
+	host, _ := os.Hostname()
+	return []byte(fmt.Sprintf("host: %s\npid: %d\ntime: %s\n", host, os.Getpid(), time.Now().Format(time.RFC3339)))
+}
+
+// Wait waits before a new attempt
+// to acquire a lock,
+// or returns an error
+// if the timeout is reached.
+func wait(start time.Time, file string) error {
+	if time.Since(start) < Timeout {
+		time.Sleep(retry)
+		return nil
+	}
+	owner := "another process"
+	if b, err := ioutil.ReadFile(file); err == nil {
+		owner = strings.Join(strings.Fields(string(b)), " ")
+	}
+	return errors.Errorf("journal: database locked by %s (if no other process is using the database, remove the file %s)", owner, file)
+}
+
+// NeedRecover returns true
+// if there is an interrupted commit
+// in the database.
+func needRecover(path string, dirs []string) bool {
+	if _, err := os.Stat(filepath.Join(path, journalFile)); err == nil {
+		return true
+	}
+	for _, d := range dirs {
+		if ls, _ := filepath.Glob(filepath.Join(path, d, "*"+tmpExt)); len(ls) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// File name of the version
+// of a database directory.
+const versionFile = "version"
+
+// Version returns the version
+// of a database directory,
+// i.e. a value that is updated
+// each time the directory is committed.
+func Version(path, dir string) string {
+	b, err := ioutil.ReadFile(filepath.Join(path, dir, versionFile))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// Update checks that a database directory
+// is at the indicated version,
+// i.e. that it was not modified
+// by another process,
+// and returns the new version of the directory,
+// that will be stored
+// when the transaction is committed.
+func (tx *Tx) Update(dir, version string) (string, error) {
+	if v := Version(tx.path, dir); v != version {
+		return "", errors.Errorf("journal: %s: database modified by another process", dir)
+	}
+	v := time.Now().UTC().Format("20060102150405") + "-" + unique()
+	f, err := tx.Create(filepath.Join(dir, versionFile))
+	if err != nil {
+		return "", err
+	}
+	if _, err := fmt.Fprintf(f, "%s\n", v); err != nil {
+		f.Close()
+		return "", errors.Wrap(err, "journal: update")
+	}
+	if err := f.Close(); err != nil {
+		return "", errors.Wrap(err, "journal: update")
+	}
+	return v, nil
+}
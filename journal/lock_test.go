@@ -0,0 +1,103 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package journal
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatalf("unable to create temporal directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(t time.Duration) { Timeout = t }(Timeout)
+	Timeout = 0
+
+	r1, err := Shared(dir)
+	if err != nil {
+		t.Fatalf("shared: unexpected error: %v", err)
+	}
+	r2, err := Shared(dir)
+	if err != nil {
+		t.Fatalf("shared: unexpected error: %v", err)
+	}
+	if _, err := exclusive(dir); err == nil {
+		t.Errorf("exclusive: expecting error on a shared locked database")
+	}
+	r1.Release()
+	r2.Release()
+
+	x, err := exclusive(dir)
+	if err != nil {
+		t.Fatalf("exclusive: unexpected error: %v", err)
+	}
+	if _, err := shared(dir); err == nil {
+		t.Errorf("shared: expecting error on an exclusive locked database")
+	}
+	if _, err := exclusive(dir); err == nil {
+		t.Errorf("exclusive: expecting error on an exclusive locked database")
+	}
+
+	// wait for the lock
+	Timeout = time.Second
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		x.Release()
+	}()
+	r, err := Shared(dir)
+	if err != nil {
+		t.Fatalf("shared: unexpected error: %v", err)
+	}
+	r.Release()
+
+	ls, _ := filepath.Glob(filepath.Join(dir, lockFile+"*"))
+	if len(ls) > 0 {
+		t.Errorf("lock files %v not removed", ls)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatalf("unable to create temporal directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// two process read the same version
+	v := Version(dir, "taxonomy")
+
+	tx, err := Begin(dir)
+	if err != nil {
+		t.Fatalf("begin: unexpected error: %v", err)
+	}
+	nv, err := tx.Update("taxonomy", v)
+	if err != nil {
+		t.Fatalf("update: unexpected error: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("commit: unexpected error: %v", err)
+	}
+	if got := Version(dir, "taxonomy"); got != nv {
+		t.Errorf("version: got %q, want %q", got, nv)
+	}
+
+	tx, err = Begin(dir)
+	if err != nil {
+		t.Fatalf("begin: unexpected error: %v", err)
+	}
+	if _, err := tx.Update("taxonomy", v); err == nil {
+		t.Errorf("update: expecting error on a modified database")
+	}
+	tx.Rollback()
+}
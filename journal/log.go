@@ -0,0 +1,298 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package journal
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/js-arias/biodv/encoding/stanza"
+
+	"github.com/pkg/errors"
+)
+
+// File name of the change log.
+const logFile = "changes.stz"
+
+// Fields of the change log.
+const (
+	timeKey    = "time"
+	commandKey = "command"
+	undoKey    = "undo"
+	dbKey      = "database"
+	idKey      = "id"
+	keyKey     = "key"
+	oldKey     = "old"
+	newKey     = "new"
+)
+
+// Command is the name of the command
+// stored in the change log.
+// By default it is the first argument
+// of the program
+// after the program flags
+// (e.g. 'tax.db.sync' in 'biodv -wait 2m tax.db.sync').
+var Command string
+
+func command() string {
+	if Command != "" {
+		return Command
+	}
+	if flag.Parsed() {
+		if flag.NArg() > 0 {
+			return flag.Arg(0)
+		}
+		return filepath.Base(os.Args[0])
+	}
+	if len(os.Args) > 1 {
+		return os.Args[1]
+	}
+	return filepath.Base(os.Args[0])
+}
+
+// A Change is a modification
+// of a value of an element
+// (a taxon, a record, or a dataset)
+// in a database.
+//
+// When an element is added,
+// all of its values are stored
+// with an empty old value,
+// and when an element is deleted,
+// all of its values are stored
+// with an empty new value.
+type Change struct {
+	DB  string // database of the element
+	ID  string // ID of the element
+	Key string
+	Old string
+	New string
+}
+
+// Diff returns the changes
+// between two states of a database,
+// in which each element
+// is stored as a map of key-values,
+// sorted by ID and key.
+func Diff(db string, old, new map[string]map[string]string) []Change {
+	ids := make(map[string]bool)
+	for id := range old {
+		ids[id] = true
+	}
+	for id := range new {
+		ids[id] = true
+	}
+	ls := make([]string, 0, len(ids))
+	for id := range ids {
+		ls = append(ls, id)
+	}
+	sort.Strings(ls)
+
+	var ch []Change
+	for _, id := range ls {
+		o, n := old[id], new[id]
+		keys := make(map[string]bool)
+		for k := range o {
+			keys[k] = true
+		}
+		for k := range n {
+			keys[k] = true
+		}
+		kl := make([]string, 0, len(keys))
+		for k := range keys {
+			kl = append(kl, k)
+		}
+		sort.Strings(kl)
+		for _, k := range kl {
+			ov := strings.TrimSpace(o[k])
+			nv := strings.TrimSpace(n[k])
+			if ov == nv {
+				continue
+			}
+			ch = append(ch, Change{db, id, k, ov, nv})
+		}
+	}
+	return ch
+}
+
+// Revert sets the values of a database,
+// stored as a map of key-values,
+// to the old values of the changes
+// of the indicated database.
+// The changes should be ordered
+// from the oldest to the newest.
+func Revert(db string, data map[string]map[string]string, ch []Change) {
+	for i := len(ch) - 1; i >= 0; i-- {
+		c := ch[i]
+		if c.DB != db {
+			continue
+		}
+		m := data[c.ID]
+		if m == nil {
+			m = make(map[string]string)
+			data[c.ID] = m
+		}
+		if c.Old == "" {
+			delete(m, c.Key)
+		} else {
+			m[c.Key] = c.Old
+		}
+		if len(m) == 0 {
+			delete(data, c.ID)
+		}
+	}
+}
+
+// Log adds changes
+// to be written in the change log
+// when the transaction is committed.
+func (tx *Tx) Log(ch ...Change) {
+	if tx.done {
+		return
+	}
+	tx.changes = append(tx.changes, ch...)
+}
+
+// Reverts indicates
+// that the changes of the transaction
+// revert the operations
+// with the given IDs.
+func (tx *Tx) Reverts(ops ...string) {
+	if tx.done {
+		return
+	}
+	tx.undo = append(tx.undo, ops...)
+}
+
+// WriteLog writes the changes of a transaction
+// in a temporary file,
+// to be appended into the change log.
+func (tx *Tx) writeLog() (err error) {
+	file := filepath.Join(tx.path, logFile)
+	var size int64
+	if fi, err := os.Stat(file); err == nil {
+		size = fi.Size()
+	}
+
+	f, err := os.Create(file + tmpExt)
+	if err != nil {
+		return err
+	}
+	tx.ops = append(tx.ops, op{opAppend, logFile, size})
+	jf := &File{f}
+	w := stanza.NewWriter(jf)
+	defer func() {
+		e1 := w.Flush()
+		if e2 := jf.Close(); e1 == nil {
+			e1 = e2
+		}
+		if err == nil && e1 != nil {
+			err = e1
+		}
+	}()
+	w.SetFields([]string{timeKey, commandKey, undoKey, dbKey, idKey, keyKey, oldKey, newKey})
+
+	t := time.Now().UTC().Format(time.RFC3339Nano)
+	cmd := command()
+	undo := strings.Join(tx.undo, " ")
+	for _, c := range tx.changes {
+		rec := map[string]string{
+			timeKey:    t,
+			commandKey: cmd,
+			undoKey:    undo,
+			dbKey:      c.DB,
+			idKey:      c.ID,
+			keyKey:     c.Key,
+			oldKey:     c.Old,
+			newKey:     c.New,
+		}
+		if err := w.Write(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// An Op is a set of changes
+// committed in a single transaction.
+type Op struct {
+	ID      string // the time of the commit
+	Command string
+	Undo    []string // IDs of the reverted operations
+	Changes []Change
+}
+
+// ReadLog reads the change log
+// of the database in the given path.
+// The operations are returned
+// from the oldest to the newest.
+func ReadLog(path string) ([]*Op, error) {
+	f, err := os.Open(filepath.Join(path, logFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "journal: read log")
+	}
+	defer f.Close()
+
+	var ls []*Op
+	var o *Op
+	sc := stanza.NewScanner(f)
+	for sc.Scan() {
+		rec := sc.Record()
+		if o == nil || o.ID != rec[timeKey] {
+			o = &Op{
+				ID:      rec[timeKey],
+				Command: rec[commandKey],
+				Undo:    strings.Fields(rec[undoKey]),
+			}
+			ls = append(ls, o)
+		}
+		o.Changes = append(o.Changes, Change{
+			DB:  rec[dbKey],
+			ID:  rec[idKey],
+			Key: rec[keyKey],
+			Old: rec[oldKey],
+			New: rec[newKey],
+		})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, errors.Wrap(err, "journal: read log")
+	}
+	return ls, nil
+}
+
+// Undoable returns the last n operations
+// that can be reverted,
+// i.e. operations that are not undo operations
+// and that were not already reverted,
+// from the oldest to the newest.
+func Undoable(ops []*Op, n int) []*Op {
+	undone := make(map[string]bool)
+	for _, o := range ops {
+		for _, u := range o.Undo {
+			undone[u] = true
+		}
+	}
+	var ls []*Op
+	for i := len(ops) - 1; i >= 0 && len(ls) < n; i-- {
+		o := ops[i]
+		if len(o.Undo) > 0 || undone[o.ID] {
+			continue
+		}
+		ls = append(ls, o)
+	}
+	for i, j := 0, len(ls)-1; i < j; i, j = i+1, j-1 {
+		ls[i], ls[j] = ls[j], ls[i]
+	}
+	return ls
+}
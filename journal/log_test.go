@@ -0,0 +1,124 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package journal
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := map[string]map[string]string{
+		"a": {"name": "a", "rank": "genus"},
+		"b": {"name": "b"},
+	}
+	new := map[string]map[string]string{
+		"a": {"name": "a", "rank": "species", "author": "Linnaeus"},
+		"c": {"name": "c"},
+	}
+	want := []Change{
+		{"taxonomy", "a", "author", "", "Linnaeus"},
+		{"taxonomy", "a", "rank", "genus", "species"},
+		{"taxonomy", "b", "name", "b", ""},
+		{"taxonomy", "c", "name", "", "c"},
+	}
+	ch := Diff("taxonomy", old, new)
+	if !reflect.DeepEqual(ch, want) {
+		t.Errorf("diff: got %v, want %v", ch, want)
+	}
+
+	Revert("records", new, ch)
+	if _, ok := new["b"]; ok {
+		t.Errorf("revert: changes of other database should be ignored")
+	}
+	Revert("taxonomy", new, ch)
+	if !reflect.DeepEqual(new, old) {
+		t.Errorf("revert: got %v, want %v", new, old)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	cl := flag.CommandLine
+	defer func() { flag.CommandLine = cl }()
+
+	flag.CommandLine = flag.NewFlagSet("biodv", flag.ContinueOnError)
+	flag.Duration("wait", 0, "")
+	flag.Bool("offline", false, "")
+	if err := flag.CommandLine.Parse([]string{"-wait", "2m", "-offline", "tax.db.sync", "-e", "gbif"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c := command(); c != "tax.db.sync" {
+		t.Errorf("command %q, want %q", c, "tax.db.sync")
+	}
+}
+
+func TestLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatalf("unable to create temporal directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	Command = "test"
+	defer func() { Command = "" }()
+	changes := [][]Change{
+		{{"taxonomy", "a", "name", "", "a"}},
+		{{"taxonomy", "a", "rank", "", "genus"}, {"taxonomy", "b", "name", "", "b"}},
+		{{"dataset", "s", "title", "", "s"}},
+	}
+	for _, ch := range changes {
+		tx, err := Begin(dir)
+		if err != nil {
+			t.Fatalf("begin: unexpected error: %v", err)
+		}
+		tx.Log(ch...)
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("commit: unexpected error: %v", err)
+		}
+	}
+
+	ops, err := ReadLog(dir)
+	if err != nil {
+		t.Fatalf("read log: unexpected error: %v", err)
+	}
+	if len(ops) != len(changes) {
+		t.Fatalf("read log: got %d operations, want %d", len(ops), len(changes))
+	}
+	for i, o := range ops {
+		if o.Command != "test" {
+			t.Errorf("operation %d: command %q, want %q", i, o.Command, "test")
+		}
+		if !reflect.DeepEqual(o.Changes, changes[i]) {
+			t.Errorf("operation %d: changes %v, want %v", i, o.Changes, changes[i])
+		}
+	}
+
+	// undo the last operation
+	tx, err := Begin(dir)
+	if err != nil {
+		t.Fatalf("begin: unexpected error: %v", err)
+	}
+	tx.Reverts(ops[2].ID)
+	tx.Log(Change{"dataset", "s", "title", "s", ""})
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("commit: unexpected error: %v", err)
+	}
+	ops, err = ReadLog(dir)
+	if err != nil {
+		t.Fatalf("read log: unexpected error: %v", err)
+	}
+	u := Undoable(ops, 5)
+	if len(u) != 2 {
+		t.Fatalf("undoable: got %d operations, want %d", len(u), 2)
+	}
+	if u[0].ID != ops[0].ID || u[1].ID != ops[1].ID {
+		t.Errorf("undoable: got [%s %s], want [%s %s]", u[0].ID, u[1].ID, ops[0].ID, ops[1].ID)
+	}
+}
@@ -0,0 +1,330 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package biodv
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A Name is a scientific name
+// split in its components.
+//
+// For example,
+// the name "Puma concolor cougar (Kerr, 1792)"
+// is split as:
+//
+//	Name{
+//		Genus:     "Puma",
+//		Species:   "concolor",
+//		Infra:     "cougar",
+//		Rank:      Subspecies,
+//		BasAuthor: "Kerr",
+//		BasYear:   1792,
+//	}
+type Name struct {
+	// Genus is the genus of the name,
+	// or the name itself,
+	// if it is a name above the species.
+	Genus string
+
+	// Subgenus is the subgenus,
+	// written in parentheses
+	// after the genus.
+	Subgenus string
+
+	// Species is the specific epithet.
+	Species string
+
+	// Infra is the infraspecific epithet.
+	Infra string
+
+	// Rank is the rank implied by the name:
+	// species for binomials,
+	// the rank of the marker
+	// (e.g. 'var.')
+	// or subspecies,
+	// for infraspecific names,
+	// subgenus for a genus with a subgenus,
+	// and unranked for any other name.
+	Rank Rank
+
+	// Author is the author of the name,
+	// and Year the year of the name.
+	// In a new combination,
+	// it is the author of the combination.
+	Author string
+	Year   int
+
+	// BasAuthor is the author
+	// written in parentheses,
+	// i.e. the author of the basionym,
+	// or of the original combination,
+	// and BasYear is its year.
+	BasAuthor string
+	BasYear   int
+}
+
+// Name particles of authors
+// that are written in lower case.
+var authorParticles = map[string]bool{
+	"d'":  true,
+	"da":  true,
+	"de":  true,
+	"del": true,
+	"dem": true,
+	"den": true,
+	"der": true,
+	"des": true,
+	"di":  true,
+	"du":  true,
+	"la":  true,
+	"le":  true,
+	"ten": true,
+	"ter": true,
+	"van": true,
+	"von": true,
+	"zu":  true,
+	"zur": true,
+}
+
+// Words that start the authorship
+// of a name,
+// as in 'Aus bus sensu lato'.
+var authorMarkers = map[string]bool{
+	"auct":   true,
+	"auct.":  true,
+	"s.l.":   true,
+	"s.s.":   true,
+	"s.str.": true,
+	"sensu":  true,
+}
+
+// ParseName splits a scientific name
+// in its components.
+// Rank markers of infraspecific names
+// (e.g. 'subsp.', 'var.', or 'f.')
+// are used to set the rank of the name.
+func ParseName(s string) Name {
+	f := strings.Fields(s)
+	if len(f) == 0 {
+		return Name{}
+	}
+	n := Name{Genus: TaxCanon(f[0])}
+	i := 1
+
+	// epithets in upper case
+	// are only valid
+	// if the whole name is in upper case,
+	// otherwise they are authors
+	// (e.g. 'DC' in 'Aus bus DC').
+	caps := isUpper(f[0])
+
+	// subgenus,
+	// authors in parentheses
+	// are only used after an epithet.
+	if i < len(f) && isSubgenus(f[i]) {
+		n.Subgenus = TaxCanon(strings.Trim(f[i], "()"))
+		i++
+	}
+
+	// epithets
+	if i < len(f) && isEpithet(f[i], caps) && !isAuthor(f, i) {
+		n.Species = strings.ToLower(f[i])
+		i++
+		if i < len(f)-1 && isMarker(f[i]) && isEpithet(f[i+1], caps) {
+			n.Rank = GetRank(f[i])
+			n.Infra = strings.ToLower(f[i+1])
+			i += 2
+		} else if i < len(f) && isEpithet(f[i], caps) && !isAuthor(f, i) {
+			n.Rank = Subspecies
+			n.Infra = strings.ToLower(f[i])
+			i++
+		}
+	}
+	switch {
+	case n.Infra != "":
+	case n.Species != "":
+		n.Rank = Species
+	case n.Subgenus != "":
+		n.Rank = Subgenus
+	}
+
+	n.Author, n.Year, n.BasAuthor, n.BasYear = splitAuthorship(strings.Join(f[i:], " "))
+	return n
+}
+
+// IsSubgenus returns true
+// if a word is a subgenus name
+// in parentheses.
+func isSubgenus(w string) bool {
+	if len(w) < 3 || w[0] != '(' || w[len(w)-1] != ')' {
+		return false
+	}
+	w = w[1 : len(w)-1]
+	r, _ := utf8.DecodeRuneInString(w)
+	if !unicode.IsUpper(r) {
+		return false
+	}
+	for _, r := range w {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsEpithet returns true
+// if a word can be an epithet,
+// i.e. a word with only letters
+// (or hyphens),
+// that starts in lower case,
+// or, if caps is true,
+// is written completely in upper case.
+func isEpithet(w string, caps bool) bool {
+	r, _ := utf8.DecodeRuneInString(w)
+	if !unicode.IsLetter(r) {
+		return false
+	}
+	for _, r := range w {
+		if r != '-' && !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	if unicode.IsLower(r) {
+		return true
+	}
+	return caps && isUpper(w)
+}
+
+// IsUpper returns true
+// if a word of more than one letter
+// is written completely in upper case.
+func isUpper(w string) bool {
+	n := 0
+	for _, r := range w {
+		if unicode.IsLower(r) {
+			return false
+		}
+		if unicode.IsLetter(r) {
+			n++
+		}
+	}
+	return n > 1
+}
+
+// IsAuthor returns true
+// if the word at position i
+// starts the authorship of a name,
+// i.e. it is a marker
+// (e.g. 'sensu'),
+// or the particles of an author name
+// (e.g. 'de' in 'de Candolle',
+// or 'van der' in 'van der Berg').
+func isAuthor(f []string, i int) bool {
+	if authorMarkers[strings.ToLower(f[i])] {
+		return true
+	}
+	j := i
+	for j < len(f) && authorParticles[strings.ToLower(f[j])] {
+		j++
+	}
+	if j == i || j >= len(f) {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(f[j])
+	return unicode.IsUpper(r)
+}
+
+// IsMarker returns true
+// if a word is an infraspecific rank marker.
+func isMarker(w string) bool {
+	r := GetRank(w)
+	return r > Species
+}
+
+// SplitAuthorship splits an authorship string
+// into the author and year,
+// and the author and year
+// written in parentheses.
+func splitAuthorship(s string) (author string, year int, basAuthor string, basYear int) {
+	s = strings.Join(strings.Fields(s), " ")
+	if strings.HasPrefix(s, "(") {
+		if i := strings.Index(s, ")"); i > 0 {
+			basAuthor, basYear = splitYear(s[1:i])
+			s = s[i+1:]
+		}
+	}
+	author, year = splitYear(s)
+	return author, year, basAuthor, basYear
+}
+
+// SplitYear splits an author
+// and its year.
+func splitYear(s string) (string, int) {
+	s = strings.TrimSpace(s)
+	i := strings.LastIndexAny(s, " ,")
+	y := strings.Trim(s[i+1:], "()[].")
+	if len(y) != 4 {
+		return s, 0
+	}
+	year, err := strconv.Atoi(y)
+	if err != nil {
+		return s, 0
+	}
+	if i < 0 {
+		return "", year
+	}
+	return strings.TrimRight(s[:i], " ,"), year
+}
+
+// Canon returns the canonical form
+// of the name,
+// i.e. the name without the subgenus,
+// the rank markers,
+// and the authors.
+func (n Name) Canon() string {
+	if n.Species == "" && n.Subgenus != "" {
+		return n.Subgenus
+	}
+	name := n.Genus
+	if n.Species != "" {
+		name += " " + n.Species
+	}
+	if n.Infra != "" {
+		name += " " + n.Infra
+	}
+	return TaxCanon(name)
+}
+
+// Authorship returns the authors
+// and years of the name,
+// as they are usually written,
+// e.g. "(Kerr, 1792)",
+// or "(L.) Mill.".
+func (n Name) Authorship() string {
+	var a []string
+	if b := authorYear(n.BasAuthor, n.BasYear); b != "" {
+		a = append(a, "("+b+")")
+	}
+	if c := authorYear(n.Author, n.Year); c != "" {
+		a = append(a, c)
+	}
+	return strings.Join(a, " ")
+}
+
+func authorYear(author string, year int) string {
+	if year == 0 {
+		return author
+	}
+	if author == "" {
+		return strconv.Itoa(year)
+	}
+	return author + ", " + strconv.Itoa(year)
+}
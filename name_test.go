@@ -0,0 +1,59 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package biodv
+
+import "testing"
+
+func TestParseName(t *testing.T) {
+	testData := []struct {
+		text   string
+		name   Name
+		canon  string
+		author string
+	}{
+		{"", Name{}, "", ""},
+		{"Rheidae", Name{Genus: "Rheidae"}, "Rheidae", ""},
+		{"Rheidae Bonaparte, 1849", Name{Genus: "Rheidae", Author: "Bonaparte", Year: 1849}, "Rheidae", "Bonaparte, 1849"},
+		{"rhea americana", Name{Genus: "Rhea", Species: "americana", Rank: Species}, "Rhea americana", ""},
+		{"Rhea americana (Linnaeus, 1758)", Name{Genus: "Rhea", Species: "americana", Rank: Species, BasAuthor: "Linnaeus", BasYear: 1758}, "Rhea americana", "(Linnaeus, 1758)"},
+		{"Puma concolor cougar (Kerr, 1792)", Name{Genus: "Puma", Species: "concolor", Infra: "cougar", Rank: Subspecies, BasAuthor: "Kerr", BasYear: 1792}, "Puma concolor cougar", "(Kerr, 1792)"},
+		{"Quercus alba var. latiloba Sarg.", Name{Genus: "Quercus", Species: "alba", Infra: "latiloba", Rank: Variety, Author: "Sarg."}, "Quercus alba latiloba", "Sarg."},
+		{"Poa annua subsp. exilis (Tomm. ex Freyn) Asch. & Graebn.", Name{Genus: "Poa", Species: "annua", Infra: "exilis", Rank: Subspecies, Author: "Asch. & Graebn.", BasAuthor: "Tomm. ex Freyn"}, "Poa annua exilis", "(Tomm. ex Freyn) Asch. & Graebn."},
+		{"Crataegus monogyna f. laciniata (Stev.) Rehder", Name{Genus: "Crataegus", Species: "monogyna", Infra: "laciniata", Rank: Form, Author: "Rehder", BasAuthor: "Stev."}, "Crataegus monogyna laciniata", "(Stev.) Rehder"},
+		{"Bombus (Psithyrus) rupestris (Fabricius, 1793)", Name{Genus: "Bombus", Subgenus: "Psithyrus", Species: "rupestris", Rank: Species, BasAuthor: "Fabricius", BasYear: 1793}, "Bombus rupestris", "(Fabricius, 1793)"},
+		{"Bombus (Psithyrus)", Name{Genus: "Bombus", Subgenus: "Psithyrus", Rank: Subgenus}, "Psithyrus", ""},
+		{"Homo sapiens L.", Name{Genus: "Homo", Species: "sapiens", Rank: Species, Author: "L."}, "Homo sapiens", "L."},
+		{"Lupinus albus de Candolle", Name{Genus: "Lupinus", Species: "albus", Rank: Species, Author: "de Candolle"}, "Lupinus albus", "de Candolle"},
+		{"PAN TROGLODYTES", Name{Genus: "Pan", Species: "troglodytes", Rank: Species}, "Pan troglodytes", ""},
+		{"Aus bus DC", Name{Genus: "Aus", Species: "bus", Rank: Species, Author: "DC"}, "Aus bus", "DC"},
+		{"Aus bus L", Name{Genus: "Aus", Species: "bus", Rank: Species, Author: "L"}, "Aus bus", "L"},
+		{"Aus DC", Name{Genus: "Aus", Author: "DC"}, "Aus", "DC"},
+		{"AUS BUS CUS", Name{Genus: "Aus", Species: "bus", Infra: "cus", Rank: Subspecies}, "Aus bus cus", ""},
+		{"Aus bus van der Berg, 1900", Name{Genus: "Aus", Species: "bus", Rank: Species, Author: "van der Berg", Year: 1900}, "Aus bus", "van der Berg, 1900"},
+		{"Aus bus de la Cruz", Name{Genus: "Aus", Species: "bus", Rank: Species, Author: "de la Cruz"}, "Aus bus", "de la Cruz"},
+		{"Aus bus cus von dem Busche", Name{Genus: "Aus", Species: "bus", Infra: "cus", Rank: Subspecies, Author: "von dem Busche"}, "Aus bus cus", "von dem Busche"},
+		{"Aus bus sensu lato", Name{Genus: "Aus", Species: "bus", Rank: Species, Author: "sensu lato"}, "Aus bus", "sensu lato"},
+		{"Aus bus s.l.", Name{Genus: "Aus", Species: "bus", Rank: Species, Author: "s.l."}, "Aus bus", "s.l."},
+		{"Aus bus s.str.", Name{Genus: "Aus", Species: "bus", Rank: Species, Author: "s.str."}, "Aus bus", "s.str."},
+		{"Aus bus auct.", Name{Genus: "Aus", Species: "bus", Rank: Species, Author: "auct."}, "Aus bus", "auct."},
+		{"Aus (Bus) L.", Name{Genus: "Aus", Subgenus: "Bus", Rank: Subgenus, Author: "L."}, "Bus", "L."},
+		{"Aus (Bus) Smith, 1900", Name{Genus: "Aus", Subgenus: "Bus", Rank: Subgenus, Author: "Smith", Year: 1900}, "Bus", "Smith, 1900"},
+	}
+
+	for _, d := range testData {
+		n := ParseName(d.text)
+		if n != d.name {
+			t.Errorf("name %q: got %+v, want %+v", d.text, n, d.name)
+		}
+		if c := n.Canon(); c != d.canon {
+			t.Errorf("name %q: canon %q, want %q", d.text, c, d.canon)
+		}
+		if a := n.Authorship(); a != d.author {
+			t.Errorf("name %q: authorship %q, want %q", d.text, a, d.author)
+		}
+	}
+}
@@ -175,8 +175,8 @@ func (rsc *RecScan) Add(rec Record, err error) bool {
 		return false
 	}
 	if err != nil {
-		close(rsc.c)
 		rsc.err = err
+		close(rsc.c)
 		return true
 	}
 	rsc.c <- rec
@@ -250,6 +250,44 @@ func (rsc *RecScan) Scan() bool {
 	return true
 }
 
+// JoinCatalog returns a catalog number
+// that includes the institution
+// and collection codes,
+// in the form <institution>:<collection>:<catalog>.
+// Codes that are repeated,
+// or already included in the catalog number,
+// are omitted.
+func JoinCatalog(inst, coll, cat string) string {
+	if cat == "" {
+		return ""
+	}
+	if inst == "" {
+		return cat
+	}
+	if inst == coll {
+		coll = ""
+	}
+	if strings.HasPrefix(cat, coll) {
+		coll = ""
+	}
+	if strings.HasPrefix(cat, inst) {
+		inst = ""
+		coll = ""
+	}
+	if coll != "" && strings.HasPrefix(coll, inst) {
+		inst = ""
+	}
+
+	v := cat
+	if coll != "" {
+		v = coll + ":" + cat
+	}
+	if inst != "" {
+		v = inst + ":" + v
+	}
+	return v
+}
+
 // BasisOfRecord indicates the physic basis
 // of an specimen record.
 type BasisOfRecord uint
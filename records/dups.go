@@ -0,0 +1,262 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package records
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/js-arias/biodv"
+
+	"github.com/pkg/errors"
+)
+
+// DupDist is the distance,
+// in meters,
+// added to the uncertainty of two georeferences,
+// for the records to be taken as duplicates.
+var DupDist uint = 1000
+
+// Reasons for two records
+// to be taken as duplicates.
+const (
+	DupCatalog   = "catalog"   // equivalent catalog numbers
+	DupCollector = "collector" // same date and collector
+)
+
+// CatalogTokens returns the normalized tokens
+// of a catalog number,
+// i.e. the catalog is split in its alphanumeric parts,
+// letters are set in upper case,
+// and leading zeros of numbers are removed.
+// For example,
+// "MSU:MR:MR.08672" will be normalized
+// as ["MSU", "MR", "MR", "8672"].
+func CatalogTokens(cat string) []string {
+	f := strings.FieldsFunc(cat, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, t := range f {
+		t = strings.ToUpper(t)
+		if strings.IndexFunc(t, func(r rune) bool { return !unicode.IsDigit(r) }) < 0 {
+			if n := strings.TrimLeft(t, "0"); n != "" {
+				t = n
+			} else {
+				t = "0"
+			}
+		}
+		f[i] = t
+	}
+	return f
+}
+
+// SameCatalog returns true
+// if two catalog numbers are equivalent,
+// i.e. their normalized tokens are the same,
+// or the tokens of one catalog
+// are at the end of the other
+// (as in "MR 8672" and "MSU:MR:8672",
+// a common case of catalog numbers
+// with or without the institution code,
+// as built by the gbif driver).
+// As a bare number does not have
+// institution or collection tokens to match,
+// it is only equivalent
+// to the same bare number.
+// The last token must include a digit.
+func SameCatalog(a, b string) bool {
+	ta, tb := CatalogTokens(a), CatalogTokens(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return false
+	}
+	if len(ta) > len(tb) {
+		ta, tb = tb, ta
+	}
+	if strings.IndexFunc(ta[len(ta)-1], unicode.IsDigit) < 0 {
+		return false
+	}
+	if len(ta) == 1 && len(tb) > 1 {
+		return false
+	}
+	tb = tb[len(tb)-len(ta):]
+	for i, t := range ta {
+		if t != tb[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Duplicate returns the reason
+// by which two records are likely duplicates,
+// or an empty string
+// if the records are not duplicates.
+//
+// Two records are duplicates
+// if their catalog numbers are equivalent
+// (see SameCatalog),
+// or if they do not have catalog numbers
+// (or only one of them has it)
+// and they were collected at the same date
+// by the same collector.
+// In both cases,
+// if the records are georeferenced,
+// the maximum distance between the georeferences
+// must be smaller than twice its uncertainties
+// plus DupDist,
+// i.e. the areas defined by the uncertainties
+// (enlarged by DupDist)
+// overlap.
+func Duplicate(a, b biodv.Record) string {
+	if a.ID() == b.ID() {
+		return ""
+	}
+	if ga, gb := a.GeoRef(), b.GeoRef(); ga.IsValid() && gb.IsValid() {
+		if ga.MaxDist(gb) > 2*(ga.Uncertainty+gb.Uncertainty)+DupDist {
+			return ""
+		}
+	}
+
+	ca, cb := a.Value(biodv.RecCatalog), b.Value(biodv.RecCatalog)
+	if ca != "" && cb != "" {
+		if SameCatalog(ca, cb) {
+			return DupCatalog
+		}
+		return ""
+	}
+
+	ea, eb := a.CollEvent(), b.CollEvent()
+	if ea.Date.IsZero() || eb.Date.IsZero() {
+		return ""
+	}
+	ya, ma, da := ea.Date.Date()
+	yb, mb, db := eb.Date.Date()
+	if ya != yb || ma != mb || da != db {
+		return ""
+	}
+	if ea.Collector == "" || eb.Collector == "" {
+		return ""
+	}
+	if strings.Join(strings.Fields(strings.ToLower(ea.Collector)), " ") != strings.Join(strings.Fields(strings.ToLower(eb.Collector)), " ") {
+		return ""
+	}
+	return DupCollector
+}
+
+// Merge merges a duplicated record
+// into another record,
+// and removes the duplicated record
+// from the database.
+//
+// The values that are empty
+// in the kept record
+// are filled with the values
+// of the duplicated record,
+// and the extern IDs of both records
+// are kept.
+// If both records have an extern ID
+// from the same service,
+// it returns an error,
+// and the records are not changed.
+func (db *DB) Merge(id, dupID string) error {
+	rec := db.Record(id)
+	if rec == nil {
+		return errors.Errorf("records: db: merge: record %q not found", id)
+	}
+	dup := db.Record(dupID)
+	if dup == nil {
+		return errors.Errorf("records: db: merge: record %q not found", dupID)
+	}
+	if rec == dup {
+		return errors.Errorf("records: db: merge: record %q merged with itself", id)
+	}
+	ext := strings.Fields(dup.data[biodv.RecExtern])
+	for _, e := range ext {
+		if srv := getService(e); rec.hasService(srv) {
+			return errors.Errorf("records: db: merge: records %q and %q have different %s IDs", rec.ID(), dup.ID(), srv)
+		}
+	}
+
+	if rec.Basis() == biodv.UnknownBasis {
+		rec.Set(basisKey, dup.Basis().String())
+	}
+
+	ev, dEv := rec.CollEvent(), dup.CollEvent()
+	if ev.Date.IsZero() {
+		ev.Date = dEv.Date
+	}
+	if ev.Admin.Country == "" {
+		ev.Admin = dEv.Admin
+	} else if ev.CountryCode() == dEv.CountryCode() {
+		if ev.Admin.State == "" {
+			ev.Admin.State = dEv.Admin.State
+		}
+		if ev.Admin.County == "" {
+			ev.Admin.County = dEv.Admin.County
+		}
+	}
+	if ev.Locality == "" {
+		ev.Locality = dEv.Locality
+	}
+	if ev.Collector == "" {
+		ev.Collector = dEv.Collector
+	}
+	if ev.Z == 0 {
+		ev.Z = dEv.Z
+	}
+	rec.SetCollEvent(ev)
+
+	if !rec.GeoRef().IsValid() {
+		rec.SetGeoRef(dup.GeoRef())
+	}
+
+	for _, k := range dup.Keys() {
+		if k == biodv.RecExtern || k == biodv.RecCatalog {
+			continue
+		}
+		if rec.Value(k) != "" {
+			continue
+		}
+		if err := rec.Set(k, dup.Value(k)); err != nil {
+			return errors.Wrap(err, "records: db: merge")
+		}
+	}
+
+	// the IDs of the duplicated record
+	// are moved to the kept record
+	// before the duplicated record is removed.
+	if cat := dup.data[biodv.RecCatalog]; cat != "" && rec.Value(biodv.RecCatalog) == "" {
+		rec.data[biodv.RecCatalog] = cat
+		rec.taxon.sorted = false
+		db.ids[cat] = rec
+	}
+	if len(ext) > 0 {
+		for _, e := range ext {
+			db.ids[e] = rec
+		}
+		ext = append(ext, strings.Fields(rec.data[biodv.RecExtern])...)
+		sort.Strings(ext)
+		rec.data[biodv.RecExtern] = strings.Join(ext, " ")
+	}
+	rec.taxon.changed = true
+
+	db.remove(dup)
+	return nil
+}
+
+// HasService returns true
+// if the record has an extern ID
+// of the given service.
+func (rec *Record) hasService(srv string) bool {
+	for _, e := range strings.Fields(rec.data[biodv.RecExtern]) {
+		if getService(e) == srv {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,332 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package records
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/js-arias/biodv"
+	"github.com/js-arias/biodv/encoding/dwca"
+	"github.com/js-arias/biodv/geography"
+
+	"github.com/pkg/errors"
+)
+
+// ArchiveScanner scans records
+// from the core of a Darwin Core Archive.
+//
+// The Darwin Core terms
+// are mapped to the record keys,
+// other terms are ignored.
+type ArchiveScanner struct {
+	sc    *dwca.Scanner
+	rec   map[string]string
+	names []string
+	err   error
+}
+
+// NewArchiveScanner returns a scanner
+// that reads records
+// from the core of a Darwin Core Archive.
+func NewArchiveScanner(a *dwca.Archive) *ArchiveScanner {
+	if a.Core == nil {
+		return &ArchiveScanner{err: errors.New("records: archive scanner: archive without core")}
+	}
+	if n := a.Core.Name(); n != "" && n != "Occurrence" {
+		return &ArchiveScanner{err: errors.Errorf("records: archive scanner: core %q is not an occurrence core", n)}
+	}
+	return &ArchiveScanner{sc: a.Scanner(a.Core)}
+}
+
+// Close closes the scanner,
+// preventing further enumeration.
+//
+// If Scan returns false,
+// the scanner is closed automatically
+// and it will suffice to check the result of Err.
+func (sc *ArchiveScanner) Close() {
+	if sc.err == io.EOF {
+		return
+	}
+	if sc.sc != nil {
+		sc.sc.Close()
+	}
+	sc.err = io.EOF
+}
+
+// Err returns the error,
+// if any,
+// that was encountered during iteration.
+func (sc *ArchiveScanner) Err() error {
+	if sc.err == io.EOF {
+		return nil
+	}
+	return sc.err
+}
+
+// Names returns the names
+// that can be used to search
+// the taxon of the last read record,
+// from the more specific
+// (the scientific name as given in the archive)
+// to the less specific
+// (i.e. the genus and epithets).
+//
+// It should be called
+// after a call to Scan.
+func (sc *ArchiveScanner) Names() []string {
+	return sc.names
+}
+
+// Record returns the last read record.
+// The taxon of the record
+// is the scientific name of the record
+// without the authorship.
+//
+// Every call to Record must be preceded
+// by a call to Scan.
+func (sc *ArchiveScanner) Record() biodv.Record {
+	if sc.err != nil {
+		panic("records: archive scanner: accessing a closed scanner")
+	}
+	if sc.rec == nil {
+		panic("records: archive scanner: calling Record without a Scan call")
+	}
+	rec := recmap(sc.rec)
+	sc.rec = nil
+	return rec
+}
+
+// Scan advances the scanner to the next record.
+// It returns false when there is no more records,
+// or an error happens when preparing it.
+// Err should be consulted to distinguish
+// between the two cases.
+//
+// Records without a scientific name
+// are ignored.
+//
+// Every call to Record,
+// even the first one,
+// must be preceded by a call to Scan.
+func (sc *ArchiveScanner) Scan() bool {
+	if sc.err != nil {
+		return false
+	}
+	for sc.sc.Scan() {
+		row := sc.sc.Record()
+		names := archiveNames(row)
+		if len(names) == 0 {
+			continue
+		}
+		rec := archiveRecord(row)
+		if rec[idKey] == "" {
+			continue
+		}
+		rec[taxonKey] = names[0]
+		if len(names) > 1 {
+			rec[taxonKey] = names[1]
+		}
+		sc.names = names
+		sc.rec = rec
+		return true
+	}
+	if err := sc.sc.Err(); err != nil {
+		sc.Close()
+		sc.err = errors.Wrap(err, "records: archive scanner")
+		return false
+	}
+	sc.Close()
+	return false
+}
+
+// ArchiveNames returns the candidate names
+// of a Darwin Core row.
+func archiveNames(row map[string]string) []string {
+	var names []string
+	add := func(nm string) {
+		nm = strings.Join(strings.Fields(nm), " ")
+		if nm == "" {
+			return
+		}
+		for _, n := range names {
+			if n == nm {
+				return
+			}
+		}
+		names = append(names, nm)
+	}
+
+	sn := strings.Join(strings.Fields(row["scientificName"]), " ")
+	add(sn)
+	if auth := strings.Join(strings.Fields(row["scientificNameAuthorship"]), " "); auth != "" && strings.HasSuffix(sn, auth) {
+		add(strings.TrimSuffix(sn, auth))
+	}
+	add(stripAuthor(sn))
+
+	if gen := row["genus"]; gen != "" && row["specificEpithet"] != "" {
+		nm := gen + " " + row["specificEpithet"]
+		if inf := row["infraspecificEpithet"]; inf != "" {
+			add(nm + " " + inf)
+		}
+		add(nm)
+	}
+	return names
+}
+
+// StripAuthor removes the author
+// from a scientific name,
+// i.e. any word,
+// after the first one,
+// that starts with an upper case letter,
+// a parenthesis,
+// or a digit.
+func stripAuthor(name string) string {
+	f := strings.Fields(name)
+	if len(f) == 0 {
+		return ""
+	}
+	nm := []string{f[0]}
+	for _, w := range f[1:] {
+		r, _ := utf8.DecodeRuneInString(w)
+		if unicode.IsUpper(r) || unicode.IsDigit(r) || r == '(' || r == '&' {
+			break
+		}
+		nm = append(nm, w)
+	}
+	return strings.Join(nm, " ")
+}
+
+// ArchiveBasis translates the Darwin Core
+// basis of record terms.
+var archiveBasis = map[string]biodv.BasisOfRecord{
+	"preservedspecimen":  biodv.Preserved,
+	"fossilspecimen":     biodv.Fossil,
+	"humanobservation":   biodv.Observation,
+	"observation":        biodv.Observation,
+	"machineobservation": biodv.Machine,
+}
+
+// ArchiveKeys are the Darwin Core terms
+// stored as additional keys of a record.
+var archiveKeys = map[string]string{
+	"identifiedBy":         biodv.RecDeterm,
+	"organismID":           biodv.RecOrganism,
+	"sex":                  biodv.RecSex,
+	"lifeStage":            biodv.RecStage,
+	"occurrenceRemarks":    biodv.RecComment,
+	"associatedReferences": biodv.RecRef,
+}
+
+// ArchiveRecord maps the Darwin Core terms
+// of a row into a record.
+func archiveRecord(row map[string]string) map[string]string {
+	rec := make(map[string]string)
+
+	rec[idKey] = row["occurrenceID"]
+	if rec[idKey] == "" {
+		rec[idKey] = row[dwca.IDKey]
+	}
+	if cat := biodv.JoinCatalog(row["institutionCode"], row["collectionCode"], row["catalogNumber"]); cat != "" {
+		rec[biodv.RecCatalog] = cat
+	}
+
+	basis := biodv.GetBasis(row["basisOfRecord"])
+	if b, ok := archiveBasis[strings.ToLower(row["basisOfRecord"])]; ok {
+		basis = b
+	}
+	rec[basisKey] = basis.String()
+
+	if t := archiveDate(row); !t.IsZero() {
+		rec[dateKey] = t.Format(time.RFC3339)
+	}
+	if cc := row["countryCode"]; geography.IsValidCode(cc) {
+		rec[countryKey] = strings.ToUpper(cc)
+		if v := row["stateProvince"]; v != "" {
+			rec[stateKey] = v
+		}
+		if v := row["county"]; v != "" {
+			rec[countyKey] = v
+		}
+	}
+	if v := row["locality"]; v != "" {
+		rec[localityKey] = v
+	}
+	if v := row["recordedBy"]; v != "" {
+		rec[collectorKey] = v
+	}
+
+	lat, e1 := strconv.ParseFloat(row["decimalLatitude"], 64)
+	lon, e2 := strconv.ParseFloat(row["decimalLongitude"], 64)
+	if e1 == nil && e2 == nil {
+		storeLatLon(rec, lat, lon)
+	}
+	if _, ok := rec[latlonKey]; ok {
+		if un, err := strconv.ParseFloat(row["coordinateUncertaintyInMeters"], 64); err == nil && un >= 1 {
+			rec[uncertaintyKey] = strconv.Itoa(int(un))
+		}
+		if v := row["georeferenceSources"]; v != "" {
+			rec[geosourceKey] = v
+		}
+		if v := row["georeferenceVerificationStatus"]; v != "" {
+			rec[validationKey] = v
+		}
+	}
+	if elv, err := strconv.ParseFloat(row["minimumElevationInMeters"], 64); err == nil && elv >= 1 {
+		rec[elevationKey] = strconv.Itoa(int(elv))
+	}
+
+	for t, k := range archiveKeys {
+		if v := row[t]; v != "" {
+			rec[k] = v
+		}
+	}
+	return rec
+}
+
+// ArchiveDate returns the date
+// of a Darwin Core row.
+func archiveDate(row map[string]string) time.Time {
+	if ev := row["eventDate"]; ev != "" {
+		// if the date is an interval
+		// use the first date.
+		if i := strings.Index(ev, "/"); i > 0 {
+			ev = ev[:i]
+		}
+		for _, l := range []string{
+			time.RFC3339,
+			"2006-01-02T15:04:05",
+			"2006-01-02T15:04",
+			"2006-01-02",
+			"2006-01",
+			"2006",
+		} {
+			if t, err := time.Parse(l, ev); err == nil {
+				return t
+			}
+		}
+	}
+
+	y, _ := strconv.Atoi(row["year"])
+	if y == 0 {
+		return time.Time{}
+	}
+	m, _ := strconv.Atoi(row["month"])
+	if m < 1 || m > 12 {
+		m = 1
+	}
+	d, _ := strconv.Atoi(row["day"])
+	if d < 1 || d > 31 {
+		d = 1
+	}
+	return time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package records
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/js-arias/biodv"
+)
+
+func TestArchiveNames(t *testing.T) {
+	rows := []struct {
+		row   map[string]string
+		names []string
+	}{
+		{
+			map[string]string{"scientificName": "Puma concolor"},
+			[]string{"Puma concolor"},
+		},
+		{
+			map[string]string{
+				"scientificName":           "Puma concolor (Linnaeus, 1771)",
+				"scientificNameAuthorship": "(Linnaeus, 1771)",
+			},
+			[]string{"Puma concolor (Linnaeus, 1771)", "Puma concolor"},
+		},
+		{
+			map[string]string{
+				"scientificName":       "Felis concolor couguar Kerr, 1792",
+				"genus":                "Puma",
+				"specificEpithet":      "concolor",
+				"infraspecificEpithet": "couguar",
+			},
+			[]string{"Felis concolor couguar Kerr, 1792", "Felis concolor couguar", "Puma concolor couguar", "Puma concolor"},
+		},
+	}
+
+	for _, r := range rows {
+		names := archiveNames(r.row)
+		if !reflect.DeepEqual(names, r.names) {
+			t.Errorf("names %q, want %q", names, r.names)
+		}
+	}
+}
+
+func TestArchiveRecord(t *testing.T) {
+	row := map[string]string{
+		"id":                            "919431660",
+		"basisOfRecord":                 "PreservedSpecimen",
+		"institutionCode":               "MSU",
+		"collectionCode":                "MR",
+		"catalogNumber":                 "MR.8672",
+		"eventDate":                     "1911-05-09/1911-05-12",
+		"countryCode":                   "EC",
+		"stateProvince":                 "El Oro",
+		"decimalLatitude":               "-3.25",
+		"decimalLongitude":              "-79.8333",
+		"coordinateUncertaintyInMeters": "5000.0",
+		"recordedBy":                    "W. B. Richardson",
+		"sex":                           "female",
+	}
+	rec := recmap(archiveRecord(row))
+	if rec.ID() != "919431660" {
+		t.Errorf("id %q, want %q", rec.ID(), "919431660")
+	}
+	if rec.Basis() != biodv.Preserved {
+		t.Errorf("basis %v, want %v", rec.Basis(), biodv.Preserved)
+	}
+	if c := rec.Value(biodv.RecCatalog); c != "MSU:MR.8672" {
+		t.Errorf("catalog %q, want %q", c, "MSU:MR.8672")
+	}
+	ev := rec.CollEvent()
+	if want := time.Date(1911, 5, 9, 0, 0, 0, 0, time.UTC); !ev.Date.Equal(want) {
+		t.Errorf("date %v, want %v", ev.Date, want)
+	}
+	if ev.CountryCode() != "EC" {
+		t.Errorf("country %q, want %q", ev.CountryCode(), "EC")
+	}
+	if ev.State() != "El Oro" {
+		t.Errorf("state %q, want %q", ev.State(), "El Oro")
+	}
+	if ev.Collector != "W. B. Richardson" {
+		t.Errorf("collector %q, want %q", ev.Collector, "W. B. Richardson")
+	}
+	geo := rec.GeoRef()
+	if !geo.IsValid() {
+		t.Errorf("invalid georeference")
+	}
+	if geo.Uncertainty != 5000 {
+		t.Errorf("uncertainty %d, want %d", geo.Uncertainty, 5000)
+	}
+	if s := rec.Value(biodv.RecSex); s != "female" {
+		t.Errorf("sex %q, want %q", s, "female")
+	}
+}
@@ -0,0 +1,495 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package records
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/js-arias/biodv"
+
+	"github.com/pkg/errors"
+)
+
+// A Filter is a compiled filter expression
+// used to select records.
+//
+// A filter expression is made of terms,
+// joined by the logical operators
+// AND, OR, and NOT
+// (case insensitive),
+// and grouped with parenthesis.
+// AND has precedence over OR.
+//
+// A term is a field name,
+// an operator,
+// and a value,
+// for example:
+//
+//	basis=preserved
+//	country=AR
+//	date>=1990
+//	uncertainty<5000
+//	locality~"san miguel"
+//
+// Valid operators are
+// '=', '!=', '<', '<=', '>', '>=',
+// and '~' (contains).
+// Values with spaces
+// or operator characters
+// must be quoted.
+// String comparisons are case insensitive,
+// and if both values are numbers,
+// they are compared as numbers.
+//
+// A field without operator and value
+// is true if the field is defined
+// in the record.
+// The special field 'georef'
+// is true if the record has a valid georeference.
+//
+// Field names are the names used
+// in the records database
+// (for example 'catalog' or 'collector'),
+// with the additional fields
+// 'lat' and 'lon'
+// for the latitude and longitude.
+// Dates can be given as a year ('1990'),
+// a year and month ('1990-05'),
+// or a full date ('1990-05-12').
+// The basis of record is compared
+// using its canonical name.
+// For fields with a list of values
+// (as 'flags' or 'extern'),
+// '=' and '!=' compare against each element
+// of the list.
+type Filter struct {
+	root node
+}
+
+// ParseFilter compiles a filter expression.
+func ParseFilter(expr string) (*Filter, error) {
+	toks, err := lexFilter(expr)
+	if err != nil {
+		return nil, errors.Wrap(err, "records: filter")
+	}
+	if len(toks) == 0 {
+		return nil, errors.New("records: filter: empty expression")
+	}
+	p := &parser{toks: toks}
+	n, err := p.or()
+	if err != nil {
+		return nil, errors.Wrap(err, "records: filter")
+	}
+	if p.pos < len(p.toks) {
+		return nil, errors.Errorf("records: filter: unexpected %q", p.toks[p.pos].val)
+	}
+	return &Filter{root: n}, nil
+}
+
+// Match returns true
+// if a record is selected by the filter.
+// A nil filter matches all records.
+func (f *Filter) Match(r biodv.Record) bool {
+	if f == nil {
+		return true
+	}
+	return f.root.eval(r)
+}
+
+// Node is an element of a filter expression.
+type node interface {
+	eval(r biodv.Record) bool
+}
+
+type andNode struct{ a, b node }
+
+func (n andNode) eval(r biodv.Record) bool { return n.a.eval(r) && n.b.eval(r) }
+
+type orNode struct{ a, b node }
+
+func (n orNode) eval(r biodv.Record) bool { return n.a.eval(r) || n.b.eval(r) }
+
+type notNode struct{ a node }
+
+func (n notNode) eval(r biodv.Record) bool { return !n.a.eval(r) }
+
+// Token kinds of a filter expression.
+const (
+	tokWord = iota
+	tokOp
+	tokOpen
+	tokClose
+)
+
+type token struct {
+	kind int
+	val  string
+}
+
+// IsOpChar returns true
+// if a rune is part of a comparison operator.
+func isOpChar(r rune) bool {
+	return r == '=' || r == '!' || r == '<' || r == '>' || r == '~'
+}
+
+// LexFilter splits a filter expression
+// in tokens.
+func lexFilter(expr string) ([]token, error) {
+	var toks []token
+	rs := []rune(expr)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			toks = append(toks, token{tokOpen, "("})
+			i++
+		case r == ')':
+			toks = append(toks, token{tokClose, ")"})
+			i++
+		case isOpChar(r):
+			j := i + 1
+			for j < len(rs) && isOpChar(rs[j]) {
+				j++
+			}
+			op := string(rs[i:j])
+			switch op {
+			case "=", "!=", "<", "<=", ">", ">=", "~":
+			default:
+				return nil, errors.Errorf("invalid operator %q", op)
+			}
+			toks = append(toks, token{tokOp, op})
+			i = j
+		case r == '"':
+			j := i + 1
+			for j < len(rs) && rs[j] != '"' {
+				j++
+			}
+			if j == len(rs) {
+				return nil, errors.New("unterminated string")
+			}
+			toks = append(toks, token{tokWord, string(rs[i+1 : j])})
+			i = j + 1
+		default:
+			j := i
+			for j < len(rs) && !unicode.IsSpace(rs[j]) && !isOpChar(rs[j]) && rs[j] != '(' && rs[j] != ')' && rs[j] != '"' {
+				j++
+			}
+			toks = append(toks, token{tokWord, string(rs[i:j])})
+			i = j
+		}
+	}
+	return toks, nil
+}
+
+// Parser is a recursive descent parser
+// for filter expressions.
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() *token {
+	if p.pos >= len(p.toks) {
+		return nil
+	}
+	return &p.toks[p.pos]
+}
+
+// IsKeyword returns true
+// if the next token is the given keyword.
+func (p *parser) isKeyword(kw string) bool {
+	t := p.peek()
+	return t != nil && t.kind == tokWord && strings.EqualFold(t.val, kw)
+}
+
+func (p *parser) or() (node, error) {
+	n, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		p.pos++
+		b, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		n = orNode{n, b}
+	}
+	return n, nil
+}
+
+func (p *parser) and() (node, error) {
+	n, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		p.pos++
+		b, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		n = andNode{n, b}
+	}
+	return n, nil
+}
+
+func (p *parser) not() (node, error) {
+	if p.isKeyword("not") {
+		p.pos++
+		n, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, errors.New("unexpected end of expression")
+	}
+	switch t.kind {
+	case tokOpen:
+		p.pos++
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.peek(); t == nil || t.kind != tokClose {
+			return nil, errors.New("missing ')'")
+		}
+		p.pos++
+		return n, nil
+	case tokWord:
+		p.pos++
+		field := strings.ToLower(t.val)
+		op := p.peek()
+		if op == nil || op.kind != tokOp {
+			return existNode{field}, nil
+		}
+		p.pos++
+		v := p.peek()
+		if v == nil || v.kind != tokWord {
+			return nil, errors.Errorf("expecting value for %q", field)
+		}
+		p.pos++
+		return newTerm(field, op.val, v.val)
+	}
+	return nil, errors.Errorf("unexpected %q", t.val)
+}
+
+// ExistNode is a field without value.
+type existNode struct {
+	field string
+}
+
+func (n existNode) eval(r biodv.Record) bool {
+	if n.field == "georef" {
+		return r.GeoRef().IsValid()
+	}
+	_, ok := fieldValue(r, n.field)
+	return ok
+}
+
+// Term is a comparison
+// between a field and a value.
+type term struct {
+	field string
+	op    string
+	val   string
+
+	// for date fields
+	start, end time.Time
+}
+
+// NewTerm returns a new comparison term.
+func newTerm(field, op, val string) (node, error) {
+	t := &term{field: field, op: op, val: val}
+	switch field {
+	case "georef":
+		return nil, errors.Errorf("field %q does not accept a value", field)
+	case dateKey:
+		var err error
+		t.start, t.end, err = dateRange(val)
+		if err != nil {
+			return nil, err
+		}
+	case basisKey:
+		t.val = biodv.GetBasis(val).String()
+	}
+	return t, nil
+}
+
+// DateRange returns the time range
+// defined by a date value.
+func dateRange(val string) (start, end time.Time, err error) {
+	for _, l := range []struct {
+		layout  string
+		y, m, d int
+	}{
+		{"2006-01-02", 0, 0, 1},
+		{"2006-01", 0, 1, 0},
+		{"2006", 1, 0, 0},
+	} {
+		start, err = time.Parse(l.layout, val)
+		if err == nil {
+			return start, start.AddDate(l.y, l.m, l.d), nil
+		}
+	}
+	return start, end, errors.Errorf("invalid date %q", val)
+}
+
+func (t *term) eval(r biodv.Record) bool {
+	if t.field == dateKey {
+		d := r.CollEvent().Date
+		if d.IsZero() {
+			return false
+		}
+		switch t.op {
+		case "=":
+			return !d.Before(t.start) && d.Before(t.end)
+		case "!=":
+			return d.Before(t.start) || !d.Before(t.end)
+		case "<":
+			return d.Before(t.start)
+		case "<=":
+			return d.Before(t.end)
+		case ">":
+			return !d.Before(t.end)
+		case ">=":
+			return !d.Before(t.start)
+		}
+		return false
+	}
+
+	v, ok := fieldValue(r, t.field)
+	if !ok {
+		return t.op == "!="
+	}
+	if t.field == biodv.RecFlags || t.field == biodv.RecExtern {
+		if t.op == "=" || t.op == "!=" {
+			found := false
+			for _, e := range strings.Fields(v) {
+				if strings.EqualFold(e, t.val) {
+					found = true
+					break
+				}
+			}
+			return found == (t.op == "=")
+		}
+	}
+	if t.op == "~" {
+		return strings.Contains(strings.ToLower(v), strings.ToLower(t.val))
+	}
+	return compare(v, t.val, t.op)
+}
+
+// Compare compares two values
+// using the given operator.
+func compare(a, b, op string) bool {
+	c := 0
+	x, errA := strconv.ParseFloat(a, 64)
+	y, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		if x < y {
+			c = -1
+		} else if x > y {
+			c = 1
+		}
+	} else {
+		c = strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	}
+	switch op {
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+// FieldValue returns the value
+// of a field of a record,
+// and true if the field is defined.
+func fieldValue(r biodv.Record, field string) (string, bool) {
+	var v string
+	switch field {
+	case idKey:
+		v = r.ID()
+	case taxonKey:
+		v = r.Taxon()
+	case basisKey:
+		if b := r.Basis(); b != biodv.UnknownBasis {
+			v = b.String()
+		}
+	case dateKey:
+		if d := r.CollEvent().Date; !d.IsZero() {
+			v = d.Format(time.RFC3339)
+		}
+	case countryKey:
+		v = r.CollEvent().CountryCode()
+	case stateKey:
+		v = r.CollEvent().State()
+	case countyKey:
+		v = r.CollEvent().County()
+	case localityKey:
+		v = r.CollEvent().Locality
+	case collectorKey:
+		v = r.CollEvent().Collector
+	case zKey:
+		if z := r.CollEvent().Z; z != 0 {
+			v = strconv.Itoa(z)
+		}
+	case "lat", "lon", latlonKey, uncertaintyKey, elevationKey, geosourceKey, validationKey:
+		geo := r.GeoRef()
+		if !geo.IsValid() {
+			return "", false
+		}
+		switch field {
+		case "lat":
+			v = strconv.FormatFloat(geo.Lat, 'f', -1, 64)
+		case "lon":
+			v = strconv.FormatFloat(geo.Lon, 'f', -1, 64)
+		case latlonKey:
+			v = strconv.FormatFloat(geo.Lat, 'f', -1, 64) + " " + strconv.FormatFloat(geo.Lon, 'f', -1, 64)
+		// zero uncertainty or elevation
+		// means the value is not recorded.
+		case uncertaintyKey:
+			if geo.Uncertainty != 0 {
+				v = strconv.Itoa(int(geo.Uncertainty))
+			}
+		case elevationKey:
+			if geo.Elevation != 0 {
+				v = strconv.Itoa(int(geo.Elevation))
+			}
+		case geosourceKey:
+			v = geo.Source
+		case validationKey:
+			v = geo.Validation
+		}
+		return v, v != ""
+	default:
+		v = r.Value(field)
+	}
+	return v, v != ""
+}
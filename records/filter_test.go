@@ -0,0 +1,107 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package records
+
+import "testing"
+
+func TestFilter(t *testing.T) {
+	rec := recmap{
+		taxonKey:       "Rhea americana",
+		idKey:          "MLP:8672",
+		basisKey:       "preserved",
+		dateKey:        "1998-11-06T00:00:00Z",
+		countryKey:     "AR",
+		localityKey:    "San Miguel de Tucuman",
+		latlonKey:      "-26.82 -65.22",
+		uncertaintyKey: "1000",
+		"flags":        "capital precision",
+	}
+	noGeo := recmap{
+		taxonKey: "Rhea americana",
+		idKey:    "MLP:8673",
+		basisKey: "observation",
+	}
+
+	testData := []struct {
+		expr  string
+		rec   bool
+		noGeo bool
+	}{
+		{"basis=preserved AND country=AR AND date>=1990 AND georef AND uncertainty<5000", true, false},
+		{"basis=preserved", true, false},
+		{"basis=PRESERVED", true, false},
+		{"country=ar", true, false},
+		{"date=1998-11", true, false},
+		{"date<1998", false, false},
+		{"date<=1998", true, false},
+		{"date>1998-11-06", false, false},
+		{"not georef", false, true},
+		{"locality~\"miguel de\"", true, false},
+		{"flags=capital", true, false},
+		{"flags!=sea", true, true},
+		{"lat<-20 and lon>-70", true, false},
+		{"basis=observation or (country=AR and uncertainty<=1000)", true, true},
+		{"NOT (country=AR OR basis=observation)", false, false},
+		{"collector", false, false},
+		{"taxon=\"rhea americana\"", true, true},
+	}
+	for _, d := range testData {
+		f, err := ParseFilter(d.expr)
+		if err != nil {
+			t.Errorf("filter %q: unexpected error: %v", d.expr, err)
+			continue
+		}
+		if m := f.Match(rec); m != d.rec {
+			t.Errorf("filter %q: record %s: match %v, want %v", d.expr, rec.ID(), m, d.rec)
+		}
+		if m := f.Match(noGeo); m != d.noGeo {
+			t.Errorf("filter %q: record %s: match %v, want %v", d.expr, noGeo.ID(), m, d.noGeo)
+		}
+	}
+
+	// a georeferenced record
+	// without recorded uncertainty or elevation
+	noUncert := recmap{
+		taxonKey:  "Rhea americana",
+		idKey:     "MLP:8674",
+		basisKey:  "preserved",
+		latlonKey: "-26.82 -65.22",
+	}
+	for _, d := range []struct {
+		expr string
+		want bool
+	}{
+		{"uncertainty<5000", false},
+		{"not uncertainty", true},
+		{"elevation<100", false},
+		{"georef", true},
+	} {
+		f, err := ParseFilter(d.expr)
+		if err != nil {
+			t.Errorf("filter %q: unexpected error: %v", d.expr, err)
+			continue
+		}
+		if m := f.Match(noUncert); m != d.want {
+			t.Errorf("filter %q: record %s: match %v, want %v", d.expr, noUncert.ID(), m, d.want)
+		}
+	}
+
+	for _, expr := range []string{
+		"",
+		"country=",
+		"(country=AR",
+		"country=AR)",
+		"country=>AR",
+		"date>=yesterday",
+		"georef=true",
+		"locality~\"san",
+	} {
+		if _, err := ParseFilter(expr); err == nil {
+			t.Errorf("filter %q: expecting error", expr)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package outlier implements methods
+// to detect geographic outliers
+// in a set of georeferences,
+// usually, the specimen records of a taxon.
+//
+// All methods are based on the distance
+// of each point to its nearest neighbour.
+package outlier
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/js-arias/biodv/geography"
+)
+
+// Flag is the flag used to mark
+// a record as a geographic outlier.
+const Flag = "outlier"
+
+// MinPoints is the minimum number of points
+// required to search for outliers
+// with the MAD method.
+var MinPoints = 7
+
+// NNDist returns the distance,
+// in meters,
+// of each point to its nearest neighbour.
+// If there is only a single point,
+// its distance will be 0.
+func NNDist(pts []geography.Position) []uint {
+	dist := make([]uint, len(pts))
+	if len(pts) < 2 {
+		return dist
+	}
+	for i, p := range pts {
+		min := ^uint(0)
+		for j, op := range pts {
+			if i == j {
+				continue
+			}
+			if d := p.Distance(op); d < min {
+				min = d
+			}
+		}
+		dist[i] = min
+	}
+	return dist
+}
+
+// MAD returns the index
+// of the points that are outliers
+// using the median absolute deviation
+// of the nearest neighbour distances.
+// A point is an outlier
+// if its nearest neighbour distance
+// is greater than the median
+// plus k times the median absolute deviation.
+//
+// If there are less than MinPoints points,
+// or the median absolute deviation is 0,
+// it returns nil.
+func MAD(pts []geography.Position, k float64) []int {
+	if len(pts) < MinPoints {
+		return nil
+	}
+	dist := NNDist(pts)
+	ds := make([]float64, len(dist))
+	for i, d := range dist {
+		ds[i] = float64(d)
+	}
+	m := median(ds)
+	dev := make([]float64, len(ds))
+	for i, d := range ds {
+		dev[i] = d - m
+		if dev[i] < 0 {
+			dev[i] = -dev[i]
+		}
+	}
+	mad := median(dev)
+	if mad == 0 {
+		return nil
+	}
+
+	var out []int
+	for i, d := range ds {
+		if d > m+k*mad {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// Distance returns the index
+// of the points that are outliers
+// using a fixed distance threshold,
+// in meters,
+// i.e. the points that are farther
+// than the threshold
+// from its nearest neighbour.
+//
+// If there are less than two points,
+// it returns nil.
+func Distance(pts []geography.Position, max uint) []int {
+	if len(pts) < 2 {
+		return nil
+	}
+	var out []int
+	for i, d := range NNDist(pts) {
+		if d > max {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// Median returns the median
+// of a set of values.
+func median(v []float64) float64 {
+	s := append([]float64(nil), v...)
+	sort.Float64s(s)
+	n := len(s)
+	if n%2 == 1 {
+		return s[n/2]
+	}
+	return (s[n/2-1] + s[n/2]) / 2
+}
+
+// Update returns a flags value
+// (i.e. a list of flags separated by spaces)
+// in which the outlier flag
+// is set or removed.
+// Other flags are kept.
+func Update(value string, outlier bool) string {
+	var ls []string
+	for _, f := range strings.Fields(value) {
+		if strings.ToLower(f) == Flag {
+			continue
+		}
+		ls = append(ls, f)
+	}
+	if outlier {
+		ls = append(ls, Flag)
+	}
+	sort.Strings(ls)
+	return strings.Join(ls, " ")
+}
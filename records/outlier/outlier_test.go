@@ -0,0 +1,53 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package outlier
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/js-arias/biodv/geography"
+)
+
+var points = []geography.Position{
+	{Lat: -26.82, Lon: -65.22},
+	{Lat: -26.90, Lon: -65.30},
+	{Lat: -27.10, Lon: -65.40},
+	{Lat: -26.70, Lon: -65.10},
+	{Lat: -27.30, Lon: -65.60},
+	{Lat: -26.50, Lon: -65.00},
+	{Lat: -27.00, Lon: -64.90},
+	{Lat: -26.60, Lon: -65.50},
+	{Lat: 40.42, Lon: -3.70}, // Madrid
+}
+
+func TestMAD(t *testing.T) {
+	if got := MAD(points, 5); !reflect.DeepEqual(got, []int{8}) {
+		t.Errorf("mad: outliers %v, want %v", got, []int{8})
+	}
+	if got := MAD(points[:5], 5); got != nil {
+		t.Errorf("mad with few points: outliers %v, want none", got)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	if got := Distance(points, 1000000); !reflect.DeepEqual(got, []int{8}) {
+		t.Errorf("distance: outliers %v, want %v", got, []int{8})
+	}
+	if got := Distance(points, 10000); len(got) != len(points) {
+		t.Errorf("distance: outliers %v, want all", got)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	if v := Update("sea zero", true); v != "outlier sea zero" {
+		t.Errorf("update %q, want %q", v, "outlier sea zero")
+	}
+	if v := Update("outlier sea", false); v != "sea" {
+		t.Errorf("update %q, want %q", v, "sea")
+	}
+}
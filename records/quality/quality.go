@@ -0,0 +1,254 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+// Package quality implements tests
+// to detect common problems
+// in the georeferences of specimen records,
+// modeled after the tests
+// of the CoordinateCleaner R package.
+//
+// The tests that use country borders
+// (swapped, inverted, centroid, and sea)
+// use the borders loaded in the geography package,
+// or its default world borders
+// (see geography.LoadBorders).
+package quality
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/biodv"
+	"github.com/js-arias/biodv/geography"
+)
+
+// Flags of the quality tests.
+const (
+	Zero      = "zero"      // zero latitude and longitude
+	Equal     = "equal"     // equal absolute latitude and longitude
+	Swapped   = "swapped"   // latitude and longitude swapped
+	Inverted  = "inverted"  // inverted sign of the coordinates
+	Centroid  = "centroid"  // at the country centroid
+	Capital   = "capital"   // at a country capital
+	Sea       = "sea"       // in the sea
+	Precision = "precision" // low decimal precision
+)
+
+// CentroidDist is the maximum distance,
+// in meters,
+// to the country centroid
+// for a record to be flagged.
+var CentroidDist uint = 1000
+
+// CapitalDist is the maximum distance,
+// in meters,
+// to a country capital
+// for a record to be flagged.
+var CapitalDist uint = 10000
+
+// MinDecimals is the minimum number of decimals
+// expected in the latitude or the longitude
+// of a georeference.
+var MinDecimals = 2
+
+// Marine if true,
+// the records in the sea
+// will not be flagged.
+var Marine = false
+
+// A Test is a quality test.
+type test struct {
+	flag string
+	fn   func(r biodv.Record, geo geography.Position) bool
+}
+
+// Tests is the list of tests,
+// in the order in which they are made.
+var tests = []test{
+	{Equal, isEqual},
+	{Swapped, isSwapped},
+	{Inverted, isInverted},
+	{Centroid, isCentroid},
+	{Capital, isCapital},
+	{Sea, isSea},
+	{Precision, isLowPrecision},
+}
+
+// Flags returns the list of flags
+// of the quality tests.
+func Flags() []string {
+	ls := []string{Zero}
+	for _, t := range tests {
+		ls = append(ls, t.flag)
+	}
+	return ls
+}
+
+// IsFlag returns true if a flag
+// is a flag of the quality tests.
+func IsFlag(flag string) bool {
+	flag = strings.ToLower(flag)
+	for _, f := range Flags() {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// Check makes the quality tests
+// on the georeference of a record,
+// and returns the flags of the failed tests.
+// If the record is not georeferenced,
+// it returns nil.
+//
+// If the coordinates are both zero,
+// it returns only the Zero flag.
+func Check(r biodv.Record) []string {
+	geo := r.GeoRef()
+	if !geo.IsValid() {
+		return nil
+	}
+	if geo.Lat == 0 && geo.Lon == 0 {
+		return []string{Zero}
+	}
+
+	var flags []string
+	for _, t := range tests {
+		if t.fn(r, geo) {
+			flags = append(flags, t.flag)
+		}
+	}
+	return flags
+}
+
+// Update returns a flags value
+// (i.e. a list of flags separated by spaces)
+// in which the flags of the quality tests
+// are replaced by a new set of flags.
+// Other flags are kept.
+func Update(value string, flags []string) string {
+	var ls []string
+	for _, f := range strings.Fields(value) {
+		if IsFlag(f) {
+			continue
+		}
+		ls = append(ls, f)
+	}
+	ls = append(ls, flags...)
+	sort.Strings(ls)
+	return strings.Join(ls, " ")
+}
+
+// HasFlag returns true
+// if a record has a given flag.
+func HasFlag(r biodv.Record, flag string) bool {
+	flag = strings.ToLower(flag)
+	for _, f := range strings.Fields(r.Value(biodv.RecFlags)) {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+func isEqual(r biodv.Record, geo geography.Position) bool {
+	return math.Abs(geo.Lat) == math.Abs(geo.Lon)
+}
+
+// Country returns the country of a record,
+// or the country in which the record is found.
+func country(r biodv.Record, geo geography.Position) string {
+	ev := r.CollEvent()
+	if geography.IsValidCode(ev.CountryCode()) {
+		return strings.ToUpper(ev.CountryCode())
+	}
+	return geography.CountryAt(geo)
+}
+
+// Misplaced returns true
+// if the record is not inside its country,
+// but one of the positions
+// defined by the alternative functions is.
+func misplaced(r biodv.Record, geo geography.Position, alt ...func(p geography.Position) geography.Position) bool {
+	ev := r.CollEvent()
+	if !geography.IsValidCode(ev.CountryCode()) || !geography.HasBorders() {
+		return false
+	}
+	if ev.Admin.Contains(geo) {
+		return false
+	}
+	for _, fn := range alt {
+		p := fn(geo)
+		if !p.IsValid() {
+			continue
+		}
+		if geography.CountryAt(p) == strings.ToUpper(ev.CountryCode()) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSwapped(r biodv.Record, geo geography.Position) bool {
+	return misplaced(r, geo, func(p geography.Position) geography.Position {
+		return geography.Position{Lat: p.Lon, Lon: p.Lat}
+	})
+}
+
+func isInverted(r biodv.Record, geo geography.Position) bool {
+	return misplaced(r, geo,
+		func(p geography.Position) geography.Position {
+			return geography.Position{Lat: -p.Lat, Lon: p.Lon}
+		},
+		func(p geography.Position) geography.Position {
+			return geography.Position{Lat: p.Lat, Lon: -p.Lon}
+		},
+		func(p geography.Position) geography.Position {
+			return geography.Position{Lat: -p.Lat, Lon: -p.Lon}
+		},
+	)
+}
+
+func isCentroid(r biodv.Record, geo geography.Position) bool {
+	cc := country(r, geo)
+	if cc == "" {
+		return false
+	}
+	c := geography.Centroid(cc)
+	if !c.IsValid() {
+		return false
+	}
+	return geo.Distance(c) <= CentroidDist
+}
+
+func isCapital(r biodv.Record, geo geography.Position) bool {
+	return geography.NearCapital(geo, CapitalDist) != ""
+}
+
+func isSea(r biodv.Record, geo geography.Position) bool {
+	if Marine || r.CollEvent().Z < 0 {
+		return false
+	}
+	return geography.InSea(geo)
+}
+
+func isLowPrecision(r biodv.Record, geo geography.Position) bool {
+	return decimals(geo.Lat) < MinDecimals && decimals(geo.Lon) < MinDecimals
+}
+
+// Decimals returns the number of decimals
+// of a coordinate.
+func decimals(v float64) int {
+	s := strconv.FormatFloat(v, 'f', -1, 64)
+	i := strings.Index(s, ".")
+	if i < 0 {
+		return 0
+	}
+	return len(s) - i - 1
+}
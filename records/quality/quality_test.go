@@ -0,0 +1,109 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package quality
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/js-arias/biodv"
+	"github.com/js-arias/biodv/geography"
+)
+
+type record struct {
+	country string
+	z       int
+	geo     geography.Position
+	flags   string
+}
+
+func (r record) Taxon() string              { return "Rhea americana" }
+func (r record) ID() string                 { return "1" }
+func (r record) Basis() biodv.BasisOfRecord { return biodv.Preserved }
+func (r record) GeoRef() geography.Position { return r.geo }
+func (r record) Keys() []string             { return []string{biodv.RecFlags} }
+
+func (r record) CollEvent() biodv.CollectionEvent {
+	return biodv.CollectionEvent{
+		Admin: geography.Admin{Country: r.country},
+		Z:     r.z,
+	}
+}
+
+func (r record) Value(key string) string {
+	if key == biodv.RecFlags {
+		return r.flags
+	}
+	return ""
+}
+
+var bordersBlob = `{
+"type": "FeatureCollection",
+"features": [
+{"type": "Feature",
+"properties": {"ISO_A2": "AR"},
+"geometry": {"type": "Polygon", "coordinates": [
+	[[-73, -55], [-58, -55], [-58, -22], [-73, -22], [-73, -55]]
+]}},
+{"type": "Feature",
+"properties": {"ISO_A2": "UY"},
+"geometry": {"type": "Polygon", "coordinates": [
+	[[-58, -35], [-53, -35], [-53, -30], [-58, -30], [-58, -35]]
+]}}
+]
+}`
+
+func TestCheck(t *testing.T) {
+	if err := geography.LoadBorders(strings.NewReader(bordersBlob)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testData := []struct {
+		r    record
+		want []string
+	}{
+		{record{country: "AR", geo: geography.Position{Lat: -26.8241, Lon: -65.2226}}, nil},
+		{record{geo: geography.NewPosition()}, nil},
+		{record{country: "AR", geo: geography.Position{Lat: 0, Lon: 0}}, []string{Zero}},
+		{record{country: "AR", geo: geography.Position{Lat: -60.25, Lon: -60.25}}, []string{Equal, Sea}},
+		{record{country: "AR", geo: geography.Position{Lat: -65.2226, Lon: -26.8241}}, []string{Swapped, Sea}},
+		{record{country: "AR", geo: geography.Position{Lat: 26.8241, Lon: -65.2226}}, []string{Inverted, Sea}},
+		{record{country: "AR", geo: geography.Position{Lat: -38.5012, Lon: -65.4987}}, []string{Centroid}},
+		{record{country: "UY", geo: geography.Position{Lat: -34.8857, Lon: -56.1702}}, []string{Capital}},
+		{record{country: "AR", geo: geography.Position{Lat: -40.123, Lon: -40.512}}, []string{Sea}},
+		{record{country: "AR", z: -100, geo: geography.Position{Lat: -40.123, Lon: -40.512}}, nil},
+		{record{country: "AR", geo: geography.Position{Lat: -26.8, Lon: -65}}, []string{Precision}},
+		{record{country: "AR", geo: geography.Position{Lat: -26.8, Lon: -65.22}}, nil},
+	}
+	for i, d := range testData {
+		got := Check(d.r)
+		if !reflect.DeepEqual(got, d.want) {
+			t.Errorf("test %d: flags %v, want %v", i, got, d.want)
+		}
+	}
+
+	Marine = true
+	if got := Check(record{country: "AR", geo: geography.Position{Lat: -40.123, Lon: -40.512}}); got != nil {
+		t.Errorf("marine: flags %v, want none", got)
+	}
+	Marine = false
+}
+
+func TestUpdate(t *testing.T) {
+	v := Update("outlier sea zero", []string{Capital, Precision})
+	if v != "capital outlier precision" {
+		t.Errorf("update %q, want %q", v, "capital outlier precision")
+	}
+	r := record{flags: v}
+	if !HasFlag(r, "Capital") {
+		t.Errorf("flag %q not found", "capital")
+	}
+	if HasFlag(r, Sea) {
+		t.Errorf("flag %q found", Sea)
+	}
+}
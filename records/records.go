@@ -23,6 +23,7 @@ import (
 	"github.com/js-arias/biodv"
 	"github.com/js-arias/biodv/encoding/stanza"
 	"github.com/js-arias/biodv/geography"
+	"github.com/js-arias/biodv/journal"
 
 	"github.com/pkg/errors"
 )
@@ -54,8 +55,20 @@ type DB struct {
 	tids    map[string]*taxon
 	ids     map[string]*Record
 	changed bool
+
+	// version of the database directory
+	// at the last commit
+	version string
+
+	// values of the records
+	// at the last commit
+	orig map[string]map[string]string
 }
 
+// Name of the records database
+// in the change log.
+const logName = "records"
+
 // TaxRecs returns a list of records from a given taxon ID.
 // This function is for compatibility with biodv.RecDB interface.
 //
@@ -106,6 +119,20 @@ func (db *DB) RecList(id string) []*Record {
 	return ls
 }
 
+// Taxa returns the IDs of the taxa
+// with records in the database.
+func (db *DB) Taxa() []string {
+	ls := make([]string, 0, len(db.tids))
+	for id, tax := range db.tids {
+		if len(tax.recs) == 0 {
+			continue
+		}
+		ls = append(ls, id)
+	}
+	sort.Strings(ls)
+	return ls
+}
+
 // Move moves a record,
 // to another taxon.
 // If the destination taxon is not in the database,
@@ -155,20 +182,22 @@ func (db *DB) Delete(id string) {
 	if rec == nil {
 		return
 	}
+	db.remove(rec)
+}
 
-	tax := rec.taxon
-
-	// remove all IDs
-	ext := strings.Fields(rec.data[biodv.RecExtern])
-	for _, e := range ext {
-		delete(db.ids, e)
-	}
-	if cat := rec.data[biodv.RecCatalog]; cat != "" {
-		delete(db.ids, cat)
+// Remove removes a record
+// from the records database,
+// and the IDs that are still used by the record.
+func (db *DB) remove(rec *Record) {
+	ids := strings.Fields(rec.data[biodv.RecExtern])
+	ids = append(ids, rec.data[biodv.RecCatalog], rec.ID())
+	for _, e := range ids {
+		if db.ids[e] == rec {
+			delete(db.ids, e)
+		}
 	}
-	delete(db.ids, rec.ID())
 
-	tax.removeRecord(rec)
+	rec.taxon.removeRecord(rec)
 }
 
 // Record returns a Record.
@@ -205,25 +234,18 @@ type taxon struct {
 
 // Commit saves a list of taxon records
 // to a file.
-func (tax *taxon) commit() (err error) {
+func (tax *taxon) commit(tx *journal.Tx) (err error) {
 	if !tax.changed {
 		return nil
 	}
 
-	taxFile := taxFileName(tax.id)
-	file := filepath.Join(tax.db.path, recDir, taxFile)
+	taxFile := filepath.Join(recDir, taxFileName(tax.id))
 
 	// If there is no records in the taxon
 	// removes the file with that records
 	if len(tax.recs) == 0 {
-		if _, err := os.Stat(file); os.IsNotExist(err) {
-			tax.changed = false
-			return nil
-		}
-		if err = os.Remove(file); err != nil {
-			return errors.Wrap(err, "records: db: commit")
-		}
-		tax.changed = false
+		tx.Remove(taxFile)
+		tx.OnCommit(tax.committed)
 		return nil
 	}
 
@@ -232,30 +254,36 @@ func (tax *taxon) commit() (err error) {
 		tax.sorted = true
 	}
 
-	var f *os.File
-	f, err = os.Create(file)
+	f, err := tx.Create(taxFile)
 	if err != nil {
-		return errors.Wrap(err, "records: db: commit")
+		return err
 	}
+	w := stanza.NewWriter(f)
 	defer func() {
-		e1 := f.Close()
+		e1 := w.Flush()
+		if e2 := f.Close(); e1 == nil {
+			e1 = e2
+		}
 		if err == nil && e1 != nil {
-			err = errors.Wrap(e1, "records: db: commit")
+			err = e1
 		}
 	}()
 
-	w := stanza.NewWriter(f)
-	defer w.Flush()
-
 	for _, rec := range tax.recs {
 		if err = rec.encode(w); err != nil {
 			return
 		}
 	}
-	tax.changed = false
+	tx.OnCommit(tax.committed)
 	return nil
 }
 
+// Committed marks the taxon
+// as stored.
+func (tax *taxon) committed() {
+	tax.changed = false
+}
+
 func (tax *taxon) removeRecord(rec *Record) {
 	for i, r := range tax.recs {
 		if r != rec {
@@ -687,10 +715,17 @@ func open(path string) (biodv.RecDB, error) {
 // Open opens a DB
 // on a given path.
 func Open(path string) (*DB, error) {
+	lk, err := journal.Shared(path, recDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "records: open")
+	}
+	defer lk.Release()
+
 	db := &DB{
-		path: path,
-		ids:  make(map[string]*Record),
-		tids: make(map[string]*taxon),
+		path:    path,
+		ids:     make(map[string]*Record),
+		tids:    make(map[string]*taxon),
+		version: journal.Version(path, recDir),
 	}
 	file := filepath.Join(path, recDir, recTaxList)
 	f, err := os.Open(file)
@@ -711,6 +746,7 @@ func Open(path string) (*DB, error) {
 		tax.sorted = true
 	}
 	db.changed = false
+	db.orig = db.snapshot()
 	return db, nil
 }
 
@@ -742,23 +778,85 @@ func (db *DB) readTaxList(r io.Reader) error {
 // to load data to a database.
 func (db *DB) scan(sc *Scanner) error {
 	for sc.Scan() {
-		r := sc.Record()
-		geo := r.GeoRef()
-		rec, err := db.Add(r.Taxon(), r.ID(), r.Value(biodv.RecCatalog), r.Basis(), geo.Lat, geo.Lon)
-		if err != nil {
+		if err := db.addRecord(sc.Record()); err != nil {
 			sc.Close()
 			return err
 		}
-		rec.SetCollEvent(r.CollEvent())
-		rec.SetGeoRef(r.GeoRef())
-		keys := r.Keys()
-		for _, k := range keys {
-			if err := rec.Set(k, r.Value(k)); err != nil {
-				return err
+	}
+	return sc.Err()
+}
+
+// AddRecord adds a record,
+// and its values,
+// to the database.
+func (db *DB) addRecord(r biodv.Record) error {
+	geo := r.GeoRef()
+	rec, err := db.Add(r.Taxon(), r.ID(), r.Value(biodv.RecCatalog), r.Basis(), geo.Lat, geo.Lon)
+	if err != nil {
+		return err
+	}
+	rec.SetCollEvent(r.CollEvent())
+	rec.SetGeoRef(r.GeoRef())
+	keys := r.Keys()
+	for _, k := range keys {
+		if err := rec.Set(k, r.Value(k)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Snapshot returns a copy
+// of the values of the records
+// in the database.
+func (db *DB) snapshot() map[string]map[string]string {
+	s := make(map[string]map[string]string)
+	for _, tax := range db.tids {
+		for _, rec := range tax.recs {
+			m := make(map[string]string, len(rec.data))
+			for k, v := range rec.data {
+				if v == "" {
+					continue
+				}
+				m[k] = v
 			}
+			s[rec.ID()] = m
 		}
 	}
-	return sc.Err()
+	return s
+}
+
+// Undo reverts a list of changes
+// stored in the change log,
+// ordered from the oldest to the newest.
+// Changes of other databases are ignored.
+func (db *DB) Undo(ch []journal.Change) error {
+	data := db.snapshot()
+	journal.Revert(logName, data, ch)
+
+	db.ids = make(map[string]*Record)
+	for _, tax := range db.tids {
+		tax.recs = nil
+		tax.changed = true
+		tax.sorted = false
+	}
+	db.changed = true
+
+	ls := make([]string, 0, len(data))
+	for id := range data {
+		ls = append(ls, id)
+	}
+	sort.Strings(ls)
+	for _, id := range ls {
+		r := recmap(data[id])
+		if r.ID() == "" || r.Taxon() == "" {
+			continue
+		}
+		if err := db.addRecord(r); err != nil {
+			return errors.Wrap(err, "records: undo")
+		}
+	}
+	return nil
 }
 
 // Add adds a new record to a DB.
@@ -812,31 +910,75 @@ func (db *DB) Add(taxID, id, catalog string, basis biodv.BasisOfRecord, lat, lon
 
 // Commit saves a record database to hard disk.
 func (db *DB) Commit() error {
+	if !db.isChanged() {
+		return nil
+	}
+	tx, err := journal.Begin(db.path)
+	if err != nil {
+		return errors.Wrap(err, "records: db: commit")
+	}
+	if err := db.CommitTx(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "records: db: commit")
+	}
+	return nil
+}
+
+// CommitTx writes the records database
+// as part of a transaction,
+// so it can be committed
+// with other databases.
+// The transaction must be started
+// in the same path of the database.
+func (db *DB) CommitTx(tx *journal.Tx) error {
+	if !db.isChanged() {
+		return nil
+	}
+	v, err := tx.Update(recDir, db.version)
+	if err != nil {
+		return errors.Wrap(err, "records: db: commit")
+	}
+
 	if db.changed {
-		if err := db.saveTaxList(); err != nil {
+		if err := db.saveTaxList(tx); err != nil {
 			return err
 		}
 	}
-
 	for _, tax := range db.tids {
-		if err := tax.commit(); err != nil {
+		if err := tax.commit(tx); err != nil {
 			return errors.Wrap(err, "records: db: commit")
 		}
 	}
-	db.changed = false
+
+	cur := db.snapshot()
+	tx.Log(journal.Diff(logName, db.orig, cur)...)
+	tx.OnCommit(func() {
+		db.orig = cur
+		db.version = v
+		db.changed = false
+	})
 	return nil
 }
 
-func (db *DB) saveTaxList() (err error) {
-	if _, err := os.Lstat(filepath.Join(db.path, recDir)); err != nil {
-		if err := os.Mkdir(filepath.Join(db.path, recDir), os.ModeDir|os.ModePerm); err != nil {
-			return errors.Wrapf(err, "records: db: commit: unable to create %s directory", recDir)
+// IsChanged returns true
+// if the database was modified.
+func (db *DB) isChanged() bool {
+	if db.changed {
+		return true
+	}
+	for _, tax := range db.tids {
+		if tax.changed {
+			return true
 		}
 	}
+	return false
+}
 
-	file := filepath.Join(db.path, recDir, recTaxList)
-	var f *os.File
-	f, err = os.Create(file)
+func (db *DB) saveTaxList(tx *journal.Tx) (err error) {
+	f, err := tx.Create(filepath.Join(recDir, recTaxList))
 	if err != nil {
 		return errors.Wrap(err, "records: db: commit")
 	}
@@ -847,17 +989,12 @@ func (db *DB) saveTaxList() (err error) {
 		}
 	}()
 
-	ls := make([]string, 0, len(db.tids))
-	for _, tax := range db.tids {
-		if len(tax.recs) == 0 {
-			continue
-		}
-		ls = append(ls, tax.id)
+	w := bufio.NewWriter(f)
+	for _, v := range db.Taxa() {
+		fmt.Fprintf(w, "%s\n", v)
 	}
-	sort.Strings(ls)
-
-	for _, v := range ls {
-		fmt.Fprintf(f, "%s\n", v)
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "records: db: commit")
 	}
 	return nil
 }
@@ -10,6 +10,7 @@ import (
 	"math"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/js-arias/biodv"
 )
@@ -255,3 +256,107 @@ func TestTaxonList(t *testing.T) {
 		t.Errorf("lines beginning with ';' should be left unread")
 	}
 }
+
+func TestSameCatalog(t *testing.T) {
+	testData := []struct {
+		a, b string
+		same bool
+	}{
+		{"MSU:MR:MR.8672", "MR 8672", true},
+		{"MSU:MR:MR.8672", "mr-08672", true},
+		{"8672", "MLP:8672", false},
+		{"123", "MACN-123", false},
+		{"123", "X:0123", false},
+		{"0123", "123", true},
+		{"ENT 123", "MLP:ENT:0123", true},
+		{"MLP:ENT:123", "MACN:ENT:123", false},
+		{"MLP:8672", "MACN:8672", false},
+		{"MLP:8672", "MLP:8673", false},
+		{"MLP:ENT", "ENT", false},
+		{"", "8672", false},
+	}
+	for _, d := range testData {
+		if s := SameCatalog(d.a, d.b); s != d.same {
+			t.Errorf("catalogs %q and %q: same %v, want %v", d.a, d.b, s, d.same)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	db := &DB{tids: make(map[string]*taxon), ids: make(map[string]*Record)}
+	date := time.Date(1998, 11, 6, 0, 0, 0, 0, time.UTC)
+
+	own, err := db.Add("Rhea americana", "", "MLP:8672", biodv.Preserved, -26.82, -65.22)
+	if err != nil {
+		t.Fatalf("when adding %q: %v", "MLP:8672", err)
+	}
+	own.SetCollEvent(biodv.CollectionEvent{Date: date, Collector: "J. Salvador Arias"})
+	gbif, err := db.Add("Rhea americana", "Rhea americana:1", "", biodv.UnknownBasis, -26.825, -65.22)
+	if err != nil {
+		t.Fatalf("when adding %q: %v", "Rhea americana:1", err)
+	}
+	gbif.SetCollEvent(biodv.CollectionEvent{Date: date, Collector: "j. salvador  arias", Locality: "San Miguel de Tucuman"})
+	gbif.Set(biodv.RecExtern, "gbif:1234")
+	other, err := db.Add("Rhea americana", "", "MLP:8673", biodv.Preserved, -26.82, -65.22)
+	if err != nil {
+		t.Fatalf("when adding %q: %v", "MLP:8673", err)
+	}
+	other.SetCollEvent(biodv.CollectionEvent{Date: date, Collector: "J. Salvador Arias"})
+
+	if r := Duplicate(own, gbif); r != DupCollector {
+		t.Errorf("duplicate %q, want %q", r, DupCollector)
+	}
+	if r := Duplicate(own, other); r != "" {
+		t.Errorf("duplicate %q, want none", r)
+	}
+	far := *gbif
+	far.data = map[string]string{latlonKey: "-34.9 -57.95", dateKey: gbif.data[dateKey], collectorKey: gbif.data[collectorKey]}
+	if r := Duplicate(own, &far); r != "" {
+		t.Errorf("distant duplicate %q, want none", r)
+	}
+
+	if err := db.Merge(own.ID(), gbif.ID()); err != nil {
+		t.Fatalf("when merging: %v", err)
+	}
+	if db.Record("Rhea americana:1") != nil {
+		t.Errorf("duplicate record not deleted")
+	}
+	if r := db.Record("gbif:1234"); r != own {
+		t.Errorf("extern ID not merged")
+	}
+	if l := own.CollEvent().Locality; l != "San Miguel de Tucuman" {
+		t.Errorf("locality %q, want %q", l, "San Miguel de Tucuman")
+	}
+	if n := len(db.RecList("Rhea americana")); n != 2 {
+		t.Errorf("records %d, want %d", n, 2)
+	}
+
+	conflict, err := db.Add("Rhea americana", "Rhea americana:2", "", biodv.UnknownBasis, -26.82, -65.22)
+	if err != nil {
+		t.Fatalf("when adding %q: %v", "Rhea americana:2", err)
+	}
+	conflict.Set(biodv.RecExtern, "gbif:5678")
+	if err := db.Merge(own.ID(), conflict.ID()); err == nil {
+		t.Errorf("merge of records with different extern IDs of the same service: expecting error")
+	}
+	if r := db.Record("gbif:5678"); r != conflict {
+		t.Errorf("extern ID of a record with a merge error changed")
+	}
+	if r := db.Record("gbif:1234"); r != own {
+		t.Errorf("extern ID of the kept record with a merge error changed")
+	}
+
+	noCat, err := db.Add("Rhea americana", "Rhea americana:3", "", biodv.UnknownBasis, -26.82, -65.22)
+	if err != nil {
+		t.Fatalf("when adding %q: %v", "Rhea americana:3", err)
+	}
+	if err := db.Merge(noCat.ID(), other.ID()); err != nil {
+		t.Fatalf("when merging: %v", err)
+	}
+	if r := db.Record("MLP:8673"); r != noCat {
+		t.Errorf("catalog not merged")
+	}
+	if b := noCat.Basis(); b != biodv.Preserved {
+		t.Errorf("basis %v, want %v", b, biodv.Preserved)
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package records
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/js-arias/biodv"
+	"github.com/js-arias/biodv/encoding/stanza"
+	"github.com/js-arias/biodv/geography"
+	"github.com/js-arias/biodv/journal"
+
+	"github.com/pkg/errors"
+)
+
+// Problems found
+// when validating a record.
+const (
+	ProbNoID       = "no id"               // a record without ID
+	ProbNoTaxon    = "no taxon"            // a record without taxon
+	ProbDupID      = "duplicated id"       // the ID is used by another record
+	ProbDupCatalog = "duplicated catalog"  // the catalog is used by another record
+	ProbTaxon      = "unknown taxon"       // the taxon is not in the taxonomy
+	ProbSynonym    = "synonym"             // the taxon is a synonym
+	ProbBasis      = "invalid basis"       // unknown basis of record
+	ProbCatalog    = "no catalog"          // a preserved specimen without catalog
+	ProbCountry    = "invalid country"     // invalid country code
+	ProbCoord      = "invalid coordinates" // invalid latitude or longitude
+	ProbDate       = "invalid date"        // the date can not be parsed
+	ProbFuture     = "future date"         // the date is after the current time
+	ProbOld        = "old date"            // the date is before MinYear
+	ProbDataset    = "unknown dataset"     // the dataset is not in the dataset database
+)
+
+// MinYear is the earliest year
+// accepted for the collection date of a record.
+const MinYear = 1700
+
+// A Problem is a problem found
+// in a record.
+type Problem struct {
+	ID    string // ID of the record
+	Taxon string // taxon of the record
+	Kind  string
+
+	// Value is the value with the problem,
+	// if any.
+	Value string
+}
+
+// String returns a description of the problem.
+func (p Problem) String() string {
+	if p.Value == "" {
+		return p.Kind
+	}
+	return p.Kind + ": " + p.Value
+}
+
+// Validate checks the records
+// of the database in the given path,
+// and returns the problems found.
+//
+// The records are read directly from the database files,
+// without the normalization done by the Scanner,
+// so problems that are silently fixed
+// (or that make it fail)
+// when the database is opened,
+// are found.
+//
+// If a taxonomy is given,
+// the taxa of the records are checked
+// against the taxonomy,
+// and if a dataset database is given,
+// the datasets of the records are checked
+// against the dataset database.
+func Validate(path string, txm biodv.Taxonomy, sets biodv.SetDB) ([]Problem, error) {
+	lk, err := journal.Shared(path, recDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "records: validate")
+	}
+	defer lk.Release()
+
+	db := &DB{tids: make(map[string]*taxon)}
+	f, err := os.Open(filepath.Join(path, recDir, recTaxList))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "records: validate")
+	}
+	err = db.readTaxList(f)
+	f.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "records: validate: when reading taxon list")
+	}
+	ls := make([]string, 0, len(db.tids))
+	for id := range db.tids {
+		ls = append(ls, id)
+	}
+	sort.Strings(ls)
+
+	v := newValidator(txm, sets)
+	for _, id := range ls {
+		if err := v.checkFile(filepath.Join(path, recDir, taxFileName(id))); err != nil {
+			return nil, errors.Wrapf(err, "records: validate: taxon %q", id)
+		}
+	}
+	return v.probs, nil
+}
+
+// A Validator stores the state
+// of a validation.
+type validator struct {
+	txm  biodv.Taxonomy
+	sets biodv.SetDB
+	now  time.Time
+
+	// IDs and catalogs already found
+	ids map[string]bool
+
+	probs []Problem
+}
+
+func newValidator(txm biodv.Taxonomy, sets biodv.SetDB) *validator {
+	return &validator{
+		txm:  txm,
+		sets: sets,
+		now:  time.Now(),
+		ids:  make(map[string]bool),
+	}
+}
+
+func (v *validator) add(r biodv.Record, kind, value string) {
+	v.probs = append(v.probs, Problem{r.ID(), r.Taxon(), kind, value})
+}
+
+// CheckFile checks the records
+// of a file.
+func (v *validator) checkFile(file string) error {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sc := stanza.NewScanner(f)
+	for sc.Scan() {
+		v.check(recmap(sc.Record()))
+	}
+	return sc.Err()
+}
+
+// Check checks a record.
+func (v *validator) check(r recmap) {
+	id := strings.TrimSpace(r.ID())
+	if id == "" {
+		v.add(r, ProbNoID, "")
+	} else {
+		if v.ids[id] {
+			v.add(r, ProbDupID, "")
+		}
+		v.ids[id] = true
+	}
+	cat := strings.TrimSpace(r.Value(biodv.RecCatalog))
+	if cat != "" && cat != id {
+		if v.ids[cat] {
+			v.add(r, ProbDupCatalog, cat)
+		}
+		v.ids[cat] = true
+	}
+
+	if biodv.TaxCanon(r.Taxon()) == "" {
+		v.add(r, ProbNoTaxon, "")
+	} else if v.txm != nil {
+		tax, _ := v.txm.TaxID(biodv.TaxCanon(r.Taxon()))
+		if tax == nil {
+			v.add(r, ProbTaxon, "")
+		} else if !tax.IsCorrect() {
+			v.add(r, ProbSynonym, "")
+		}
+	}
+
+	if b := strings.TrimSpace(r.Value(basisKey)); b != "" && biodv.GetBasis(b) == biodv.UnknownBasis && strings.ToLower(b) != biodv.UnknownBasis.String() {
+		v.add(r, ProbBasis, b)
+	}
+	if r.Basis() == biodv.Preserved && cat == "" {
+		v.add(r, ProbCatalog, "")
+	}
+
+	if c := strings.TrimSpace(r.Value(countryKey)); c != "" && !geography.IsValidCode(c) {
+		v.add(r, ProbCountry, c)
+	}
+	if ll := strings.TrimSpace(r.Value(latlonKey)); ll != "" && !validLatLon(ll) {
+		v.add(r, ProbCoord, ll)
+	}
+
+	if d := strings.TrimSpace(r.Value(dateKey)); d != "" {
+		t, err := time.Parse(time.RFC3339, d)
+		if err != nil {
+			v.add(r, ProbDate, d)
+		} else if t.After(v.now) {
+			v.add(r, ProbFuture, d)
+		} else if t.Year() < MinYear {
+			v.add(r, ProbOld, d)
+		}
+	}
+
+	if v.sets != nil {
+		if s := strings.TrimSpace(r.Value(biodv.RecDataset)); s != "" {
+			if set, _ := v.sets.SetID(s); set == nil {
+				v.add(r, ProbDataset, s)
+			}
+		}
+	}
+}
+
+// ValidLatLon returns true
+// if a latlon value
+// is a valid geographic point.
+func validLatLon(ll string) bool {
+	f := strings.Fields(ll)
+	if len(f) != 2 {
+		return false
+	}
+	lat, err := strconv.ParseFloat(f[0], 64)
+	if err != nil {
+		return false
+	}
+	lon, err := strconv.ParseFloat(f[1], 64)
+	if err != nil {
+		return false
+	}
+	return geography.IsValidCoord(lat, lon)
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package records
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/js-arias/biodv"
+	"github.com/js-arias/biodv/encoding/stanza"
+)
+
+var validateBlob = `
+taxon:	Rhea americana
+id:	MLP:1
+basis:	preserved
+catalog: MLP:1
+date:	1957-06-30T00:00:00Z
+country: AR
+latlon:	-34.5 -58.4
+dataset: MLP
+%%
+taxon:	Rhea americana
+id:	MLP:2
+basis:	preserved
+date:	2957-06-30T00:00:00Z
+country: XX
+latlon:	-134.5 -58.4
+dataset: MACN
+%%
+taxon:	Rhea americana
+id:	MLP:1
+basis:	museum
+date:	30 june 1957
+%%
+taxon:	Pterocnemia pennata
+id:	obs:1
+basis:	observation
+%%
+taxon:	Rhea nana
+id:	obs:2
+basis:	observation
+catalog: MLP:1
+%%
+id:	obs:3
+basis:	observation
+%%
+taxon:	Rhea americana
+id:	obs:4
+basis:	observation
+date:	1492-10-12T00:00:00Z
+%%
+`
+
+var validateProbs = []Problem{
+	{"MLP:2", "Rhea americana", ProbCatalog, ""},
+	{"MLP:2", "Rhea americana", ProbCountry, "XX"},
+	{"MLP:2", "Rhea americana", ProbCoord, "-134.5 -58.4"},
+	{"MLP:2", "Rhea americana", ProbFuture, "2957-06-30T00:00:00Z"},
+	{"MLP:2", "Rhea americana", ProbDataset, "MACN"},
+	{"MLP:1", "Rhea americana", ProbDupID, ""},
+	{"MLP:1", "Rhea americana", ProbBasis, "museum"},
+	{"MLP:1", "Rhea americana", ProbDate, "30 june 1957"},
+	{"obs:1", "Pterocnemia pennata", ProbSynonym, ""},
+	{"obs:2", "Rhea nana", ProbDupCatalog, "MLP:1"},
+	{"obs:2", "Rhea nana", ProbTaxon, ""},
+	{"obs:3", "", ProbNoTaxon, ""},
+	{"obs:4", "Rhea americana", ProbOld, "1492-10-12T00:00:00Z"},
+}
+
+// TestTaxonomy is a simple taxonomy
+// for testing.
+type testTaxonomy map[string]bool
+
+func (tt testTaxonomy) Taxon(name string) *biodv.TaxScan  { return nil }
+func (tt testTaxonomy) Synonyms(id string) *biodv.TaxScan { return nil }
+func (tt testTaxonomy) Children(id string) *biodv.TaxScan { return nil }
+func (tt testTaxonomy) TaxID(id string) (biodv.Taxon, error) {
+	correct, ok := tt[id]
+	if !ok {
+		return nil, nil
+	}
+	return testTaxon{id, correct}, nil
+}
+
+type testTaxon struct {
+	name    string
+	correct bool
+}
+
+func (tx testTaxon) Name() string            { return tx.name }
+func (tx testTaxon) ID() string              { return tx.name }
+func (tx testTaxon) Parent() string          { return "" }
+func (tx testTaxon) Rank() biodv.Rank        { return biodv.Species }
+func (tx testTaxon) IsCorrect() bool         { return tx.correct }
+func (tx testTaxon) Keys() []string          { return nil }
+func (tx testTaxon) Value(key string) string { return "" }
+
+// TestSets is a simple dataset database
+// for testing.
+type testSets map[string]bool
+
+func (ts testSets) SetID(id string) (biodv.Dataset, error) {
+	if !ts[id] {
+		return nil, nil
+	}
+	return testSet(id), nil
+}
+
+type testSet string
+
+func (s testSet) ID() string              { return string(s) }
+func (s testSet) Title() string           { return string(s) }
+func (s testSet) Keys() []string          { return nil }
+func (s testSet) Value(key string) string { return "" }
+
+func TestValidate(t *testing.T) {
+	txm := testTaxonomy{"Rhea americana": true, "Pterocnemia pennata": false}
+	sets := testSets{"MLP": true}
+	v := newValidator(txm, sets)
+	sc := stanza.NewScanner(strings.NewReader(validateBlob))
+	for sc.Scan() {
+		v.check(recmap(sc.Record()))
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatalf("unexpected scanner error: %v", err)
+	}
+
+	if len(v.probs) != len(validateProbs) {
+		t.Errorf("found %d problems, want %d", len(v.probs), len(validateProbs))
+	}
+	for i, p := range v.probs {
+		if i >= len(validateProbs) {
+			t.Errorf("unexpected problem %v", p)
+			continue
+		}
+		if p != validateProbs[i] {
+			t.Errorf("problem %d: got %v, want %v", i, p, validateProbs[i])
+		}
+	}
+}
+
+func TestValidateOpenError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "records")
+	if err != nil {
+		t.Fatalf("unable to create temporal directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// an empty database
+	if _, err := Validate(dir, nil, nil); err != nil {
+		t.Errorf("empty database: unexpected error: %v", err)
+	}
+
+	// a taxon list that can not be opened
+	if err := os.Mkdir(filepath.Join(dir, recDir), 0755); err != nil {
+		t.Fatalf("unable to create %s: %v", recDir, err)
+	}
+	ls := filepath.Join(dir, recDir, recTaxList)
+	if err := os.Symlink(ls, ls); err != nil {
+		t.Skipf("unable to create symlink: %v", err)
+	}
+	if _, err := Validate(dir, nil, nil); err == nil {
+		t.Errorf("unreadable taxon list: expecting error")
+	}
+}
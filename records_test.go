@@ -123,3 +123,25 @@ func TestRecScan(t *testing.T) {
 		t.Errorf("scanned records %d, want %d", c, 3)
 	}
 }
+
+func TestJoinCatalog(t *testing.T) {
+	testData := []struct {
+		inst, coll, cat string
+		want            string
+	}{
+		{"AM", "Mammalogy", "M.1327", "AM:Mammalogy:M.1327"},
+		{"MSU", "MR", "MR.8672", "MSU:MR.8672"},
+		{"MACN", "", "8672", "MACN:8672"},
+		{"AMNH", "AMNH", "143466", "AMNH:143466"},
+		{"ISM", "ISM-Mammals", "686867", "ISM-Mammals:686867"},
+		{"KPM", "NF1", "KPM-NF1001895", "KPM-NF1001895"},
+		{"", "Mammalia", "RMNH.MAM.51709", "RMNH.MAM.51709"},
+		{"MACN", "Ent", "", ""},
+	}
+
+	for _, d := range testData {
+		if v := JoinCatalog(d.inst, d.coll, d.cat); v != d.want {
+			t.Errorf("catalog %q, want %q", v, d.want)
+		}
+	}
+}
@@ -9,7 +9,6 @@ package biodv
 import (
 	"io"
 	"sort"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -176,8 +175,8 @@ func (tsc *TaxScan) Add(tax Taxon, err error) bool {
 		return false
 	}
 	if err != nil {
-		close(tsc.c)
 		tsc.err = err
+		close(tsc.c)
 		return true
 	}
 	tsc.c <- tax
@@ -311,16 +310,12 @@ func TaxYear(tx Taxon) int {
 }
 
 func getYearFromAuthor(author string) int {
-	if author == "" {
-		return 0
-	}
-	author = strings.TrimRight(author, ")")
-	if len(author) < 4 {
-		return 0
-	}
-	year, err := strconv.Atoi(author[len(author)-4:])
-	if err != nil {
-		return 0
+	_, year, _, basYear := splitAuthorship(author)
+
+	// the year in parentheses
+	// is the year of the original description
+	if basYear != 0 {
+		year = basYear
 	}
 	if year < 1750 || year > time.Now().Year() {
 		return 0
@@ -345,34 +340,85 @@ type Rank uint
 const (
 	Unranked Rank = iota
 	Kingdom
+	Subkingdom
 	Phylum
+	Subphylum
+	Superclass
 	Class
+	Subclass
+	Infraclass
+	Superorder
 	Order
+	Suborder
+	Infraorder
+	Superfamily
 	Family
+	Subfamily
+	Tribe
+	Subtribe
 	Genus
+	Subgenus
 	Species
+	Subspecies
+	Variety
+	Form
 )
 
 // ranks holds a list of the accepted rank names.
 var ranks = []string{
-	Unranked: "unranked",
-	Kingdom:  "kingdom",
-	Phylum:   "phylum",
-	Class:    "class",
-	Order:    "order",
-	Family:   "family",
-	Genus:    "genus",
-	Species:  "species",
+	Unranked:    "unranked",
+	Kingdom:     "kingdom",
+	Subkingdom:  "subkingdom",
+	Phylum:      "phylum",
+	Subphylum:   "subphylum",
+	Superclass:  "superclass",
+	Class:       "class",
+	Subclass:    "subclass",
+	Infraclass:  "infraclass",
+	Superorder:  "superorder",
+	Order:       "order",
+	Suborder:    "suborder",
+	Infraorder:  "infraorder",
+	Superfamily: "superfamily",
+	Family:      "family",
+	Subfamily:   "subfamily",
+	Tribe:       "tribe",
+	Subtribe:    "subtribe",
+	Genus:       "genus",
+	Subgenus:    "subgenus",
+	Species:     "species",
+	Subspecies:  "subspecies",
+	Variety:     "variety",
+	Form:        "form",
+}
+
+// rankAbbrev holds common abbreviations
+// of rank names.
+var rankAbbrev = map[string]Rank{
+	"division": Phylum,
+	"subg.":    Subgenus,
+	"sp.":      Species,
+	"subsp.":   Subspecies,
+	"ssp.":     Subspecies,
+	"var.":     Variety,
+	"f.":       Form,
+	"forma":    Form,
 }
 
 // GetRank returns a rank value from a string.
+// It also accepts common abbreviations
+// of infraspecific ranks
+// (e.g. 'subsp.', or 'var.').
 func GetRank(s string) Rank {
-	s = strings.ToLower(s)
+	s = strings.ToLower(strings.TrimSpace(s))
 	for i, r := range ranks {
 		if r == s {
 			return Rank(i)
 		}
 	}
+	if r, ok := rankAbbrev[s]; ok {
+		return r
+	}
 	return Unranked
 }
 
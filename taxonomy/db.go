@@ -7,13 +7,13 @@
 package taxonomy
 
 import (
-	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 
 	"github.com/js-arias/biodv"
 	"github.com/js-arias/biodv/encoding/stanza"
+	"github.com/js-arias/biodv/journal"
 
 	"github.com/pkg/errors"
 )
@@ -31,8 +31,20 @@ type DB struct {
 	changed bool // true if the database was modified
 	root    []*Taxon
 	sorted  bool
+
+	// version of the database directory
+	// at the last commit
+	version string
+
+	// values of the taxa
+	// at the last commit
+	orig map[string]map[string]string
 }
 
+// Name of the taxonomy
+// in the change log.
+const logName = "taxonomy"
+
 // Taxon returns a list of taxons with a given name.
 // This function is for compatibility with biodv.Taxonomy interface.
 //
@@ -330,6 +342,7 @@ func (tax *Taxon) Set(key, value string) error {
 		if tax.parent != nil {
 			tax.parent.sorted = false
 		}
+		tax.db.changed = true
 		return nil
 	default:
 		v := tax.data[key]
@@ -352,7 +365,7 @@ func (tax *Taxon) SetRank(rank biodv.Rank) error {
 		return errors.Errorf("taxonomy: db: setrank %s: inconsistent parent rank", tax.Name())
 	}
 	for _, c := range tax.children {
-		if !c.isConsistentUp(tax.IsCorrect(), rank) {
+		if !c.isConsistentUp(c.IsCorrect(), rank) {
 			return errors.Errorf("taxonomy: db: setrank %s: inconsistent children rank", tax.Name())
 		}
 	}
@@ -482,9 +495,9 @@ func (tax *Taxon) isConsistentDown(correct bool, rank biodv.Rank) bool {
 			continue
 		}
 		if rank > r {
-			return true
+			return isParentRank(r, rank)
 		}
-		if rank == r && !correct {
+		if !correct && rankGroup(rank) == rankGroup(r) {
 			return true
 		}
 		return false
@@ -507,14 +520,54 @@ func (tax *Taxon) isConsistentUp(correct bool, rank biodv.Rank) bool {
 		return true
 	}
 	if tax.Rank() > rank {
-		return true
+		return isParentRank(rank, tax.Rank())
 	}
-	if tax.Rank() == rank && !correct {
+	if !correct && rankGroup(tax.Rank()) == rankGroup(rank) {
 		return true
 	}
 	return false
 }
 
+// RankGroup returns the nomenclatural group of a rank.
+// As names in the family, genus, and species groups
+// are coordinated,
+// a synonym can have any rank
+// of the group of its parent
+// (e.g. a subspecies can be a synonym of a species).
+// Varieties and forms are not coordinated
+// with the species,
+// so they are in its own group.
+func rankGroup(r biodv.Rank) biodv.Rank {
+	switch {
+	case r > biodv.Subspecies:
+		return biodv.Variety
+	case r >= biodv.Species:
+		return biodv.Species
+	case r >= biodv.Genus:
+		return biodv.Genus
+	case r >= biodv.Superfamily:
+		return biodv.Family
+	}
+	return r
+}
+
+// IsParentRank returns true
+// if a taxon of the given rank
+// can be placed below a taxon
+// of the parent rank.
+// Infraspecific ranks
+// (subspecies, variety, and form)
+// should be placed below a species.
+func isParentRank(parent, rank biodv.Rank) bool {
+	if rank <= parent {
+		return false
+	}
+	if rank > biodv.Species {
+		return parent >= biodv.Species
+	}
+	return true
+}
+
 // Delete removes a taxon from the taxonomy.
 // If rec is true,
 // it will also remove all the descendants of the taxon,
@@ -659,6 +712,12 @@ func open(path string) (biodv.Taxonomy, error) {
 // Open opens a DB
 // on a given path.
 func Open(path string) (*DB, error) {
+	lk, err := journal.Shared(path, taxDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "taxonomy: open")
+	}
+	defer lk.Release()
+
 	db := &DB{
 		path: path,
 		ids:  make(map[string]*Taxon),
@@ -667,6 +726,8 @@ func Open(path string) (*DB, error) {
 		return nil, err
 	}
 	db.changed = false
+	db.orig = db.snapshot()
+	db.version = journal.Version(path, taxDir)
 	return db, nil
 }
 
@@ -674,20 +735,94 @@ func Open(path string) (*DB, error) {
 // to load a database.
 func (db *DB) scan(sc *Scanner) error {
 	for sc.Scan() {
-		r := sc.Taxon()
-		tax, err := db.Add(r.Name(), r.Parent(), r.Rank(), r.IsCorrect())
-		if err != nil {
+		if err := db.addTaxon(sc.Taxon()); err != nil {
 			sc.Close()
 			return err
 		}
-		keys := r.Keys()
-		for _, k := range keys {
-			if err := tax.Set(k, r.Value(k)); err != nil {
-				return err
+	}
+	return sc.Err()
+}
+
+// AddTaxon adds a taxon,
+// and its values,
+// to the database.
+func (db *DB) addTaxon(r biodv.Taxon) error {
+	tax, err := db.Add(r.Name(), r.Parent(), r.Rank(), r.IsCorrect())
+	if err != nil {
+		return err
+	}
+	keys := r.Keys()
+	for _, k := range keys {
+		if err := tax.Set(k, r.Value(k)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Snapshot returns a copy
+// of the values of the taxa
+// in the database.
+func (db *DB) snapshot() map[string]map[string]string {
+	s := make(map[string]map[string]string, len(db.ids))
+	for _, tax := range db.ids {
+		if _, ok := s[tax.ID()]; ok {
+			continue
+		}
+		m := make(map[string]string, len(tax.data))
+		for k, v := range tax.data {
+			if v == "" {
+				continue
 			}
+			m[k] = v
 		}
+		s[tax.ID()] = m
 	}
-	return sc.Err()
+	return s
+}
+
+// Undo reverts a list of changes
+// stored in the change log,
+// ordered from the oldest to the newest.
+// Changes of other databases are ignored.
+func (db *DB) Undo(ch []journal.Change) error {
+	data := db.snapshot()
+	journal.Revert(logName, data, ch)
+
+	db.ids = make(map[string]*Taxon)
+	db.root = nil
+	db.sorted = false
+	db.changed = true
+
+	// taxa are added
+	// after its parents
+	for len(data) > 0 {
+		ls := make([]string, 0, len(data))
+		for id := range data {
+			ls = append(ls, id)
+		}
+		sort.Strings(ls)
+		n := 0
+		for _, id := range ls {
+			r := record(data[id])
+			if p := r.Parent(); p != "" {
+				if _, ok := data[p]; ok {
+					continue
+				}
+			}
+			if r.Name() != "" {
+				if err := db.addTaxon(r); err != nil {
+					return errors.Wrap(err, "taxonomy: undo")
+				}
+			}
+			delete(data, id)
+			n++
+		}
+		if n == 0 {
+			return errors.Errorf("taxonomy: undo: taxa without valid parents: %s", strings.Join(ls, ", "))
+		}
+	}
+	return nil
 }
 
 // Add adds a new taxon name to a DB.
@@ -740,32 +875,54 @@ func (db *DB) Add(name, parent string, rank biodv.Rank, correct bool) (*Taxon, e
 }
 
 // Commit saves a taxonomy to a file.
-func (db *DB) Commit() (err error) {
+func (db *DB) Commit() error {
 	if !db.changed {
 		return nil
 	}
+	tx, err := journal.Begin(db.path)
+	if err != nil {
+		return errors.Wrap(err, "taxonomy: db: commit")
+	}
+	if err := db.CommitTx(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "taxonomy: db: commit")
+	}
+	return nil
+}
 
-	if _, err := os.Lstat(filepath.Join(db.path, taxDir)); err != nil {
-		if err := os.Mkdir(filepath.Join(db.path, taxDir), os.ModeDir|os.ModePerm); err != nil {
-			return errors.Wrapf(err, "taxonomy: db: commit: unable to create %s directory", taxDir)
-		}
+// CommitTx writes the taxonomy
+// as part of a transaction,
+// so it can be committed
+// with other databases.
+// The transaction must be started
+// in the same path of the database.
+func (db *DB) CommitTx(tx *journal.Tx) (err error) {
+	if !db.changed {
+		return nil
 	}
 
-	file := filepath.Join(db.path, taxDir, taxFile)
-	f, err := os.Create(file)
+	v, err := tx.Update(taxDir, db.version)
+	if err != nil {
+		return errors.Wrap(err, "taxonomy: db: commit")
+	}
+	f, err := tx.Create(filepath.Join(taxDir, taxFile))
 	if err != nil {
 		return errors.Wrap(err, "taxonomy: db: commit")
 	}
+	w := stanza.NewWriter(f)
 	defer func() {
-		e1 := f.Close()
+		e1 := w.Flush()
+		if e2 := f.Close(); e1 == nil {
+			e1 = e2
+		}
 		if err == nil && e1 != nil {
 			err = errors.Wrap(e1, "taxonomy: db: commit")
 		}
 	}()
 
-	w := stanza.NewWriter(f)
-	defer w.Flush()
-
 	if !db.sorted {
 		sortTaxons(db.root)
 		db.sorted = true
@@ -775,6 +932,13 @@ func (db *DB) Commit() (err error) {
 			return errors.Wrap(err, "taxonomy: db: commit")
 		}
 	}
-	db.changed = false
+
+	cur := db.snapshot()
+	tx.Log(journal.Diff(logName, db.orig, cur)...)
+	tx.OnCommit(func() {
+		db.orig = cur
+		db.version = v
+		db.changed = false
+	})
 	return
 }
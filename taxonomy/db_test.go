@@ -7,10 +7,14 @@
 package taxonomy
 
 import (
+	"io/ioutil"
+	"os"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/js-arias/biodv"
+	"github.com/js-arias/biodv/journal"
 )
 
 var testData = []struct {
@@ -182,6 +186,87 @@ func TestSetRank(t *testing.T) {
 	if tax.Rank() != biodv.Genus {
 		t.Errorf("taxon %q unnexpected rank: %v, want: %v", tax.Name(), tax.Rank(), biodv.Genus)
 	}
+
+	tax = db.TaxEd("Pithecanthropus")
+	if err := tax.SetRank(biodv.Subgenus); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := tax.SetRank(biodv.Tribe); err == nil {
+		t.Errorf("wanted an error: synonym rank outside its parent group")
+	}
+
+	// synonyms are checked with its own status
+	tax = db.TaxEd("Homo")
+	if err := tax.SetRank(biodv.Subgenus); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if tax.Rank() != biodv.Subgenus {
+		t.Errorf("taxon %q unnexpected rank: %v, want: %v", tax.Name(), tax.Rank(), biodv.Subgenus)
+	}
+}
+
+func TestInfraspecificRanks(t *testing.T) {
+	db := &DB{ids: make(map[string]*Taxon)}
+	sc := NewScanner(strings.NewReader(scannerBlob))
+	if err := db.scan(sc); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if _, err := db.Add("Pan troglodytes verus", "Pan troglodytes", biodv.Subspecies, true); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := db.Add("Pan troglodytes schweinfurthii", "Pan troglodytes", biodv.Subspecies, true); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := db.Add("Pan troglodytes vellerosus", "Pan troglodytes", biodv.Species, true); err == nil {
+		t.Errorf("wanted an error: species as child of a species")
+	}
+
+	// names of the species group are coordinated
+	if _, err := db.Add("Pan satyrus", "Pan troglodytes verus", biodv.Species, false); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := db.Add("Pan pygmaeus", "Pan paniscus", biodv.Subspecies, false); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := db.Add("Troglodytes", "Pan", biodv.Subgenus, false); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := db.Add("Panini", "Pan", biodv.Tribe, false); err == nil {
+		t.Errorf("wanted an error: tribe as synonym of a genus")
+	}
+
+	tax := db.TaxEd("Pan troglodytes")
+	if err := tax.SetRank(biodv.Subspecies); err == nil {
+		t.Errorf("wanted an error: inconsistent rank on children")
+	}
+
+	// infraspecific ranks are placed below a species
+	if _, err := db.Add("Pan vellerosus", "Pan", biodv.Subspecies, true); err == nil {
+		t.Errorf("wanted an error: subspecies as child of a genus")
+	}
+	if _, err := db.Add("Pan verus", "Pan", biodv.Variety, true); err == nil {
+		t.Errorf("wanted an error: variety as child of a genus")
+	}
+	if _, err := db.Add("Pan troglodytes albus", "Pan troglodytes", biodv.Variety, true); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := db.Add("Pan troglodytes niger", "Pan troglodytes albus", biodv.Form, true); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := db.Add("Pan niger", "Pan paniscus", biodv.Form, false); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := db.Add("Pan albus", "Pan", biodv.Form, false); err == nil {
+		t.Errorf("wanted an error: form as synonym of a genus")
+	}
+	if _, err := db.Add("Pan troglodytes ater", "Pan troglodytes albus", biodv.Species, false); err == nil {
+		t.Errorf("wanted an error: species as synonym of a variety")
+	}
+	tax = db.TaxEd("Pan paniscus")
+	if err := tax.SetRank(biodv.Subgenus); err == nil {
+		t.Errorf("wanted an error: inconsistent rank on children")
+	}
 }
 
 func TestSet(t *testing.T) {
@@ -234,6 +319,79 @@ func TestDelete(t *testing.T) {
 	}
 }
 
+func TestUndo(t *testing.T) {
+	db := &DB{ids: make(map[string]*Taxon)}
+	sc := NewScanner(strings.NewReader(scannerBlob))
+	if err := db.scan(sc); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	orig := db.snapshot()
+
+	db.TaxEd("Homo").Delete(false)
+	db.TaxEd("Pan paniscus").Set("author", "Schwarz, 1929")
+	if _, err := db.Add("Gorilla", "Hominidae", biodv.Genus, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ch := journal.Diff(logName, orig, db.snapshot())
+	if err := db.Undo(ch); err != nil {
+		t.Fatalf("undo: unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(db.snapshot(), orig) {
+		t.Errorf("undo: got %v, want %v", db.snapshot(), orig)
+	}
+	if tax := db.TaxEd("Homo sapiens"); tax == nil || tax.Parent() != "Homo" {
+		t.Errorf("undo: \"Homo sapiens\" should be a children of \"Homo\"")
+	}
+}
+
+func TestCommitRollback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "taxonomy")
+	if err != nil {
+		t.Fatalf("unable to create temporal directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := Open(dir)
+	if err != nil {
+		t.Fatalf("open: unexpected error: %v", err)
+	}
+	if _, err := db.Add("Homo", "", biodv.Genus, true); err != nil {
+		t.Fatalf("add: unexpected error: %v", err)
+	}
+
+	// a rolled back transaction
+	// should keep the database changes.
+	tx, err := journal.Begin(dir)
+	if err != nil {
+		t.Fatalf("begin: unexpected error: %v", err)
+	}
+	if err := db.CommitTx(tx); err != nil {
+		t.Fatalf("commit: unexpected error: %v", err)
+	}
+	tx.Rollback()
+	if !db.changed {
+		t.Errorf("rollback: database should be changed")
+	}
+	if db.version != journal.Version(dir, taxDir) {
+		t.Errorf("rollback: version %q, want %q", db.version, journal.Version(dir, taxDir))
+	}
+
+	if err := db.Commit(); err != nil {
+		t.Fatalf("commit: unexpected error: %v", err)
+	}
+	if db.changed {
+		t.Errorf("commit: database should not be changed")
+	}
+	db, err = Open(dir)
+	if err != nil {
+		t.Fatalf("open: unexpected error: %v", err)
+	}
+	if db.TaxEd("Homo") == nil {
+		t.Errorf("commit: taxon %q not found", "Homo")
+	}
+}
+
 var orderBlob = `
 name:	Mustela
 rank:	genus
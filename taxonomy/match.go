@@ -0,0 +1,282 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package taxonomy
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/js-arias/biodv"
+)
+
+// A Match is a taxon
+// that matches a name.
+type Match struct {
+	Taxon *Taxon
+
+	// Score is the similarity
+	// between the name and the taxon,
+	// from 0 to 1
+	// (an exact match).
+	Score float64
+}
+
+// Scores of the matches
+// that are not based on the edit distance
+// between the words of the names.
+const (
+	stemScore     = 0.95 // same word after removing the gender ending
+	phoneticScore = 0.9  // same phonetic key
+)
+
+// Match returns the taxa
+// that approximately match a name,
+// sorted by its score.
+//
+// The name is parsed
+// (so it can include the authors),
+// and each word of the canonical name
+// is compared with the words
+// of the names of the taxa
+// that have the same number of words.
+// The comparison is inspired by Taxamatch
+// (Rees 2014, PLoS ONE 9: e107510):
+// two words are matched
+// if they are equal,
+// if they are equal after removing
+// the gender endings
+// (for epithets),
+// if they have the same phonetic key,
+// or if they have a small edit distance
+// (one edit for words of up to five letters,
+// two for words of up to ten letters,
+// and three for longer words).
+// The score of the match
+// is the mean of the scores of the words.
+func (db *DB) Match(name string) []Match {
+	words := strings.Fields(strings.ToLower(biodv.ParseName(name).Canon()))
+	if len(words) == 0 {
+		return nil
+	}
+	keys := matchKeys(words)
+
+	seen := make(map[*Taxon]bool)
+	var ls []Match
+	for _, tax := range db.ids {
+		if seen[tax] {
+			continue
+		}
+		seen[tax] = true
+
+		tw := strings.Fields(strings.ToLower(tax.Name()))
+		if len(tw) != len(words) {
+			continue
+		}
+		score, ok := matchWords(words, keys, tw)
+		if !ok {
+			continue
+		}
+		ls = append(ls, Match{tax, score})
+	}
+	sort.Slice(ls, func(i, j int) bool {
+		if ls[i].Score != ls[j].Score {
+			return ls[i].Score > ls[j].Score
+		}
+		return ls[i].Taxon.Name() < ls[j].Taxon.Name()
+	})
+	return ls
+}
+
+// A MatchKey stores the stem
+// and the phonetic key
+// of a word.
+type matchKey struct {
+	stem     string
+	phonetic string
+}
+
+func matchKeys(words []string) []matchKey {
+	keys := make([]matchKey, len(words))
+	for i, w := range words {
+		keys[i] = wordKey(w, i > 0)
+	}
+	return keys
+}
+
+// WordKey returns the key of a word.
+// The gender ending is removed
+// only from epithets.
+func wordKey(w string, epithet bool) matchKey {
+	k := matchKey{stem: w}
+	if epithet {
+		k.stem = stem(w)
+	}
+	k.phonetic = phonetic(k.stem)
+	return k
+}
+
+// MatchWords compares the words
+// of two names.
+func matchWords(words []string, keys []matchKey, tw []string) (float64, bool) {
+	var sum float64
+	for i, w := range words {
+		t := tw[i]
+		if w == t {
+			sum += 1
+			continue
+		}
+		d := editDist(w, t)
+		n := len([]rune(w))
+		if m := len([]rune(t)); m > n {
+			n = m
+		}
+		s := 1 - float64(d)/float64(n)
+
+		k := wordKey(t, i > 0)
+		switch {
+		case i > 0 && k.stem == keys[i].stem:
+			if s < stemScore {
+				s = stemScore
+			}
+		case k.phonetic == keys[i].phonetic:
+			if s < phoneticScore {
+				s = phoneticScore
+			}
+		case d > maxDist(n):
+			return 0, false
+		}
+		sum += s
+	}
+	return sum / float64(len(words)), true
+}
+
+// MaxDist returns the maximum edit distance
+// accepted for a word of a given length.
+func maxDist(n int) int {
+	switch {
+	case n <= 5:
+		return 1
+	case n <= 10:
+		return 2
+	}
+	return 3
+}
+
+// Gender endings of epithets,
+// the longest first.
+var endings = []string{"ii", "ae", "um", "us", "is", "er", "a", "e", "i", "o"}
+
+// Stem removes the gender ending
+// of an epithet.
+func stem(w string) string {
+	for _, e := range endings {
+		if len(w) > len(e)+2 && strings.HasSuffix(w, e) {
+			return w[:len(w)-len(e)]
+		}
+	}
+	return w
+}
+
+// Replacements of the initial letters
+// of a word,
+// for the phonetic key.
+var initials = []struct {
+	from, to string
+}{
+	{"ae", "e"}, {"cn", "n"}, {"ct", "t"}, {"cz", "c"},
+	{"dj", "j"}, {"ea", "e"}, {"eu", "u"}, {"gn", "n"},
+	{"kn", "n"}, {"mc", "mac"}, {"mn", "n"}, {"oe", "e"},
+	{"qu", "q"}, {"ps", "s"}, {"pt", "t"}, {"ts", "s"},
+	{"wr", "r"}, {"x", "z"},
+}
+
+// Replacements of letters
+// in the rest of the word,
+// for the phonetic key.
+var phoneticReplacer = strings.NewReplacer(
+	"ae", "i", "ia", "i", "oe", "i", "oi", "a",
+	"sc", "s", "ph", "f", "th", "t", "ch", "c", "rh", "r",
+	"k", "c", "z", "s", "y", "i", "h", "",
+	"e", "i", "o", "a", "u", "i",
+)
+
+// Phonetic returns the phonetic key
+// of a word,
+// i.e. a simplified spelling,
+// in which letters
+// (or group of letters)
+// with similar sounds
+// are replaced by a single letter,
+// and repeated letters are removed.
+func phonetic(w string) string {
+	w = strings.Map(func(r rune) rune {
+		if !unicode.IsLetter(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, w)
+	if w == "" {
+		return ""
+	}
+	for _, in := range initials {
+		if strings.HasPrefix(w, in.from) {
+			w = in.to + w[len(in.from):]
+			break
+		}
+	}
+	w = w[:1] + phoneticReplacer.Replace(w[1:])
+
+	var b strings.Builder
+	var last rune
+	for _, r := range w {
+		if r == last {
+			continue
+		}
+		b.WriteRune(r)
+		last = r
+	}
+	return b.String()
+}
+
+// EditDist returns the edit distance
+// (Damerau-Levenshtein distance,
+// i.e. with transpositions)
+// between two strings.
+func editDist(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			v := d[i-1][j] + 1
+			if x := d[i][j-1] + 1; x < v {
+				v = x
+			}
+			if x := d[i-1][j-1] + cost; x < v {
+				v = x
+			}
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				if x := d[i-2][j-2] + 1; x < v {
+					v = x
+				}
+			}
+			d[i][j] = v
+		}
+	}
+	return d[len(s)][len(t)]
+}
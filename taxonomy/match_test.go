@@ -0,0 +1,87 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package taxonomy
+
+import (
+	"testing"
+
+	"github.com/js-arias/biodv"
+)
+
+func TestMatch(t *testing.T) {
+	db := &DB{ids: make(map[string]*Taxon)}
+	for _, d := range []struct {
+		name, parent string
+		rank         biodv.Rank
+	}{
+		{"Rhea", "", biodv.Genus},
+		{"Rhea americana", "Rhea", biodv.Species},
+		{"Rhea pennata", "Rhea", biodv.Species},
+		{"Puma", "", biodv.Genus},
+		{"Puma concolor", "Puma", biodv.Species},
+	} {
+		if _, err := db.Add(d.name, d.parent, d.rank, true); err != nil {
+			t.Fatalf("when adding %s: %v", d.name, err)
+		}
+	}
+
+	testData := []struct {
+		name  string
+		match string
+		score float64
+	}{
+		{"Rhea americana", "Rhea americana", 1},
+		{"Rhea americana (Linnaeus, 1758)", "Rhea americana", 1},
+		{"Rhea americanus", "Rhea americana", 0.975},
+		{"Rea americana", "Rhea americana", 0.95},
+		{"Rhea amerikana", "Rhea americana", 0.95},
+		{"Rhea pennatus", "Rhea pennata", 0.975},
+		{"Puma concolr", "Puma concolor", 0.9375},
+		{"Pumma", "Puma", 0.9},
+		{"Struthio camelus", "", 0},
+		{"Rhea nana", "", 0},
+	}
+
+	for _, d := range testData {
+		ls := db.Match(d.name)
+		if d.match == "" {
+			if len(ls) > 0 {
+				t.Errorf("match %q: got %q, want no match", d.name, ls[0].Taxon.Name())
+			}
+			continue
+		}
+		if len(ls) == 0 {
+			t.Errorf("match %q: no match, want %q", d.name, d.match)
+			continue
+		}
+		if ls[0].Taxon.Name() != d.match {
+			t.Errorf("match %q: got %q, want %q", d.name, ls[0].Taxon.Name(), d.match)
+		}
+		if s := ls[0].Score; s < d.score-0.001 || s > d.score+0.001 {
+			t.Errorf("match %q: score %.4f, want %.4f", d.name, s, d.score)
+		}
+	}
+}
+
+func TestEditDist(t *testing.T) {
+	testData := []struct {
+		a, b string
+		d    int
+	}{
+		{"", "", 0},
+		{"rhea", "rhea", 0},
+		{"rhea", "rea", 1},
+		{"rhea", "hrea", 1},
+		{"concolor", "concolr", 1},
+		{"americana", "americanus", 2},
+	}
+	for _, d := range testData {
+		if v := editDist(d.a, d.b); v != d.d {
+			t.Errorf("distance %q %q: got %d, want %d", d.a, d.b, v, d.d)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package taxonomy
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/js-arias/biodv"
+	"github.com/js-arias/biodv/encoding/stanza"
+	"github.com/js-arias/biodv/journal"
+
+	"github.com/pkg/errors"
+)
+
+// Problems found
+// when validating a taxon.
+const (
+	ProbNoName      = "no name"                // a taxon without name
+	ProbDupName     = "duplicated name"        // the name is used by another taxon
+	ProbRank        = "invalid rank"           // unknown rank
+	ProbParent      = "unknown parent"         // the parent is not in the taxonomy
+	ProbParentOrder = "parent after taxon"     // the parent is defined after the taxon
+	ProbSynParent   = "synonym parent"         // the parent is a synonym
+	ProbNoParent    = "synonym without parent" // a synonym attached to the root
+	ProbRankOrder   = "rank inversion"         // the rank is not lower than the rank of its parents
+)
+
+// A Problem is a problem found
+// in a taxon.
+type Problem struct {
+	ID   string // ID of the taxon
+	Kind string
+
+	// Value is the value with the problem,
+	// if any.
+	Value string
+}
+
+// String returns a description of the problem.
+func (p Problem) String() string {
+	if p.Value == "" {
+		return p.Kind
+	}
+	return p.Kind + ": " + p.Value
+}
+
+// Validate checks the taxonomy
+// of the database in the given path,
+// and returns the problems found.
+//
+// The taxa are read directly from the database file,
+// without the normalization done by the Scanner,
+// so problems that make Open fail
+// are found.
+func Validate(path string) ([]Problem, error) {
+	lk, err := journal.Shared(path, taxDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "taxonomy: validate")
+	}
+	defer lk.Release()
+
+	f, err := os.Open(filepath.Join(path, taxDir, taxFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "taxonomy: validate")
+	}
+	defer f.Close()
+	probs, err := validate(f)
+	if err != nil {
+		return nil, errors.Wrap(err, "taxonomy: validate")
+	}
+	return probs, nil
+}
+
+// Validate checks the taxa
+// read from r.
+func validate(r io.Reader) ([]Problem, error) {
+	var ls []record
+	pos := make(map[string]int)
+	var probs []Problem
+	sc := stanza.NewScanner(r)
+	for sc.Scan() {
+		rec := record(sc.Record())
+		rec[nameKey] = biodv.TaxCanon(rec[nameKey])
+		rec[parentKey] = biodv.TaxCanon(rec[parentKey])
+		name := rec.Name()
+		if name == "" {
+			probs = append(probs, Problem{"", ProbNoName, ""})
+			continue
+		}
+		if _, dup := pos[name]; dup {
+			probs = append(probs, Problem{name, ProbDupName, ""})
+			continue
+		}
+		pos[name] = len(ls)
+		ls = append(ls, rec)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, rec := range ls {
+		name := rec.Name()
+		if rk := strings.ToLower(strings.TrimSpace(rec[rankKey])); rk != "" && rec.Rank() == biodv.Unranked && rk != biodv.Unranked.String() {
+			probs = append(probs, Problem{name, ProbRank, rec[rankKey]})
+		}
+
+		pn := rec.Parent()
+		if pn == "" {
+			if !rec.IsCorrect() {
+				probs = append(probs, Problem{name, ProbNoParent, ""})
+			}
+			continue
+		}
+		j, ok := pos[pn]
+		if !ok {
+			probs = append(probs, Problem{name, ProbParent, pn})
+			continue
+		}
+		if j > i {
+			probs = append(probs, Problem{name, ProbParentOrder, pn})
+		}
+		if !ls[j].IsCorrect() {
+			probs = append(probs, Problem{name, ProbSynParent, pn})
+			continue
+		}
+		if p := rankedParent(ls, pos, rec); p != nil && !consistentRank(p.Rank(), rec.Rank(), rec.IsCorrect()) {
+			probs = append(probs, Problem{name, ProbRankOrder, rec.Rank().String() + " in " + p.Rank().String() + " " + p.Name()})
+		}
+	}
+	return probs, nil
+}
+
+// RankedParent returns the nearest parent
+// of a taxon
+// with a rank.
+func rankedParent(ls []record, pos map[string]int, rec record) record {
+	visited := map[string]bool{rec.Name(): true}
+	for pn := rec.Parent(); pn != ""; {
+		if visited[pn] {
+			// a cycle
+			return nil
+		}
+		visited[pn] = true
+		j, ok := pos[pn]
+		if !ok {
+			return nil
+		}
+		p := ls[j]
+		if p.Rank() != biodv.Unranked {
+			return p
+		}
+		pn = p.Parent()
+	}
+	return nil
+}
+
+// ConsistentRank returns true
+// if a rank is consistent
+// with the rank of its parent.
+func consistentRank(parent, rank biodv.Rank, correct bool) bool {
+	if rank == biodv.Unranked || parent == biodv.Unranked {
+		return true
+	}
+	if rank > parent {
+		return isParentRank(parent, rank)
+	}
+	if !correct && rankGroup(rank) == rankGroup(parent) {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2018 The Biodv Authors.
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+//
+// Originally written by J. Salvador Arias <jsalarias@csnat.unt.edu.ar>.
+
+package taxonomy
+
+import (
+	"strings"
+	"testing"
+)
+
+var validateBlob = `
+name:	Rheidae
+rank:	family
+correct: true
+%%
+name:	Rhea americana
+parent:	Rhea
+rank:	species
+correct: true
+%%
+name:	Rhea
+parent:	Rheidae
+rank:	genus
+correct: true
+%%
+name:	Pterocnemia
+parent:	Rhea
+rank:	genus
+correct: false
+%%
+name:	Pterocnemia pennata
+parent:	Pterocnemia
+rank:	species
+correct: true
+%%
+name:	Rhea nana
+parent:	Rhea americana
+rank:	genus
+correct: true
+%%
+name:	Rhea americana albescens
+parent:	Rhea americana
+rank:	subspecies
+correct: false
+%%
+name:	Rhea pallida
+parent:	Rhea
+rank:	variety
+correct: true
+%%
+name:	Rhea
+rank:	genus
+correct: true
+%%
+name:	Struthio
+parent:	Struthionidae
+rank:	genus
+correct: true
+%%
+name:	Casuarius
+rank:	genre
+correct: false
+%%
+name:
+rank:	genus
+%%
+`
+
+var validateProbs = []Problem{
+	{"Rhea", ProbDupName, ""},
+	{"", ProbNoName, ""},
+	{"Rhea americana", ProbParentOrder, "Rhea"},
+	{"Pterocnemia pennata", ProbSynParent, "Pterocnemia"},
+	{"Rhea nana", ProbRankOrder, "genus in species Rhea americana"},
+	{"Rhea pallida", ProbRankOrder, "variety in genus Rhea"},
+	{"Struthio", ProbParent, "Struthionidae"},
+	{"Casuarius", ProbRank, "genre"},
+	{"Casuarius", ProbNoParent, ""},
+}
+
+func TestValidate(t *testing.T) {
+	probs, err := validate(strings.NewReader(validateBlob))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(probs) != len(validateProbs) {
+		t.Errorf("found %d problems, want %d", len(probs), len(validateProbs))
+	}
+	for i, p := range probs {
+		if i >= len(validateProbs) {
+			t.Errorf("unexpected problem %v", p)
+			continue
+		}
+		if p != validateProbs[i] {
+			t.Errorf("problem %d: got %v, want %v", i, p, validateProbs[i])
+		}
+	}
+}
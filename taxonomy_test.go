@@ -164,3 +164,37 @@ func TestAuthorYear(t *testing.T) {
 		}
 	}
 }
+
+func TestGetRank(t *testing.T) {
+	testData := []struct {
+		text string
+		rank Rank
+	}{
+		{"", Unranked},
+		{"Kingdom", Kingdom},
+		{"SUBFAMILY", Subfamily},
+		{"tribe", Tribe},
+		{"subgenus", Subgenus},
+		{"species", Species},
+		{"subsp.", Subspecies},
+		{"ssp.", Subspecies},
+		{"var.", Variety},
+		{"f.", Form},
+		{"clade", Unranked},
+	}
+
+	for _, d := range testData {
+		if r := GetRank(d.text); r != d.rank {
+			t.Errorf("rank %q: got %v, want %v", d.text, r, d.rank)
+		}
+	}
+
+	for i, r := range ranks {
+		if GetRank(r) != Rank(i) {
+			t.Errorf("rank %q: got %v, want %v", r, GetRank(r), Rank(i))
+		}
+		if Rank(i).String() != r {
+			t.Errorf("rank %d: got %q, want %q", i, Rank(i).String(), r)
+		}
+	}
+}